@@ -0,0 +1,319 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/hzcrv1911/frpcgui/i18n"
+	"github.com/hzcrv1911/frpcgui/pkg/config"
+	"github.com/hzcrv1911/frpcgui/pkg/sec"
+	"github.com/hzcrv1911/frpcgui/services"
+)
+
+// Exit codes of the commands
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+const (
+	profilesDir = "profiles"
+	// passwordEnv is the environment variable holding the master password for the commands.
+	passwordEnv = "FRPCGUI_PASSWORD"
+)
+
+// command is a subcommand of the command-line interface, which controls the configs
+// and their services without the GUI.
+type command struct {
+	// args describes the arguments in the usage
+	args string
+	help string
+	// flags adds the options of the command
+	flags func(c *cli, fs *flag.FlagSet)
+	run   func(c *cli, args []string) error
+	// vault tells whether the command needs the secrets sealed by the vault
+	vault bool
+}
+
+// usageError is returned by a command given invalid arguments.
+type usageError string
+
+func (e usageError) Error() string {
+	return string(e)
+}
+
+// cli holds the state shared by the commands.
+type cli struct {
+	// json prints the results in JSON
+	json bool
+	// wd is the working directory the paths in arguments are relative to
+	wd       string
+	app      config.App
+	store    *config.History
+	vault    *sec.Vault
+	vaultErr error
+
+	// Options of commands
+	replace bool
+	output  string
+	format  string
+	key     string
+	query   logQuery
+}
+
+// cliConf is a config in the store.
+type cliConf struct {
+	ID   string
+	Path string
+	Data *config.ClientConfig
+}
+
+// runCommand runs the named command, and returns the exit code.
+func runCommand(name string, args []string) int {
+	cmd := commands[name]
+	c := &cli{}
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	fs.BoolVar(&c.json, "json", false, "Print the result in JSON.")
+	if cmd.flags != nil {
+		cmd.flags(c, fs)
+	}
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: frpcgui %s [options] %s\n\n%s\n\nOptions:\n", name, cmd.args, cmd.help)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	err := c.open()
+	if err == nil && cmd.vault {
+		err = c.vaultErr
+	}
+	if err == nil {
+		err = cmd.run(c, fs.Args())
+	}
+	var usageErr usageError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &usageErr):
+		fmt.Fprintln(os.Stderr, err)
+		fs.Usage()
+		return exitUsage
+	default:
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+}
+
+// printCommands lists the commands.
+func printCommands() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Println("Usage: frpcgui <command> [options] [arguments]")
+	fmt.Println()
+	fmt.Println("Commands:")
+	for _, name := range names {
+		fmt.Printf("  %-14s %s\n", name, commands[name].help)
+	}
+	fmt.Println()
+	fmt.Printf("Configs are given by ID or name. The master password is read from %s.\n", passwordEnv)
+	fmt.Println("Run \"frpcgui <command> -h\" for the options of a command.")
+}
+
+// open loads the application configuration and the config store, which are kept next to
+// the program, and checks the master password.
+func (c *cli) open() (err error) {
+	if c.wd, err = os.Getwd(); err != nil {
+		return err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if err = os.Chdir(filepath.Dir(exe)); err != nil {
+		return err
+	}
+	c.app = config.App{History: config.DefaultRetention}
+	if _, err = config.UnmarshalAppConf(config.DefaultAppFile, &c.app); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	c.store = config.NewHistory(config.NewFileStore(profilesDir), filepath.Join(profilesDir, ".history"), c.app.History)
+	password := os.Getenv(passwordEnv)
+	if c.app.Password != "" {
+		if err = c.checkPassword(password); err != nil {
+			return err
+		}
+	}
+	if c.app.SecretVault {
+		c.vaultErr = c.openVault(password)
+	}
+	return nil
+}
+
+// checkPassword verifies the master password, counting the failures like the GUI does.
+func (c *cli) checkPassword(password string) error {
+	now := time.Now()
+	if wait := c.app.Lockout.Remaining(now); wait > 0 {
+		return errors.New(i18n.Sprintf("Too many failed attempts. Please try again in %s.", wait.Round(time.Second)))
+	}
+	ok, rehash := sec.VerifyPassword(password, c.app.Password)
+	if !ok {
+		if password != "" {
+			c.app.Lockout.Fail(now)
+			c.app.Save(config.DefaultAppFile)
+		}
+		return errors.New(i18n.Sprintf("The master password is wrong. Please set it in the %s environment variable.", passwordEnv))
+	}
+	if rehash || c.app.Lockout.Failures > 0 {
+		if rehash {
+			if hash, err := sec.HashPassword(password); err == nil {
+				c.app.Password = hash
+			}
+		}
+		c.app.Lockout.Reset()
+		c.app.Save(config.DefaultAppFile)
+	}
+	return nil
+}
+
+func (c *cli) openVault(password string) error {
+	kf, err := sec.LoadKeyFile(config.VaultKeyFile)
+	if err != nil {
+		return err
+	}
+	key, err := kf.Unlock(password)
+	if err != nil {
+		return err
+	}
+	if c.vault, err = sec.NewVault(key); err != nil {
+		return err
+	}
+	services.Vault = c.vault
+	c.store.Vault = c.vault
+	return nil
+}
+
+// abs returns the path given in an argument as an absolute path.
+func (c *cli) abs(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(c.wd, path)
+}
+
+// confs loads all configs, in the order of the GUI.
+func (c *cli) confs() ([]*cliConf, error) {
+	ids, err := c.store.List()
+	if err != nil {
+		return nil, err
+	}
+	confs := make([]*cliConf, 0, len(ids))
+	for _, id := range ids {
+		conf, err := c.load(id)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", id, err)
+			continue
+		}
+		confs = append(confs, conf)
+	}
+	slices.SortStableFunc(confs, func(a, b *cliConf) int {
+		i := slices.Index(c.app.Sort, a.ID)
+		j := slices.Index(c.app.Sort, b.ID)
+		if i < 0 && j >= 0 {
+			return 1
+		} else if j < 0 && i >= 0 {
+			return -1
+		}
+		return i - j
+	})
+	return confs, nil
+}
+
+// load loads the config of the id, with its secrets opened if the vault is open.
+func (c *cli) load(id string) (*cliConf, error) {
+	data, err := config.LoadClientConf(c.store, id)
+	if err != nil {
+		return nil, err
+	}
+	if data.Name() == "" {
+		data.ClientCommon.Name = id
+	}
+	if c.vault != nil {
+		data.OpenSecrets(c.vault)
+	}
+	return &cliConf{ID: id, Path: c.store.Path(id), Data: data}, nil
+}
+
+// find returns the config given by ID or name.
+func (c *cli) find(arg string) (*cliConf, error) {
+	confs, err := c.confs()
+	if err != nil {
+		return nil, err
+	}
+	if i := slices.IndexFunc(confs, func(conf *cliConf) bool { return conf.ID == arg }); i >= 0 {
+		return confs[i], nil
+	}
+	var found []*cliConf
+	for _, conf := range confs {
+		if conf.Data.Name() == arg {
+			found = append(found, conf)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, errors.New(i18n.Sprintf("The config \"%s\" is not found.", arg))
+	case 1:
+		return found[0], nil
+	default:
+		return nil, errors.New(i18n.Sprintf("The name \"%s\" is shared by %d configs, please use an ID instead.", arg, len(found)))
+	}
+}
+
+// save writes the config to the store, the way the GUI does.
+func (c *cli) save(conf *cliConf) error {
+	logPath, err := filepath.Abs(filepath.Join("logs", conf.ID+".log"))
+	if err != nil {
+		return err
+	}
+	conf.Data.Complete(false)
+	conf.Data.LogFile = filepath.ToSlash(logPath)
+	save := func() error { return conf.Data.SaveTo(c.store, conf.ID) }
+	if c.vault != nil {
+		return conf.Data.WithSealedSecrets(c.vault, save)
+	}
+	return save()
+}
+
+// print writes the result in JSON if requested, or in text by the text function otherwise.
+func (c *cli) print(v any, text func()) error {
+	if !c.json {
+		text()
+		return nil
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// requireArgs returns a usage error if fewer arguments than n are given.
+func requireArgs(args []string, n int) error {
+	if len(args) < n {
+		return usageError(i18n.Sprintf("Missing arguments."))
+	}
+	return nil
+}
@@ -0,0 +1,825 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/samber/lo"
+
+	"github.com/hzcrv1911/frpcgui/i18n"
+	"github.com/hzcrv1911/frpcgui/pkg/config"
+	"github.com/hzcrv1911/frpcgui/pkg/consts"
+	"github.com/hzcrv1911/frpcgui/pkg/diag"
+	"github.com/hzcrv1911/frpcgui/pkg/logparse"
+	"github.com/hzcrv1911/frpcgui/pkg/logsearch"
+	"github.com/hzcrv1911/frpcgui/pkg/res"
+	"github.com/hzcrv1911/frpcgui/pkg/sec"
+	"github.com/hzcrv1911/frpcgui/pkg/util"
+	"github.com/hzcrv1911/frpcgui/services"
+)
+
+var commands map[string]*command
+
+func init() {
+	commands = map[string]*command{
+		"help": {
+			help: "List the commands.",
+			run: func(c *cli, args []string) error {
+				printCommands()
+				return nil
+			},
+		},
+		"list": {
+			help: "List the configs.",
+			run:  runList,
+		},
+		"status": {
+			args: "[config...]",
+			help: "Show the state of the services of the configs, or of all configs.",
+			run:  runStatus,
+		},
+		"start": {
+			args:  "<config>...",
+			help:  "Install and start the services of the configs.",
+			run:   runStart,
+			vault: true,
+		},
+		"stop": {
+			args: "<config>...",
+			help: "Stop and remove the services of the configs.",
+			run:  runStop,
+		},
+		"reload": {
+			args:  "<config>...",
+			help:  "Reload the running services of the configs, without a restart if frpc allows it.",
+			run:   runReload,
+			vault: true,
+		},
+		"import": {
+			args: "<file|url>...",
+			help: "Import configs from files, ZIP files or bundles, or URLs.",
+			flags: func(c *cli, fs *flag.FlagSet) {
+				fs.BoolVar(&c.replace, "replace", false, "Replace existing configs, instead of merging the changes with them.")
+			},
+			run:   runImport,
+			vault: true,
+		},
+		"export": {
+			help: "Export all configs to a ZIP file.",
+			flags: func(c *cli, fs *flag.FlagSet) {
+				fs.StringVar(&c.output, "o", "configs.zip", "The output `file`.")
+			},
+			run: runExport,
+		},
+		"migrate": {
+			args: "[config...]",
+			help: "Upgrade the configs, or all configs, in the legacy INI format. The original configs are kept as backups and in their history.",
+			flags: func(c *cli, fs *flag.FlagSet) {
+				fs.StringVar(&c.format, "format", consts.FormatTOML, "The target `format`: toml, yaml or json.")
+			},
+			run:   runMigrate,
+			vault: true,
+		},
+		"keygen": {
+			args: "<file>",
+			help: "Create a key file for signing bundles, and show its public key.",
+			run:  runKeygen,
+		},
+		"bundle": {
+			args: "<file> <config file>...",
+			help: "Create a bundle of the config files, signed with a key created by keygen.",
+			flags: func(c *cli, fs *flag.FlagSet) {
+				fs.StringVar(&c.key, "key", "", "The signing key `file`.")
+			},
+			run: runBundle,
+		},
+		"validate": {
+			args: "[config|file...]",
+			help: "Check the configs or config files, or all configs, for problems.",
+			run:  runValidate,
+		},
+		"logs": {
+			args: "[config...]",
+			help: "Search the logs of the configs, or of all configs, including the rotated logs.",
+			flags: func(c *cli, fs *flag.FlagSet) {
+				fs.StringVar(&c.query.since, "since", "", "Show the lines from a `time`, like \"2025-01-15 09:00\", or a duration ago, like \"2h\".")
+				fs.StringVar(&c.query.until, "until", "", "Show the lines before a `time`, like -since.")
+				fs.StringVar(&c.query.level, "level", "", "Show the lines of a `level` or above: trace, debug, info, warn or error.")
+				fs.StringVar(&c.query.proxy, "proxy", "", "Show the lines about a proxy or visitor `name`.")
+				fs.StringVar(&c.query.grep, "grep", "", "Show the lines matching a regular `expression`.")
+				fs.IntVar(&c.query.limit, "n", 1000, "The maximum `number` of lines, or 0 for no limit.")
+			},
+			run: runLogs,
+		},
+		"diagnose": {
+			args: "[config...]",
+			help: "Export the diagnostics of the configs, or of all configs, to a ZIP file for support.",
+			flags: func(c *cli, fs *flag.FlagSet) {
+				fs.StringVar(&c.output, "o", "diagnostics.zip", "The output `file`.")
+			},
+			run: runDiagnose,
+		},
+		"enable-proxy": {
+			args:  "<config> <proxy>...",
+			help:  "Enable the proxies of a config, and reload its running service.",
+			run:   func(c *cli, args []string) error { return toggleProxies(c, args, false) },
+			vault: true,
+		},
+		"disable-proxy": {
+			args:  "<config> <proxy>...",
+			help:  "Disable the proxies of a config, and reload its running service.",
+			run:   func(c *cli, args []string) error { return toggleProxies(c, args, true) },
+			vault: true,
+		},
+	}
+}
+
+// confInfo describes a config in the output.
+type confInfo struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Path        string `json:"path"`
+	Format      string `json:"format"`
+	Server      string `json:"server"`
+	Proxies     int    `json:"proxies"`
+	ManualStart bool   `json:"manualStart"`
+	State       string `json:"state,omitempty"`
+}
+
+func newConfInfo(conf *cliConf) confInfo {
+	return confInfo{
+		ID:          conf.ID,
+		Name:        conf.Data.Name(),
+		Path:        conf.Path,
+		Format:      strings.TrimPrefix(conf.Data.Ext(), "."),
+		Server:      fmt.Sprintf("%s:%d", conf.Data.ServerAddress, conf.Data.ServerPort),
+		Proxies:     len(conf.Data.Proxies),
+		ManualStart: !conf.Data.AutoStart(),
+	}
+}
+
+// findAll returns the configs given by ID or name.
+func (c *cli) findAll(args []string) ([]*cliConf, error) {
+	confs := make([]*cliConf, 0, len(args))
+	for _, arg := range args {
+		conf, err := c.find(arg)
+		if err != nil {
+			return nil, err
+		}
+		confs = append(confs, conf)
+	}
+	return confs, nil
+}
+
+func runList(c *cli, args []string) error {
+	confs, err := c.confs()
+	if err != nil {
+		return err
+	}
+	infos := lo.Map(confs, func(conf *cliConf, i int) confInfo { return newConfInfo(conf) })
+	return c.print(infos, func() {
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tSERVER\tPROXIES")
+		for _, info := range infos {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\n", info.ID, info.Name, info.Server, info.Proxies)
+		}
+		w.Flush()
+	})
+}
+
+func runStatus(c *cli, args []string) error {
+	var confs []*cliConf
+	var err error
+	if len(args) == 0 {
+		confs, err = c.confs()
+	} else {
+		confs, err = c.findAll(args)
+	}
+	if err != nil {
+		return err
+	}
+	return c.printStates(confs)
+}
+
+// printStates shows the state of the services of the configs.
+func (c *cli) printStates(confs []*cliConf) error {
+	infos := lo.Map(confs, func(conf *cliConf, i int) confInfo {
+		info := newConfInfo(conf)
+		info.State = services.QueryState(conf.Path).String()
+		return info
+	})
+	return c.print(infos, func() {
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tSTATE")
+		for _, info := range infos {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", info.ID, info.Name, info.State)
+		}
+		w.Flush()
+	})
+}
+
+// forEachConf runs the action on the configs given by ID or name, and shows the state of
+// their services. The configs failing the action are reported, and fail the command.
+func (c *cli) forEachConf(args []string, action func(conf *cliConf) error) error {
+	if err := requireArgs(args, 1); err != nil {
+		return err
+	}
+	confs, err := c.findAll(args)
+	if err != nil {
+		return err
+	}
+	var failed int
+	for _, conf := range confs {
+		if err := action(conf); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", conf.Data.Name(), err)
+			failed++
+		}
+	}
+	if err = c.printStates(confs); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return errors.New(i18n.Sprintf("%d of %d configs failed.", failed, len(confs)))
+	}
+	return nil
+}
+
+func runStart(c *cli, args []string) error {
+	return c.forEachConf(args, func(conf *cliConf) error {
+		if err := services.VerifyClientConfig(conf.Path); err != nil {
+			return err
+		}
+		// Ensure log directory is valid
+		if logFile := conf.Data.LogFile; logFile != "" && logFile != "console" {
+			if err := os.MkdirAll(filepath.Dir(logFile), os.ModePerm); err != nil {
+				return err
+			}
+		}
+		return services.InstallService(conf.Data.Name(), conf.Path, !conf.Data.AutoStart())
+	})
+}
+
+func runStop(c *cli, args []string) error {
+	return c.forEachConf(args, func(conf *cliConf) error {
+		return services.UninstallService(conf.Path, true)
+	})
+}
+
+func runReload(c *cli, args []string) error {
+	return c.forEachConf(args, func(conf *cliConf) error {
+		if services.QueryState(conf.Path) != consts.ConfigStateStarted {
+			return errors.New(i18n.Sprintf("The service is not running."))
+		}
+		return services.ReloadService(conf.Path)
+	})
+}
+
+// importResult describes an imported config in the output.
+type importResult struct {
+	Source string `json:"source"`
+	ID     string `json:"id,omitempty"`
+	Name   string `json:"name,omitempty"`
+	// Result is one of "created", "updated", "unchanged" or "failed".
+	Result   string   `json:"result"`
+	Problems []string `json:"problems,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+func runImport(c *cli, args []string) error {
+	if err := requireArgs(args, 1); err != nil {
+		return err
+	}
+	var results []importResult
+	for _, arg := range args {
+		results = append(results, c.importSource(arg)...)
+	}
+	failed := lo.CountBy(results, func(r importResult) bool { return r.Result == "failed" })
+	err := c.print(results, func() {
+		for _, r := range results {
+			if r.Error != "" {
+				fmt.Printf("%s: %s: %s\n", r.Source, r.Result, r.Error)
+				continue
+			}
+			fmt.Printf("%s: %s %s (%s)\n", r.Source, r.Result, r.Name, r.ID)
+			for _, problem := range r.Problems {
+				fmt.Printf("  %s\n", problem)
+			}
+		}
+	})
+	if err == nil && failed > 0 {
+		err = errors.New(i18n.Sprintf("%d of %d configs failed.", failed, len(results)))
+	}
+	return err
+}
+
+// importSource imports the configs in a file or at a URL. The content downloaded from
+// a URL, or found in a ZIP file, is subject to the bundle policy.
+func (c *cli) importSource(source string) []importResult {
+	fail := func(err error) []importResult {
+		return []importResult{{Source: source, Result: "failed", Error: err.Error()}}
+	}
+	var filename string
+	var data []byte
+	var zip bool
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		var mediaType string
+		var err error
+		if filename, mediaType, data, err = util.DownloadFile(ctx, source); err != nil {
+			return fail(err)
+		}
+		zip = mediaType == "application/zip" || strings.ToLower(filepath.Ext(filename)) == ".zip"
+		if !zip {
+			if err = c.app.Bundles.CheckUnsigned(); err != nil {
+				return fail(err)
+			}
+		}
+	} else {
+		var err error
+		path := c.abs(source)
+		if data, err = os.ReadFile(path); err != nil {
+			return fail(err)
+		}
+		filename = filepath.Base(path)
+		zip = strings.ToLower(filepath.Ext(filename)) == ".zip"
+	}
+	if !zip {
+		return []importResult{c.importData(source, filename, data)}
+	}
+	bundle, err := c.app.Bundles.OpenBundle(data)
+	if err != nil {
+		return fail(err)
+	}
+	var results []importResult
+	for _, file := range bundle.Files {
+		if slices.Contains(res.SupportedConfigFormats, strings.ToLower(filepath.Ext(file.Name))) {
+			results = append(results, c.importData(source+":"+file.Name, file.Name, file.Data))
+		}
+	}
+	return results
+}
+
+// importData imports a config, the way the GUI does. A config with the name of an existing
+// config is merged with it, keeping the local changes, unless it's replaced.
+func (c *cli) importData(source, filename string, src []byte) importResult {
+	result := importResult{Source: source, Result: "failed"}
+	data, err := config.UnmarshalClientConf(src)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if data.Name() == "" {
+		data.ClientCommon.Name = util.FileNameWithoutExt(filename)
+	}
+	result.Name = data.Name()
+	if err = data.OpenSecrets(c.vault); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	confs, err := c.confs()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	conf, found := lo.Find(confs, func(item *cliConf) bool { return item.Data.Name() == data.Name() })
+	if !found {
+		conf = &cliConf{ID: config.NewConfigID(), Data: data}
+		conf.Path = c.store.Path(conf.ID)
+		result.Result = "created"
+	} else {
+		// The log file is always decided by the path of the config
+		data.LogFile = conf.Data.LogFile
+		if !c.replace {
+			// The content imported last time tells the local changes apart from the remote ones
+			var base *config.ClientConfig
+			if b, err := config.LoadBase(c.store, conf.ID); err == nil {
+				if base, err = config.UnmarshalClientConf(b); err == nil {
+					base.OpenSecrets(c.vault)
+				}
+			}
+			data, _ = config.Merge(base, conf.Data, data)
+		}
+		result.Result = "unchanged"
+		if !config.Diff(conf.Data, data).Empty() {
+			result.Result = "updated"
+		}
+		conf.Data = data
+	}
+	if result.Result != "unchanged" {
+		if err = c.save(conf); err != nil {
+			result.Result = "failed"
+			result.Error = err.Error()
+			return result
+		}
+	}
+	result.ID = conf.ID
+	if err = config.SaveBase(c.store, conf.ID, config.SealContent(src, c.vault)); err != nil {
+		result.Result = "failed"
+		result.Error = err.Error()
+		return result
+	}
+	for _, d := range data.Validate() {
+		result.Problems = append(result.Problems, d.String())
+	}
+	return result
+}
+
+func runExport(c *cli, args []string) error {
+	confs, err := c.confs()
+	if err != nil {
+		return err
+	}
+	path := c.abs(c.output)
+	if !strings.HasSuffix(path, ".zip") {
+		path += ".zip"
+	}
+	// The secrets are archived as stored, so they stay sealed if the vault is enabled
+	paths := lo.Map(confs, func(conf *cliConf, _ int) string {
+		return conf.Path
+	})
+	if err = config.ExportClientConfs(path, paths); err != nil {
+		return err
+	}
+	result := struct {
+		Path    string `json:"path"`
+		Configs int    `json:"configs"`
+	}{path, len(paths)}
+	return c.print(result, func() {
+		fmt.Println(i18n.Sprintf("Exported %d configs to %s.", result.Configs, result.Path))
+	})
+}
+
+// migrateResult describes a migrated config in the output.
+type migrateResult struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	*config.MigrationReport
+	Error string `json:"error,omitempty"`
+}
+
+func runMigrate(c *cli, args []string) error {
+	var confs []*cliConf
+	var err error
+	if len(args) == 0 {
+		confs, err = c.confs()
+		confs = lo.Filter(confs, func(conf *cliConf, i int) bool { return conf.Data.Format == consts.FormatINI })
+	} else {
+		confs, err = c.findAll(args)
+	}
+	if err != nil {
+		return err
+	}
+	results := lo.Map(confs, func(conf *cliConf, i int) migrateResult {
+		result := migrateResult{ID: conf.ID, Name: conf.Data.Name()}
+		if err := c.migrate(conf, &result); err != nil {
+			result.Error = err.Error()
+		}
+		return result
+	})
+	failed := lo.CountBy(results, func(r migrateResult) bool { return r.Error != "" })
+	err = c.print(results, func() {
+		for _, r := range results {
+			if r.Error != "" {
+				fmt.Printf("%s: %s\n", r.Name, r.Error)
+				continue
+			}
+			fmt.Print(r.MigrationReport)
+		}
+	})
+	if err == nil && failed > 0 {
+		err = errors.New(i18n.Sprintf("%d of %d configs failed.", failed, len(results)))
+	}
+	return err
+}
+
+// migrate upgrades the config, keeping a backup of the original, and saves it once the
+// way the GUI does. Its service is reloaded if it's running.
+func (c *cli) migrate(conf *cliConf, result *migrateResult) error {
+	data, report, err := config.MigrateConf(c.store, conf.ID, c.format)
+	if err != nil {
+		return err
+	}
+	result.MigrationReport = report
+	if data.Name() == "" {
+		data.ClientCommon.Name = conf.Data.Name()
+	}
+	if c.vault != nil {
+		data.OpenSecrets(c.vault)
+	}
+	conf.Data = data
+	if err = c.save(conf); err != nil {
+		return err
+	}
+	if services.QueryState(conf.Path) == consts.ConfigStateStarted {
+		return services.ReloadService(conf.Path)
+	}
+	return nil
+}
+
+// runKeygen creates a key for signing bundles and shows its public key, which is added
+// to the trusted keys of the receiving side.
+func runKeygen(c *cli, args []string) error {
+	if err := requireArgs(args, 1); err != nil {
+		return err
+	}
+	path := c.abs(args[0])
+	if _, err := os.Stat(path); err == nil {
+		return errors.New(i18n.Sprintf("The file \"%s\" already exists.", path))
+	}
+	key, err := sec.GenerateSigningKey()
+	if err != nil {
+		return err
+	}
+	if err = sec.SaveSigningKey(path, key); err != nil {
+		return err
+	}
+	result := struct {
+		Path      string `json:"path"`
+		PublicKey string `json:"publicKey"`
+	}{path, sec.EncodePublicKey(key.Public().(ed25519.PublicKey))}
+	return c.print(result, func() {
+		fmt.Println(i18n.Sprintf("The signing key is created. Its public key is:"))
+		fmt.Println(result.PublicKey)
+	})
+}
+
+// runBundle signs the config files into a bundle.
+func runBundle(c *cli, args []string) error {
+	if err := requireArgs(args, 2); err != nil {
+		return err
+	}
+	if c.key == "" {
+		return usageError(i18n.Sprintf("No signing key is given."))
+	}
+	key, err := sec.LoadSigningKey(c.abs(c.key))
+	if err != nil {
+		return err
+	}
+	path, files := c.abs(args[0]), args[1:]
+	bundleFiles := make([]config.BundleFile, 0, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(c.abs(file))
+		if err != nil {
+			return err
+		}
+		// Only valid configs are signed
+		if _, err = config.UnmarshalClientConf(data); err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		bundleFiles = append(bundleFiles, config.BundleFile{Name: filepath.Base(file), Data: data})
+	}
+	data, err := config.CreateBundle(util.FileNameWithoutExt(path), bundleFiles, key)
+	if err != nil {
+		return err
+	}
+	if err = util.WriteFileAtomic(path, data, 0666); err != nil {
+		return err
+	}
+	result := struct {
+		Path    string `json:"path"`
+		Configs int    `json:"configs"`
+	}{path, len(bundleFiles)}
+	return c.print(result, func() {
+		fmt.Println(i18n.Sprintf("Signed %d configs into %s.", result.Configs, result.Path))
+	})
+}
+
+// validateResult describes the problems found in a config in the output.
+type validateResult struct {
+	Name        string             `json:"name"`
+	Source      string             `json:"source"`
+	Diagnostics config.Diagnostics `json:"diagnostics"`
+}
+
+func runValidate(c *cli, args []string) error {
+	var results []validateResult
+	if len(args) == 0 {
+		confs, err := c.confs()
+		if err != nil {
+			return err
+		}
+		for _, conf := range confs {
+			results = append(results, validateResult{conf.Data.Name(), conf.Path, conf.Data.Validate()})
+		}
+	}
+	for _, arg := range args {
+		// A file is checked as is, before looking for a config of the name
+		if path := c.abs(arg); util.FileExists(path) {
+			ds, err := config.ValidateClientConf(path)
+			if err != nil {
+				return fmt.Errorf("%s: %w", arg, err)
+			}
+			results = append(results, validateResult{filepath.Base(path), path, ds})
+			continue
+		}
+		conf, err := c.find(arg)
+		if err != nil {
+			return err
+		}
+		results = append(results, validateResult{conf.Data.Name(), conf.Path, conf.Data.Validate()})
+	}
+	invalid := lo.CountBy(results, func(r validateResult) bool { return r.Diagnostics.HasErrors() })
+	err := c.print(results, func() {
+		for _, r := range results {
+			if len(r.Diagnostics) == 0 {
+				fmt.Printf("%s: ok\n", r.Name)
+			}
+			for _, d := range r.Diagnostics {
+				fmt.Printf("%s: %s\n", r.Name, d)
+			}
+		}
+	})
+	if err == nil && invalid > 0 {
+		err = errors.New(i18n.Sprintf("%d of %d configs have errors.", invalid, len(results)))
+	}
+	return err
+}
+
+// toggleProxies enables or disables the named proxies of a config, and reloads its service
+// if it's running.
+func toggleProxies(c *cli, args []string, disabled bool) error {
+	if err := requireArgs(args, 2); err != nil {
+		return err
+	}
+	conf, err := c.find(args[0])
+	if err != nil {
+		return err
+	}
+	for _, name := range args[1:] {
+		proxy, found := lo.Find(conf.Data.Proxies, func(p *config.Proxy) bool { return p.Name == name })
+		if !found {
+			return errors.New(i18n.Sprintf("The proxy \"%s\" is not found.", name))
+		}
+		proxy.Disabled = disabled
+	}
+	// We can't disable all proxies
+	if conf.Data.CountStart() == 0 {
+		return errors.New(i18n.Sprintf("At least one proxy must be enabled."))
+	}
+	if err = c.save(conf); err != nil {
+		return err
+	}
+	if services.QueryState(conf.Path) == consts.ConfigStateStarted {
+		if err = services.ReloadService(conf.Path); err != nil {
+			return err
+		}
+	}
+	return c.printStates([]*cliConf{conf})
+}
+
+// logQuery holds the options of the logs command.
+type logQuery struct {
+	since string
+	until string
+	level string
+	proxy string
+	grep  string
+	limit int
+}
+
+// build returns the query given by the options, with the times relative to now.
+func (q *logQuery) build(now time.Time) (query logsearch.Query, err error) {
+	if query.Since, err = parseTimeOption(q.since, now); err != nil {
+		return
+	}
+	if query.Until, err = parseTimeOption(q.until, now); err != nil {
+		return
+	}
+	if q.level != "" {
+		var ok bool
+		if query.Level, ok = logparse.ParseLevel(q.level); !ok {
+			return query, usageError(i18n.Sprintf("Invalid level \"%s\".", q.level))
+		}
+	}
+	if q.grep != "" {
+		if query.Pattern, err = regexp.Compile(q.grep); err != nil {
+			return query, usageError(err.Error())
+		}
+	}
+	query.Proxy = q.proxy
+	query.Limit = q.limit
+	return query, nil
+}
+
+// parseTimeOption parses a time in local time, or a duration before now.
+func parseTimeOption(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{time.DateTime, "2006-01-02 15:04", time.DateOnly} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, usageError(i18n.Sprintf("Invalid time \"%s\".", s))
+}
+
+// logLine describes a log line in the output.
+type logLine struct {
+	Config string `json:"config"`
+	Time   string `json:"time,omitempty"`
+	Level  string `json:"level"`
+	Proxy  string `json:"proxy,omitempty"`
+	Event  string `json:"event,omitempty"`
+	Line   string `json:"line"`
+}
+
+// runLogs prints the matching log lines as they are found. In JSON, each line is printed
+// as an object on its own line, so that the output can be streamed.
+func runLogs(c *cli, args []string) error {
+	query, err := c.query.build(time.Now())
+	if err != nil {
+		return err
+	}
+	var confs []*cliConf
+	if len(args) == 0 {
+		confs, err = c.confs()
+	} else {
+		confs, err = c.findAll(args)
+	}
+	if err != nil {
+		return err
+	}
+	sources := lo.Map(confs, func(conf *cliConf, i int) logsearch.Source {
+		return logsearch.Source{Name: conf.Data.Name(), Path: conf.Data.LogFile}
+	})
+	enc := json.NewEncoder(os.Stdout)
+	err = logsearch.Search(context.Background(), sources, query, func(r logsearch.Result) error {
+		if !c.json {
+			_, err := fmt.Printf("%s: %s\n", r.Source, r.Line)
+			return err
+		}
+		line := logLine{Config: r.Source, Level: r.Record.Level.String(), Proxy: r.Record.Proxy, Line: r.Line}
+		if !r.Record.Time.IsZero() {
+			line.Time = r.Record.Time.Format(time.RFC3339Nano)
+		}
+		if r.Record.Event != logparse.EventNone {
+			line.Event = r.Record.Event.String()
+		}
+		return enc.Encode(line)
+	})
+	if errors.Is(err, logsearch.ErrLimit) {
+		fmt.Fprintln(os.Stderr, i18n.Sprintf("More lines are found, use -n to show more."))
+		return nil
+	}
+	return err
+}
+
+func runDiagnose(c *cli, args []string) error {
+	var confs []*cliConf
+	var err error
+	if len(args) == 0 {
+		confs, err = c.confs()
+	} else {
+		confs, err = c.findAll(args)
+	}
+	if err != nil {
+		return err
+	}
+	path := c.abs(c.output)
+	if !strings.HasSuffix(path, ".zip") {
+		path += ".zip"
+	}
+	sources := lo.Map(confs, func(conf *cliConf, i int) diag.Source {
+		return diag.Source{Path: conf.Path, Data: conf.Data}
+	})
+	m, err := services.ExportDiagnostics(context.Background(), path, sources)
+	if err != nil {
+		return err
+	}
+	result := struct {
+		Path string `json:"path"`
+		*diag.Manifest
+	}{path, m}
+	return c.print(result, func() {
+		fmt.Println(i18n.Sprintf("Exported the diagnostics of %d configs to %s.", len(m.Configs), path))
+		for _, info := range m.Configs {
+			for _, check := range info.Checks {
+				if check.Status == diag.CheckFailed {
+					fmt.Printf("%s: %s %s: %s\n", info.Name, check.Kind, check.Target, check.Error)
+				}
+			}
+			for _, e := range info.Errors {
+				fmt.Printf("%s: %s\n", info.Name, e)
+			}
+		}
+		for _, e := range m.Errors {
+			fmt.Println(e)
+		}
+	})
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+var procAttachConsole = windows.NewLazySystemDLL("kernel32.dll").NewProc("AttachConsole")
+
+const attachParentProcess = ^uintptr(0)
+
+// attachConsole connects the standard streams to the console of the parent process, since
+// the program is built for the GUI subsystem and gets no console of its own. Streams
+// redirected to files or pipes are kept.
+func attachConsole() {
+	if r, _, _ := procAttachConsole.Call(attachParentProcess); r == 0 {
+		return
+	}
+	for _, std := range []**os.File{&os.Stdout, &os.Stderr} {
+		if _, err := (*std).Stat(); err == nil {
+			continue
+		}
+		if f, err := os.OpenFile("CONOUT$", os.O_WRONLY, 0); err == nil {
+			*std = f
+		}
+	}
+}
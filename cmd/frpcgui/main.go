@@ -53,6 +53,10 @@ func main() {
 		}, "\n"))
 		return
 	}
+	if name := flag.Arg(0); commands[name] != nil {
+		attachConsole()
+		os.Exit(runCommand(name, flag.Args()[1:]))
+	}
 	inService, err := svc.IsWindowsService()
 	if err != nil {
 		fatal(err)
@@ -9,9 +9,11 @@ require (
 	github.com/lxn/win v0.0.0-20210218163916-a377121e959e
 	github.com/pelletier/go-toml/v2 v2.2.0
 	github.com/samber/lo v1.47.0
+	golang.org/x/crypto v0.37.0
 	golang.org/x/sys v0.38.0
 	golang.org/x/text v0.24.0
 	gopkg.in/ini.v1 v1.67.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require gopkg.in/Knetic/govaluate.v3 v3.0.0 // indirect
@@ -0,0 +1,125 @@
+// Package api implements the local HTTP API, which lets other programs drive the manager
+// while it's running.
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strings"
+)
+
+// DefaultAddress is the address the API listens on if none is given.
+const DefaultAddress = "127.0.0.1:7450"
+
+// unixPrefix marks an address as the path of a Unix socket.
+const unixPrefix = "unix:"
+
+var (
+	// ErrNotFound is returned by a backend for a config or proxy that doesn't exist.
+	ErrNotFound = errors.New("not found")
+	// ErrInvalid is returned by a backend for a request it can't perform.
+	ErrInvalid = errors.New("invalid request")
+)
+
+// Config describes a config.
+type Config struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// State is the state of the service, see consts.ConfigState.
+	State string `json:"state"`
+}
+
+// Proxy describes a proxy of a config, along with its status.
+type Proxy struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Disabled bool   `json:"disabled"`
+	// State is the running state of the proxy, see consts.ProxyState.
+	State      string `json:"state"`
+	Error      string `json:"error,omitempty"`
+	RemoteAddr string `json:"remoteAddr,omitempty"`
+}
+
+// ImportResult describes a config imported by a request.
+type ImportResult struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Created tells a new config apart from an existing one that's replaced.
+	Created bool `json:"created"`
+	// Problems are found by validating the config.
+	Problems []string `json:"problems,omitempty"`
+}
+
+// Event is a change sent to the event stream.
+type Event struct {
+	// Type is the name of the event, such as "state".
+	Type  string `json:"-"`
+	ID    string `json:"id"`
+	State string `json:"state,omitempty"`
+}
+
+// Backend performs the requests on the configs. The methods are called from the goroutines
+// serving the requests, and should return ErrNotFound or ErrInvalid, possibly wrapped, for
+// the errors caused by the request.
+type Backend interface {
+	// Configs returns all configs.
+	Configs(ctx context.Context) ([]Config, error)
+	// Proxies returns the proxies of a config.
+	Proxies(ctx context.Context, id string) ([]Proxy, error)
+	// Start starts the service of a config, installing it if needed.
+	Start(ctx context.Context, id string) error
+	// Stop stops the service of a config.
+	Stop(ctx context.Context, id string) error
+	// Reload reloads the running service of a config.
+	Reload(ctx context.Context, id string) error
+	// SetProxyDisabled enables or disables a proxy, and reloads the running service.
+	SetProxyDisabled(ctx context.Context, id, proxy string, disabled bool) error
+	// Import imports the configs in a file, which may be a zip file or a bundle. A config of
+	// the same name as an existing one replaces it. Nothing is imported if a config is invalid.
+	Import(ctx context.Context, filename string, data []byte) ([]ImportResult, error)
+	// Export returns the file of a config as stored, and its file name.
+	Export(ctx context.Context, id string) (filename string, data []byte, err error)
+}
+
+// NewToken returns a random token for authenticating the requests.
+func NewToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Listen listens on the address, which is a loopback TCP address, or "unix:" followed by
+// the path of a Unix socket. An empty address means DefaultAddress.
+func Listen(address string) (net.Listener, error) {
+	if path, ok := strings.CutPrefix(address, unixPrefix); ok {
+		if path == "" {
+			return nil, fmt.Errorf("the path of the Unix socket is empty")
+		}
+		// A socket left behind by an earlier run would fail the listening
+		if fi, err := os.Lstat(path); err == nil && fi.Mode().Type() == fs.ModeSocket {
+			os.Remove(path)
+		}
+		return net.Listen("unix", path)
+	}
+	if address == "" {
+		address = DefaultAddress
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	if host != "localhost" {
+		if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+			return nil, fmt.Errorf("the address %s is not a loopback address", address)
+		}
+	}
+	return net.Listen("tcp", address)
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"testing"
+)
+
+func TestListen(t *testing.T) {
+	tests := []struct {
+		input string
+		ok    bool
+	}{
+		{input: "127.0.0.1:0", ok: true},
+		{input: "localhost:0", ok: true},
+		{input: "[::1]:0", ok: true},
+		{input: "0.0.0.0:0", ok: false},
+		{input: "192.168.1.1:0", ok: false},
+		{input: "example.com:0", ok: false},
+		{input: "127.0.0.1", ok: false},
+		{input: "unix:", ok: false},
+		{input: "unix:" + t.TempDir() + "/api.sock", ok: true},
+	}
+	for i, test := range tests {
+		l, err := Listen(test.input)
+		if (err == nil) != test.ok {
+			t.Errorf("Test %d: expected: %v, got: %v", i, test.ok, err)
+		}
+		if l != nil {
+			l.Close()
+		}
+	}
+}
+
+func TestNewToken(t *testing.T) {
+	a, err := NewToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := NewToken()
+	if len(a) != 64 || a == b {
+		t.Errorf("Expected: %v, got: %v %v", "two different tokens of 64 characters", a, b)
+	}
+}
@@ -0,0 +1,258 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxImportSize limits the size of an imported file.
+	maxImportSize = 16 << 20
+	// keepAliveInterval is the interval of the comments sent to idle event streams.
+	keepAliveInterval = 30 * time.Second
+	// eventBuffer is the number of events queued for a client. A client falling
+	// further behind is disconnected, and gets the current states on reconnection.
+	eventBuffer = 64
+)
+
+// Server serves the API. Every request must carry the token in the Authorization header,
+// in the form of "Bearer <token>".
+type Server struct {
+	token   string
+	backend Backend
+	srv     *http.Server
+
+	mu      sync.Mutex
+	streams map[chan Event]struct{}
+}
+
+// NewServer returns a server performing the requests authenticated by the token on the backend.
+func NewServer(token string, backend Backend) *Server {
+	s := &Server{
+		token:   token,
+		backend: backend,
+		streams: make(map[chan Event]struct{}),
+	}
+	s.srv = &http.Server{Handler: s.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	return s
+}
+
+// Handler returns the handler of the API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/configs", s.listConfigs)
+	mux.HandleFunc("GET /api/v1/configs/{id}/proxies", s.listProxies)
+	mux.HandleFunc("POST /api/v1/configs/{id}/start", s.action(s.backend.Start))
+	mux.HandleFunc("POST /api/v1/configs/{id}/stop", s.action(s.backend.Stop))
+	mux.HandleFunc("POST /api/v1/configs/{id}/reload", s.action(s.backend.Reload))
+	mux.HandleFunc("POST /api/v1/configs/{id}/proxies/{name}/enable", s.toggleProxy(false))
+	mux.HandleFunc("POST /api/v1/configs/{id}/proxies/{name}/disable", s.toggleProxy(true))
+	mux.HandleFunc("GET /api/v1/configs/{id}/export", s.exportConfig)
+	mux.HandleFunc("POST /api/v1/import", s.importConfig)
+	mux.HandleFunc("GET /api/v1/events", s.events)
+	return s.authenticate(mux)
+}
+
+// Serve serves the API on the listener until the server is closed.
+func (s *Server) Serve(l net.Listener) error {
+	if err := s.srv.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Close stops the server, closing all connections.
+func (s *Server) Close() error {
+	return s.srv.Close()
+}
+
+// Publish sends the event to the event streams.
+func (s *Server) Publish(e Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.streams {
+		select {
+		case ch <- e:
+		default:
+			delete(s.streams, ch)
+			close(ch)
+		}
+	}
+}
+
+func (s *Server) subscribe() chan Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ch := make(chan Event, eventBuffer)
+	s.streams[ch] = struct{}{}
+	return ch
+}
+
+func (s *Server) unsubscribe(ch chan Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.streams[ch]; ok {
+		delete(s.streams, ch)
+		close(ch)
+	}
+}
+
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || s.token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="frpcgui"`)
+			writeJSON(w, http.StatusUnauthorized, errorResponse{"unauthorized"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) listConfigs(w http.ResponseWriter, r *http.Request) {
+	confs, err := s.backend.Configs(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, confs)
+}
+
+func (s *Server) listProxies(w http.ResponseWriter, r *http.Request) {
+	proxies, err := s.backend.Proxies(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, proxies)
+}
+
+// action returns a handler performing the action on the config in the path.
+func (s *Server) action(f func(ctx context.Context, id string) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := f(r.Context(), r.PathValue("id")); err != nil {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *Server) toggleProxy(disabled bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := s.backend.SetProxyDisabled(r.Context(), r.PathValue("id"), r.PathValue("name"), disabled); err != nil {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *Server) exportConfig(w http.ResponseWriter, r *http.Request) {
+	filename, data, err := s.backend.Export(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	w.Write(data)
+}
+
+// importConfig imports the file in the request body. The file name, which gives the name
+// of a config without one, is given by the "filename" query parameter.
+func (s *Server) importConfig(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportSize))
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			writeJSON(w, http.StatusRequestEntityTooLarge, errorResponse{err.Error()})
+			return
+		}
+		writeError(w, err)
+		return
+	}
+	results, err := s.backend.Import(r.Context(), r.URL.Query().Get("filename"), data)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+// events streams the changes as Server-Sent Events. The current states of all configs
+// are sent first, so a client needs no other request to catch up after reconnecting.
+func (s *Server) events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, errors.New("streaming is not supported"))
+		return
+	}
+	ch := s.subscribe()
+	defer s.unsubscribe(ch)
+	confs, err := s.backend.Configs(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	for _, conf := range confs {
+		writeEvent(w, Event{Type: "state", ID: conf.ID, State: conf.State})
+	}
+	flusher.Flush()
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case e, ok := <-ch:
+			if !ok {
+				return
+			}
+			writeEvent(w, e)
+		case <-ticker.C:
+			io.WriteString(w, ": keep-alive\n\n")
+		case <-r.Context().Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+func writeEvent(w io.Writer, e Event) {
+	b, _ := json.Marshal(e)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, b)
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// writeError writes the error with the status decided by its kind.
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrInvalid):
+		status = http.StatusBadRequest
+	}
+	writeJSON(w, status, errorResponse{err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type fakeBackend struct {
+	confs    []Config
+	proxies  map[string][]Proxy
+	started  []string
+	imported []byte
+}
+
+func (b *fakeBackend) Configs(ctx context.Context) ([]Config, error) {
+	return b.confs, nil
+}
+
+func (b *fakeBackend) Proxies(ctx context.Context, id string) ([]Proxy, error) {
+	proxies, ok := b.proxies[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return proxies, nil
+}
+
+func (b *fakeBackend) Start(ctx context.Context, id string) error {
+	b.started = append(b.started, id)
+	return nil
+}
+
+func (b *fakeBackend) Stop(ctx context.Context, id string) error {
+	return nil
+}
+
+func (b *fakeBackend) Reload(ctx context.Context, id string) error {
+	return fmt.Errorf("%w: the service is not running", ErrInvalid)
+}
+
+func (b *fakeBackend) SetProxyDisabled(ctx context.Context, id, proxy string, disabled bool) error {
+	for i, p := range b.proxies[id] {
+		if p.Name == proxy {
+			b.proxies[id][i].Disabled = disabled
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (b *fakeBackend) Import(ctx context.Context, filename string, data []byte) ([]ImportResult, error) {
+	b.imported = data
+	return []ImportResult{{ID: "new", Name: strings.TrimSuffix(filename, ".toml"), Created: true}}, nil
+}
+
+func (b *fakeBackend) Export(ctx context.Context, id string) (string, []byte, error) {
+	return "home.toml", []byte("serverAddr = \"example.com\"\n"), nil
+}
+
+func TestServer(t *testing.T) {
+	backend := &fakeBackend{
+		confs:   []Config{{ID: "a", Name: "home", State: "started"}},
+		proxies: map[string][]Proxy{"a": {{Name: "ssh", Type: "tcp", State: "running"}}},
+	}
+	ts := httptest.NewServer(NewServer("secret", backend).Handler())
+	defer ts.Close()
+
+	tests := []struct {
+		method   string
+		path     string
+		token    string
+		body     string
+		status   int
+		expected string
+	}{
+		{method: "GET", path: "/api/v1/configs", token: "", status: http.StatusUnauthorized},
+		{method: "GET", path: "/api/v1/configs", token: "wrong", status: http.StatusUnauthorized},
+		{method: "GET", path: "/api/v1/configs", token: "secret", status: http.StatusOK,
+			expected: `[{"id":"a","name":"home","state":"started"}]`},
+		{method: "GET", path: "/api/v1/configs/a/proxies", token: "secret", status: http.StatusOK,
+			expected: `[{"name":"ssh","type":"tcp","disabled":false,"state":"running"}]`},
+		{method: "GET", path: "/api/v1/configs/b/proxies", token: "secret", status: http.StatusNotFound,
+			expected: `{"error":"not found"}`},
+		{method: "POST", path: "/api/v1/configs/a/start", token: "secret", status: http.StatusNoContent},
+		{method: "GET", path: "/api/v1/configs/a/start", token: "secret", status: http.StatusMethodNotAllowed},
+		{method: "POST", path: "/api/v1/configs/a/reload", token: "secret", status: http.StatusBadRequest,
+			expected: `{"error":"invalid request: the service is not running"}`},
+		{method: "POST", path: "/api/v1/configs/a/proxies/ssh/disable", token: "secret", status: http.StatusNoContent},
+		{method: "POST", path: "/api/v1/configs/a/proxies/web/disable", token: "secret", status: http.StatusNotFound,
+			expected: `{"error":"not found"}`},
+		{method: "POST", path: "/api/v1/import?filename=office.toml", token: "secret", body: "serverPort = 7000", status: http.StatusOK,
+			expected: `[{"id":"new","name":"office","created":true}]`},
+		{method: "GET", path: "/api/v1/configs/a/export", token: "secret", status: http.StatusOK,
+			expected: `serverAddr = "example.com"`},
+	}
+	for i, test := range tests {
+		req, err := http.NewRequest(test.method, ts.URL+test.path, strings.NewReader(test.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.token != "" {
+			req.Header.Set("Authorization", "Bearer "+test.token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != test.status {
+			t.Errorf("Test %d: expected: %v, got: %v", i, test.status, resp.StatusCode)
+		}
+		if output := strings.TrimSpace(string(body)); test.expected != "" && output != test.expected {
+			t.Errorf("Test %d: expected: %v, got: %v", i, test.expected, output)
+		}
+	}
+	if !reflect.DeepEqual(backend.started, []string{"a"}) {
+		t.Errorf("Expected: %v, got: %v", []string{"a"}, backend.started)
+	}
+	if !backend.proxies["a"][0].Disabled {
+		t.Errorf("Expected: %v, got: %v", true, false)
+	}
+	if string(backend.imported) != "serverPort = 7000" {
+		t.Errorf("Expected: %v, got: %v", "serverPort = 7000", string(backend.imported))
+	}
+}
+
+func TestServerEvents(t *testing.T) {
+	backend := &fakeBackend{confs: []Config{{ID: "a", Name: "home", State: "stopped"}}}
+	server := NewServer("secret", backend)
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	req, _ := http.NewRequest("GET", ts.URL+"/api/v1/events", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected: %v, got: %v", "text/event-stream", ct)
+	}
+	r := bufio.NewReader(resp.Body)
+	readEvent := func() (string, Event) {
+		var name string
+		var e Event
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+			line = strings.TrimSpace(line)
+			if line == "" {
+				return name, e
+			}
+			if s, ok := strings.CutPrefix(line, "event: "); ok {
+				name = s
+			} else if s, ok := strings.CutPrefix(line, "data: "); ok {
+				json.Unmarshal([]byte(s), &e)
+			}
+		}
+	}
+	// The current states come first
+	if name, e := readEvent(); name != "state" || e != (Event{ID: "a", State: "stopped"}) {
+		t.Errorf("Expected: %v, got: %v %v", "state a stopped", name, e)
+	}
+	server.Publish(Event{Type: "state", ID: "a", State: "starting"})
+	if name, e := readEvent(); name != "state" || e != (Event{ID: "a", State: "starting"}) {
+		t.Errorf("Expected: %v, got: %v %v", "state a starting", name, e)
+	}
+}
@@ -5,20 +5,33 @@ import (
 	"os"
 
 	"github.com/hzcrv1911/frpcgui/pkg/consts"
+	"github.com/hzcrv1911/frpcgui/pkg/sec"
+	"github.com/hzcrv1911/frpcgui/pkg/util"
 )
 
 const (
 	DefaultAppFile = "app.json"
 	LangFile       = "lang.config"
+	// VaultKeyFile holds the key encrypting the secrets of configs.
+	VaultKeyFile = "vault.key"
 )
 
 type App struct {
 	Lang        string       `json:"lang,omitempty"`
 	Password    string       `json:"password,omitempty"`
+	Lockout     sec.Lockout  `json:"lockout,omitzero"`
+	SecretVault bool         `json:"secretVault,omitempty"`
 	CheckUpdate bool         `json:"checkUpdate"`
 	Defaults    DefaultValue `json:"defaults"`
-	Sort        []string     `json:"sort,omitempty"`
-	Position    []int32      `json:"position,omitempty"`
+	History     Retention    `json:"history"`
+	// Bundles decides which config bundles are accepted by imports and subscriptions
+	Bundles BundlePolicy `json:"bundles,omitzero"`
+	// API is the local HTTP API for automation
+	API APIServer `json:"api,omitzero"`
+	// Subscriptions of the configs, by config ID
+	Subscriptions map[string]*Subscription `json:"subscriptions,omitempty"`
+	Sort          []string                 `json:"sort,omitempty"`
+	Position      []int32                  `json:"position,omitempty"`
 }
 
 type DefaultValue struct {
@@ -32,7 +45,29 @@ type DefaultValue struct {
 	TCPMux               bool   `json:"tcpMux"`
 	TLSEnable            bool   `json:"tls"`
 	ManualStart          bool   `json:"manualStart,omitempty"`
-	LegacyFormat         bool   `json:"legacyFormat,omitempty"`
+	Format               string `json:"format,omitempty"`
+	// Deprecated: use Format instead.
+	LegacyFormat bool `json:"legacyFormat,omitempty"`
+}
+
+// APIServer is the settings of the local HTTP API.
+type APIServer struct {
+	Enabled bool `json:"enabled,omitempty"`
+	// Address is a loopback address to listen on, or "unix:" followed by the path of a Unix socket
+	Address string `json:"address,omitempty"`
+	// Token authenticates the requests, which send it as a bearer token
+	Token string `json:"token,omitempty"`
+}
+
+// FileFormat returns the file format of new configs.
+func (dv *DefaultValue) FileFormat() string {
+	if dv.Format != "" {
+		return dv.Format
+	}
+	if dv.LegacyFormat {
+		return consts.FormatINI
+	}
+	return consts.FormatTOML
 }
 
 func (dv *DefaultValue) AsClientConfig() ClientCommon {
@@ -48,7 +83,7 @@ func (dv *DefaultValue) AsClientConfig() ClientCommon {
 		TCPMux:                    dv.TCPMux,
 		TLSEnable:                 dv.TLSEnable,
 		ManualStart:               dv.ManualStart,
-		LegacyFormat:              dv.LegacyFormat,
+		Format:                    dv.FileFormat(),
 		DisableCustomTLSFirstByte: true,
 	}
 }
@@ -72,5 +107,5 @@ func (conf *App) Save(path string) error {
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(path, b, 0666)
+	return util.WriteFileAtomic(path, b, 0666)
 }
@@ -0,0 +1,264 @@
+package config
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hzcrv1911/frpcgui/pkg/sec"
+)
+
+// The files holding the manifest and its detached signature in a bundle.
+const (
+	BundleManifestFile  = "bundle.json"
+	BundleSignatureFile = "bundle.sig"
+)
+
+// The limits of a bundle, which is read before its signature is checked.
+const (
+	maxBundleFiles    = 256
+	maxBundleFileSize = 4 << 20
+	maxBundleSize     = 32 << 20
+)
+
+var (
+	// ErrUnsigned is returned for unsigned content if signed bundles are required.
+	ErrUnsigned = errors.New("the content is not a signed bundle")
+	// ErrUntrustedSigner is returned for a bundle signed by a key that isn't trusted.
+	ErrUntrustedSigner = errors.New("the bundle is signed by an untrusted key")
+	// ErrBadSignature is returned for a bundle whose signature doesn't match its manifest.
+	ErrBadSignature = errors.New("the signature of the bundle is invalid")
+	// ErrBundleTooLarge is returned for a zip file with too many or too large files.
+	ErrBundleTooLarge = errors.New("the bundle is too large")
+)
+
+// BundleManifest describes the files of a bundle. The manifest is the signed part of
+// a bundle, and the files are bound to it by their checksums.
+type BundleManifest struct {
+	Name    string    `json:"name,omitempty"`
+	Created time.Time `json:"created"`
+	// Signer is the public key of the signature, encoded by sec.EncodePublicKey.
+	Signer string `json:"signer"`
+	// Files maps the names of the files to their SHA-256 checksums in hex.
+	Files map[string]string `json:"files"`
+}
+
+// BundleFile is a file of a bundle.
+type BundleFile struct {
+	Name string
+	Data []byte
+}
+
+// Bundle is the content of a zip file that passed the bundle policy.
+type Bundle struct {
+	// Manifest is nil for an unsigned zip file.
+	Manifest *BundleManifest
+	// SignerName is the name of the trusted key the bundle is signed with.
+	SignerName string
+	Files      []BundleFile
+}
+
+// TrustedKey is a public key trusted to sign bundles.
+type TrustedKey struct {
+	Name string `json:"name,omitempty"`
+	Key  string `json:"key"`
+}
+
+// BundlePolicy decides which bundles are accepted.
+type BundlePolicy struct {
+	TrustedKeys []TrustedKey `json:"trustedKeys,omitempty"`
+	// RequireSigned rejects the content that isn't a bundle signed by a trusted key.
+	RequireSigned bool `json:"requireSigned,omitempty"`
+}
+
+// CreateBundle returns a zip file holding the files, along with a manifest signed by the key.
+func CreateBundle(name string, files []BundleFile, key ed25519.PrivateKey) ([]byte, error) {
+	manifest := BundleManifest{
+		Name:    name,
+		Created: time.Now().UTC(),
+		Signer:  sec.EncodePublicKey(key.Public().(ed25519.PublicKey)),
+		Files:   make(map[string]string, len(files)),
+	}
+	for _, file := range files {
+		if file.Name == BundleManifestFile || file.Name == BundleSignatureFile {
+			return nil, fmt.Errorf("the file name %s is reserved", file.Name)
+		}
+		if _, ok := manifest.Files[file.Name]; ok {
+			return nil, fmt.Errorf("duplicate file %s", file.Name)
+		}
+		sum := sha256.Sum256(file.Data)
+		manifest.Files[file.Name] = hex.EncodeToString(sum[:])
+	}
+	mb, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(key, mb))
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	files = append([]BundleFile{{BundleManifestFile, mb}, {BundleSignatureFile, []byte(signature)}}, files...)
+	for _, file := range files {
+		w, err := zw.Create(file.Name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = w.Write(file.Data); err != nil {
+			return nil, err
+		}
+	}
+	if err = zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// IsZip reports whether the data looks like a zip file.
+func IsZip(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("PK\x03\x04"))
+}
+
+// CheckUnsigned returns ErrUnsigned if the policy rejects content that isn't a bundle.
+func (p BundlePolicy) CheckUnsigned() error {
+	if p.RequireSigned {
+		return ErrUnsigned
+	}
+	return nil
+}
+
+// OpenBundle reads the bundle in the zip data, see ReadBundle.
+func (p BundlePolicy) OpenBundle(data []byte) (*Bundle, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	return p.ReadBundle(zr)
+}
+
+// ReadBundle reads the files of the zip file. A zip file with a manifest must be signed by
+// a trusted key, and only the files in the manifest are returned. A zip file without a
+// manifest is unsigned, and it's rejected if the policy requires signed bundles.
+func (p BundlePolicy) ReadBundle(zr *zip.Reader) (*Bundle, error) {
+	if len(zr.File) > maxBundleFiles {
+		return nil, ErrBundleTooLarge
+	}
+	files := make(map[string][]byte)
+	var names []string
+	var size uint64
+	for _, file := range zr.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		if size += file.UncompressedSize64; file.UncompressedSize64 > maxBundleFileSize || size > maxBundleSize {
+			return nil, ErrBundleTooLarge
+		}
+		data, err := readZipFile(file)
+		if err != nil {
+			return nil, err
+		}
+		files[file.Name] = data
+		names = append(names, file.Name)
+	}
+	mb, ok := files[BundleManifestFile]
+	if !ok {
+		if err := p.CheckUnsigned(); err != nil {
+			return nil, err
+		}
+		bundle := &Bundle{}
+		for _, name := range names {
+			bundle.Files = append(bundle.Files, BundleFile{name, files[name]})
+		}
+		return bundle, nil
+	}
+	var manifest BundleManifest
+	if err := json.Unmarshal(mb, &manifest); err != nil {
+		return nil, err
+	}
+	signer, err := sec.DecodePublicKey(manifest.Signer)
+	if err != nil {
+		return nil, err
+	}
+	i := slices.IndexFunc(p.TrustedKeys, func(key TrustedKey) bool {
+		k, err := sec.DecodePublicKey(key.Key)
+		return err == nil && k.Equal(signer)
+	})
+	if i < 0 {
+		return nil, ErrUntrustedSigner
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(files[BundleSignatureFile])))
+	if err != nil || !ed25519.Verify(signer, mb, signature) {
+		return nil, ErrBadSignature
+	}
+	bundle := &Bundle{Manifest: &manifest, SignerName: p.TrustedKeys[i].Name}
+	for _, name := range names {
+		sum, ok := manifest.Files[name]
+		if !ok {
+			continue
+		}
+		actual := sha256.Sum256(files[name])
+		if hex.EncodeToString(actual[:]) != sum {
+			return nil, fmt.Errorf("the file %s doesn't match the manifest of the bundle", name)
+		}
+		bundle.Files = append(bundle.Files, BundleFile{name, files[name]})
+	}
+	if len(bundle.Files) != len(manifest.Files) {
+		return nil, fmt.Errorf("the bundle is missing %d files of its manifest", len(manifest.Files)-len(bundle.Files))
+	}
+	return bundle, nil
+}
+
+// readZipFile reads a file of a bundle. The size in the header may be forged, so the
+// content is read up to the limit of a file.
+func readZipFile(file *zip.File) ([]byte, error) {
+	r, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	b, err := io.ReadAll(io.LimitReader(r, maxBundleFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > maxBundleFileSize {
+		return nil, ErrBundleTooLarge
+	}
+	return b, nil
+}
+
+// ParseTrustedKeys parses trusted keys, one per line, in the form of "<key> [name]".
+// Empty lines and lines starting with "#" are skipped.
+func ParseTrustedKeys(s string) ([]TrustedKey, error) {
+	var keys []TrustedKey
+	scanner := bufio.NewScanner(strings.NewReader(s))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		key, name, _ := strings.Cut(text, " ")
+		if _, err := sec.DecodePublicKey(key); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		keys = append(keys, TrustedKey{Name: strings.TrimSpace(name), Key: key})
+	}
+	return keys, scanner.Err()
+}
+
+// FormatTrustedKeys formats trusted keys in the form parsed by ParseTrustedKeys.
+func FormatTrustedKeys(keys []TrustedKey) string {
+	lines := make([]string, len(keys))
+	for i, key := range keys {
+		lines[i] = strings.TrimSpace(key.Key + " " + key.Name)
+	}
+	return strings.Join(lines, "\n")
+}
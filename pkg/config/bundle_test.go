@@ -0,0 +1,124 @@
+package config
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/hzcrv1911/frpcgui/pkg/sec"
+)
+
+func makeZip(t *testing.T, files []BundleFile) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, file := range files {
+		w, err := zw.Create(file.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(file.Data)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestBundle(t *testing.T) {
+	key, err := sec.GenerateSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _ := sec.GenerateSigningKey()
+	trusted := TrustedKey{Name: "ops", Key: sec.EncodePublicKey(key.Public().(ed25519.PublicKey))}
+	files := []BundleFile{{"home.toml", []byte("serverAddr = \"example.com\"\n")}, {"office.ini", []byte("[common]\n")}}
+	signed, err := CreateBundle("fleet", files, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsZip(signed) {
+		t.Errorf("Expected: %v, got: %v", true, false)
+	}
+	bundle, err := BundlePolicy{TrustedKeys: []TrustedKey{trusted}, RequireSigned: true}.OpenBundle(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(bundle.Files, files) || bundle.SignerName != "ops" || bundle.Manifest.Name != "fleet" {
+		t.Errorf("Expected: %v, got: %v", files, bundle)
+	}
+
+	unsigned := makeZip(t, files)
+	// A bundle whose file is replaced after signing
+	zr, err := zip.NewReader(bytes.NewReader(signed), int64(len(signed)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parts []BundleFile
+	for _, file := range zr.File[:2] {
+		data, _ := readZipFile(file)
+		parts = append(parts, BundleFile{file.Name, data})
+	}
+	tampered := makeZip(t, append(parts, BundleFile{"home.toml", []byte("serverAddr = \"evil.com\"\n")}, files[1]))
+	tests := []struct {
+		policy   BundlePolicy
+		input    []byte
+		expected error
+	}{
+		{policy: BundlePolicy{}, input: unsigned, expected: nil},
+		{policy: BundlePolicy{RequireSigned: true}, input: unsigned, expected: ErrUnsigned},
+		{policy: BundlePolicy{}, input: signed, expected: ErrUntrustedSigner},
+		{policy: BundlePolicy{TrustedKeys: []TrustedKey{{Key: sec.EncodePublicKey(other.Public().(ed25519.PublicKey))}}}, input: signed, expected: ErrUntrustedSigner},
+	}
+	for i, test := range tests {
+		if _, err := test.policy.OpenBundle(test.input); !errors.Is(err, test.expected) {
+			t.Errorf("Test %d: expected: %v, got: %v", i, test.expected, err)
+		}
+	}
+	if _, err = (BundlePolicy{TrustedKeys: []TrustedKey{trusted}}).OpenBundle(tampered); err == nil {
+		t.Errorf("Expected: %v, got: %v", "an error", err)
+	}
+}
+
+func TestParseTrustedKeys(t *testing.T) {
+	key, _ := sec.GenerateSigningKey()
+	pub := sec.EncodePublicKey(key.Public().(ed25519.PublicKey))
+	expected := []TrustedKey{{Name: "ops team", Key: pub}, {Key: pub}}
+	output, err := ParseTrustedKeys("# Signers\n" + pub + " ops team\n\n" + pub + "\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(output, expected) {
+		t.Errorf("Expected: %v, got: %v", expected, output)
+	}
+	if s := FormatTrustedKeys(expected); s != pub+" ops team\n"+pub {
+		t.Errorf("Expected: %v, got: %v", pub+" ops team\n"+pub, s)
+	}
+	if _, err = ParseTrustedKeys("invalid"); !errors.Is(err, sec.ErrSigningKey) {
+		t.Errorf("Expected: %v, got: %v", sec.ErrSigningKey, err)
+	}
+}
+
+func TestBundleLimits(t *testing.T) {
+	tests := []struct {
+		files []BundleFile
+		err   error
+	}{
+		{files: []BundleFile{{"a.toml", bytes.Repeat([]byte{' '}, maxBundleFileSize)}}},
+		{files: []BundleFile{{"a.toml", bytes.Repeat([]byte{' '}, maxBundleFileSize+1)}}, err: ErrBundleTooLarge},
+		{files: make([]BundleFile, maxBundleFiles+1), err: ErrBundleTooLarge},
+	}
+	for i, test := range tests {
+		for j := range test.files {
+			if test.files[j].Name == "" {
+				test.files[j].Name = fmt.Sprintf("%d.toml", j)
+			}
+		}
+		if _, err := (BundlePolicy{}).OpenBundle(makeZip(t, test.files)); !errors.Is(err, test.err) {
+			t.Errorf("Test %d: expected: %v, got: %v", i, test.err, err)
+		}
+	}
+}
@@ -1,10 +1,15 @@
 package config
 
 import (
+	"errors"
+	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/hzcrv1911/frpcgui/pkg/consts"
 )
 
 func TestUnmarshalClientConfFromIni(t *testing.T) {
@@ -26,7 +31,7 @@ func TestUnmarshalClientConfFromIni(t *testing.T) {
 		meta_2 = value
 	`
 	expected := NewDefaultClientConfig()
-	expected.LegacyFormat = true
+	expected.Format = consts.FormatINI
 	expected.ServerAddress = "example.com"
 	expected.ServerPort = 7001
 	expected.Token = "123456"
@@ -73,17 +78,216 @@ func TestProxyGetAlias(t *testing.T) {
 	}
 }
 
+func TestUnmarshalClientConfFromTOML(t *testing.T) {
+	input := `
+		serverAddr = "example.com"
+		serverPort = 7001
+		start = ["ssh", "stcp_visitor"]
+
+		[auth]
+		token = "123456"
+		additionalScopes = ["HeartBeats"]
+
+		[transport]
+		poolCount = 5
+
+		[transport.tls]
+		enable = false
+
+		[metadatas]
+		frpcgui_name = "test"
+		frpcgui_manual_start = "true"
+		1 = "value"
+
+		[[proxies]]
+		name = "ssh"
+		type = "tcp"
+		localIP = "192.168.1.1"
+		localPort = 22
+		remotePort = 6000
+		metadatas = { 2 = "value" }
+
+		[[proxies]]
+		name = "web"
+		type = "http"
+		localPort = 80
+		customDomains = ["a.example.com", "b.example.com"]
+		requestHeaders.set.x-from-where = "frp"
+
+		[[visitors]]
+		name = "stcp_visitor"
+		type = "stcp"
+		serverName = "secret_tcp"
+		secretKey = "abcdefg"
+		bindPort = 9000
+	`
+	expected := NewDefaultClientConfig()
+	expected.ClientCommon.Name = "test"
+	expected.ServerAddress = "example.com"
+	expected.ServerPort = 7001
+	expected.Token = "123456"
+	expected.AuthenticateHeartBeats = true
+	expected.PoolCount = 5
+	expected.TLSEnable = false
+	expected.LoginFailExit = true
+	expected.ManualStart = true
+	expected.Start = []string{"ssh", "stcp_visitor"}
+	expected.DeleteMethod = ""
+	expected.Metas = map[string]string{"1": "value"}
+	expected.Proxies = append(expected.Proxies, &Proxy{
+		BaseProxyConf: BaseProxyConf{
+			Name:      "ssh",
+			Type:      "tcp",
+			LocalIP:   "192.168.1.1",
+			LocalPort: "22",
+			Metas:     map[string]string{"2": "value"},
+		},
+		RemotePort: "6000",
+	}, &Proxy{
+		BaseProxyConf: BaseProxyConf{
+			Name:      "web",
+			Type:      "http",
+			LocalPort: "80",
+			Disabled:  true,
+		},
+		CustomDomains: "a.example.com,b.example.com",
+		Headers:       map[string]string{"x-from-where": "frp"},
+	}, &Proxy{
+		BaseProxyConf: BaseProxyConf{
+			Name: "stcp_visitor",
+			Type: "stcp",
+		},
+		Role:       "visitor",
+		SK:         "abcdefg",
+		ServerName: "secret_tcp",
+		BindPort:   9000,
+	})
+	cc, err := UnmarshalClientConf([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cc, expected) {
+		t.Errorf("Expected: %v, got: %v", expected, cc)
+	}
+}
+
 func TestClientConfigSaveTOML(t *testing.T) {
 	conf := NewDefaultClientConfig()
-	conf.LegacyFormat = false
+	conf.Format = consts.FormatTOML
 	conf.ClientCommon.Name = "test"
 	conf.ClientCommon.ServerAddress = "example.com"
 	conf.ClientCommon.Token = "token"
 	conf.ClientCommon.ServerPort = 7000
+	conf.Proxies = append(conf.Proxies, &Proxy{
+		BaseProxyConf: BaseProxyConf{Name: "ssh", Type: "tcp", LocalPort: "22"},
+		RemotePort:    "6000",
+	}, &Proxy{
+		BaseProxyConf: BaseProxyConf{Name: "visitor", Type: "xtcp"},
+		Role:          "visitor", ServerName: "ssh", BindPort: 6000,
+	})
 	conf.Complete(false)
 
 	path := filepath.Join(t.TempDir(), "test.conf")
 	if err := conf.Save(path); err != nil {
 		t.Fatalf("%T: %v", err, err)
 	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"serverAddr", "[auth]", "[[proxies]]", "[[visitors]]", "remotePort = 6000"} {
+		if !strings.Contains(string(b), key) {
+			t.Errorf("Expected %q in output:\n%s", key, b)
+		}
+	}
+	cc, err := UnmarshalClientConf(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cc, conf) {
+		t.Errorf("Expected: %v, got: %v", conf, cc)
+	}
+}
+
+func TestClientConfigSaveTOMLRange(t *testing.T) {
+	conf := NewDefaultClientConfig()
+	conf.Proxies = append(conf.Proxies, &Proxy{
+		BaseProxyConf: BaseProxyConf{Name: "range", Type: "tcp", LocalPort: "6000-6001"},
+		RemotePort:    "6000-6001",
+	})
+	if err := conf.Save(filepath.Join(t.TempDir(), "test.conf")); !errors.Is(err, ErrRangePort) {
+		t.Errorf("Expected: %v, got: %v", ErrRangePort, err)
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{input: "[common]\nserver_addr = example.com", expected: consts.FormatINI},
+		{input: "; comment\r\n  [common]  \r\nserver_port = 7000", expected: consts.FormatINI},
+		{input: "serverAddr = \"example.com\"\n# [common]", expected: consts.FormatTOML},
+		{input: "[[proxies]]\nname = \"ssh\"", expected: consts.FormatTOML},
+		{input: "serverAddr: example.com\nproxies:\n  - name: ssh", expected: consts.FormatYAML},
+		{input: "\xef\xbb\xbf{\"serverAddr\": \"example.com\"}", expected: consts.FormatJSON},
+		{input: "serverAddr = ", expected: consts.FormatTOML},
+	}
+	for i, test := range tests {
+		if output := DetectFormat([]byte(test.input)); output != test.expected {
+			t.Errorf("Test %d: Expected: %v, got: %v", i, test.expected, output)
+		}
+	}
+}
+
+func TestUnmarshalClientConfFormats(t *testing.T) {
+	expected := NewDefaultClientConfig()
+	expected.ServerAddress = "example.com"
+	expected.ServerPort = 7001
+	expected.Token = "123456"
+	expected.ManualStart = true
+	expected.LoginFailExit = true
+	expected.Proxies = append(expected.Proxies, &Proxy{
+		BaseProxyConf: BaseProxyConf{Name: "ssh", Type: "tcp", LocalIP: "192.168.1.1", LocalPort: "22"},
+		RemotePort:    "6000",
+	})
+	expected.Complete(true)
+	tests := []struct {
+		format string
+		input  string
+	}{
+		{format: consts.FormatYAML, input: `
+serverAddr: example.com
+serverPort: 7001
+auth:
+  token: "123456"
+metadatas:
+  frpcgui_manual_start: "true"
+proxies:
+  - name: ssh
+    type: tcp
+    localIP: 192.168.1.1
+    localPort: 22
+    remotePort: 6000
+`},
+		{format: consts.FormatJSON, input: `{
+  "serverAddr": "example.com",
+  "serverPort": 7001,
+  "auth": {"token": "123456"},
+  "metadatas": {"frpcgui_manual_start": "true"},
+  "proxies": [
+    {"name": "ssh", "type": "tcp", "localIP": "192.168.1.1", "localPort": 22, "remotePort": 6000}
+  ]
+}`},
+	}
+	for _, test := range tests {
+		expected.Format = test.format
+		cc, err := UnmarshalClientConf([]byte(test.input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(cc, expected) {
+			t.Errorf("Expected: %v, got: %v", expected, cc)
+		}
+	}
 }
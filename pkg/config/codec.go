@@ -0,0 +1,474 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+
+	"github.com/hzcrv1911/frpcgui/pkg/consts"
+)
+
+// The codec maps ClientCommon and Proxy to the client configuration schema introduced in frp v0.52.0.
+// Every field is described by a "toml" struct tag in the form of "path[,option]...", where path is the
+// dotted key of the option. The following options are supported:
+//
+//	omitempty     the key is omitted if the field has an empty value
+//	string        the value is stored as a string, as required by the metadatas table
+//	list          a comma-separated string is stored as an array of strings
+//	pairs         a map is stored as an array of {name, value} tables
+//	port          a port string is stored as an integer
+//	scope=<name>  a bool is stored as the presence of <name> in an array of strings
+//	default=<v>   the value used when the key is absent
+//
+// Manager-specific options are stored in the metadatas of a config, because frpc rejects
+// unknown top-level keys in strict mode.
+
+// ErrRangePort is returned when a range proxy is saved in a format that can't express it.
+var ErrRangePort = errors.New("range ports are only supported by the legacy INI format")
+
+var timeType = reflect.TypeOf(time.Time{})
+
+type fieldOptions struct {
+	path      string
+	omitEmpty bool
+	str       bool
+	list      bool
+	pairs     bool
+	port      bool
+	scope     string
+	def       string
+}
+
+func parseFieldOptions(tag string) fieldOptions {
+	parts := strings.Split(tag, ",")
+	opts := fieldOptions{path: parts[0]}
+	for _, opt := range parts[1:] {
+		name, value, _ := strings.Cut(opt, "=")
+		switch name {
+		case "omitempty":
+			opts.omitEmpty = true
+		case "string":
+			opts.str = true
+		case "list":
+			opts.list = true
+		case "pairs":
+			opts.pairs = true
+		case "port":
+			opts.port = true
+		case "scope":
+			opts.scope = value
+		case "default":
+			opts.def = value
+		}
+	}
+	return opts
+}
+
+// fieldFilter reports whether a field should be encoded or decoded.
+type fieldFilter func(field reflect.StructField) bool
+
+// pluginFilter skips plugin parameters that don't belong to the given plugin.
+func pluginFilter(plugin string) fieldFilter {
+	return func(field reflect.StructField) bool {
+		for _, p := range consts.PluginTypes {
+			if field.Tag.Get(p) == "true" {
+				return field.Tag.Get(plugin) == "true"
+			}
+		}
+		return true
+	}
+}
+
+// walkFields calls fn for every tagged field of the struct, including fields of embedded structs.
+func walkFields(v reflect.Value, filter fieldFilter, fn func(field reflect.StructField, fv reflect.Value, opts fieldOptions) error) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag, ok := field.Tag.Lookup("toml")
+		if tag == "-" {
+			continue
+		}
+		if field.Anonymous && !ok {
+			if err := walkFields(v.Field(i), filter, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if filter != nil && !filter(field) {
+			continue
+		}
+		if err := fn(field, v.Field(i), parseFieldOptions(tag)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeClientConfig converts the config to a document tree of the frp v0.52+ schema.
+func encodeClientConfig(conf *ClientConfig) (*table, error) {
+	root := newTable()
+	if !conf.proxyFile {
+		if err := encodeStruct(root, reflect.ValueOf(&conf.ClientCommon).Elem(), nil); err != nil {
+			return nil, err
+		}
+	}
+	for _, proxy := range conf.Proxies {
+		t := newTable()
+		if err := encodeStruct(t, reflect.ValueOf(proxy).Elem(), pluginFilter(proxy.Plugin)); err != nil {
+			return nil, fmt.Errorf("proxy [%s]: %w", proxy.Name, err)
+		}
+		if proxy.IsVisitor() {
+			root.append("visitors", t)
+		} else {
+			root.append("proxies", t)
+		}
+	}
+	return root, nil
+}
+
+func encodeStruct(t *table, v reflect.Value, filter fieldFilter) error {
+	return walkFields(v, filter, func(field reflect.StructField, fv reflect.Value, opts fieldOptions) error {
+		if opts.scope != "" {
+			if fv.Bool() {
+				t.append(opts.path, opts.scope)
+			}
+			return nil
+		}
+		if opts.omitEmpty && isEmptyValue(fv) {
+			return nil
+		}
+		value, err := encodeValue(fv, opts)
+		if err != nil {
+			return fmt.Errorf("%s: %w", opts.path, err)
+		}
+		if value != nil {
+			t.set(opts.path, value)
+		}
+		return nil
+	})
+}
+
+func encodeValue(fv reflect.Value, opts fieldOptions) (any, error) {
+	switch {
+	case opts.list:
+		return lo.ToAnySlice(splitList(fv.String())), nil
+	case opts.port:
+		ports, err := ParsePortSpec(fv.String())
+		if err != nil {
+			return nil, err
+		}
+		if ports.IsRange() {
+			return nil, ErrRangePort
+		}
+		if len(ports) == 0 {
+			return int64(0), nil
+		}
+		return int64(ports[0].Start), nil
+	case opts.pairs:
+		var pairs []any
+		for _, k := range sortedKeys(fv) {
+			pair := newTable()
+			pair.put("name", k)
+			pair.put("value", fv.MapIndex(reflect.ValueOf(k)).String())
+			pairs = append(pairs, pair)
+		}
+		return pairs, nil
+	}
+	if fv.Type() == timeType {
+		return fv.Interface().(time.Time).Format(time.RFC3339), nil
+	}
+	switch fv.Kind() {
+	case reflect.String:
+		return fv.String(), nil
+	case reflect.Bool:
+		if opts.str {
+			return strconv.FormatBool(fv.Bool()), nil
+		}
+		return fv.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if opts.str {
+			return strconv.FormatInt(fv.Int(), 10), nil
+		}
+		return fv.Int(), nil
+	case reflect.Slice:
+		return lo.ToAnySlice(fv.Interface().([]string)), nil
+	case reflect.Map:
+		m := newTable()
+		for _, k := range sortedKeys(fv) {
+			m.put(k, fv.MapIndex(reflect.ValueOf(k)).String())
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", fv.Type())
+}
+
+// decodeClientConfig converts a document tree of the frp v0.52+ schema to a config.
+func decodeClientConfig(doc map[string]any) (*ClientConfig, error) {
+	conf := NewDefaultClientConfig()
+	if err := decodeStruct(doc, reflect.ValueOf(&conf.ClientCommon).Elem(), nil); err != nil {
+		return nil, err
+	}
+	for _, key := range []string{"proxies", "visitors"} {
+		items, ok := doc[key]
+		if !ok {
+			continue
+		}
+		list, ok := items.([]any)
+		if !ok {
+			return nil, fmt.Errorf("%s: expected an array, got %T", key, items)
+		}
+		for i, item := range list {
+			m, ok := item.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%s[%d]: expected a table, got %T", key, i, item)
+			}
+			proxy := NewDefaultProxyConfig("")
+			plugin, _ := lookup(m, "plugin.type")
+			pluginType, _ := plugin.(string)
+			if err := decodeStruct(m, reflect.ValueOf(proxy).Elem(), pluginFilter(pluginType)); err != nil {
+				return nil, fmt.Errorf("%s[%d]: %w", key, i, err)
+			}
+			if key == "visitors" {
+				proxy.Role = "visitor"
+			}
+			conf.Proxies = append(conf.Proxies, proxy)
+		}
+	}
+	return conf, nil
+}
+
+func decodeStruct(doc map[string]any, v reflect.Value, filter fieldFilter) error {
+	// Keys of a map field are shared with the fields stored inside the same table
+	claimed := make(map[string]bool)
+	walkFields(v, filter, func(field reflect.StructField, fv reflect.Value, opts fieldOptions) error {
+		claimed[opts.path] = true
+		return nil
+	})
+	return walkFields(v, filter, func(field reflect.StructField, fv reflect.Value, opts fieldOptions) error {
+		raw, ok := lookup(doc, opts.path)
+		if !ok {
+			if opts.def != "" {
+				raw = opts.def
+				opts.str = true
+			} else {
+				return nil
+			}
+		}
+		if err := decodeValue(raw, fv, opts, claimed); err != nil {
+			return fmt.Errorf("%s: %w", opts.path, err)
+		}
+		return nil
+	})
+}
+
+func decodeValue(raw any, fv reflect.Value, opts fieldOptions, claimed map[string]bool) error {
+	switch {
+	case opts.scope != "":
+		items, err := toStrings(raw)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(slices.Contains(items, opts.scope))
+		return nil
+	case opts.list:
+		items, err := toStrings(raw)
+		if err != nil {
+			return err
+		}
+		fv.SetString(strings.Join(items, ","))
+		return nil
+	case opts.port:
+		port, err := toInt(raw)
+		if err != nil {
+			return err
+		}
+		fv.SetString(formatPort(int(port)))
+		return nil
+	case opts.pairs:
+		items, ok := raw.([]any)
+		if !ok {
+			return fmt.Errorf("expected an array, got %T", raw)
+		}
+		m := make(map[string]string)
+		for _, item := range items {
+			pair, ok := item.(map[string]any)
+			if !ok {
+				return fmt.Errorf("expected a table, got %T", item)
+			}
+			name, _ := pair["name"].(string)
+			value, _ := pair["value"].(string)
+			m[name] = value
+		}
+		if len(m) > 0 {
+			fv.Set(reflect.ValueOf(m))
+		}
+		return nil
+	}
+	if fv.Type() == timeType {
+		s, ok := raw.(string)
+		if !ok {
+			return fmt.Errorf("expected a string, got %T", raw)
+		}
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return err
+		}
+		fv.Set(reflect.ValueOf(t))
+		return nil
+	}
+	switch fv.Kind() {
+	case reflect.String:
+		s, ok := raw.(string)
+		if !ok {
+			return fmt.Errorf("expected a string, got %T", raw)
+		}
+		fv.SetString(s)
+	case reflect.Bool:
+		if s, ok := raw.(string); ok && opts.str {
+			b, err := strconv.ParseBool(s)
+			if err != nil {
+				return err
+			}
+			fv.SetBool(b)
+			return nil
+		}
+		b, ok := raw.(bool)
+		if !ok {
+			return fmt.Errorf("expected a boolean, got %T", raw)
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if s, ok := raw.(string); ok && opts.str {
+			n, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return err
+			}
+			fv.SetInt(n)
+			return nil
+		}
+		n, err := toInt(raw)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Slice:
+		items, err := toStrings(raw)
+		if err != nil {
+			return err
+		}
+		fv.Set(reflect.ValueOf(items))
+	case reflect.Map:
+		table, ok := raw.(map[string]any)
+		if !ok {
+			return fmt.Errorf("expected a table, got %T", raw)
+		}
+		m := make(map[string]string)
+		for k, v := range table {
+			if claimed[opts.path+"."+k] {
+				continue
+			}
+			m[k] = fmt.Sprint(v)
+		}
+		if len(m) > 0 {
+			fv.Set(reflect.ValueOf(m))
+		}
+	default:
+		return fmt.Errorf("unsupported type %s", fv.Type())
+	}
+	return nil
+}
+
+// lookup finds the value of a dotted key in the document tree.
+func lookup(doc map[string]any, path string) (any, bool) {
+	var cur any = doc
+	for _, key := range strings.Split(path, ".") {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+func toInt(raw any) (int64, error) {
+	switch n := raw.(type) {
+	case int64:
+		return n, nil
+	case int:
+		return int64(n), nil
+	case uint64:
+		return int64(n), nil
+	case float64:
+		if n != float64(int64(n)) {
+			return 0, fmt.Errorf("expected an integer, got %v", n)
+		}
+		return int64(n), nil
+	}
+	return 0, fmt.Errorf("expected an integer, got %T", raw)
+}
+
+func toStrings(raw any) ([]string, error) {
+	items, ok := raw.([]any)
+	if !ok {
+		return nil, fmt.Errorf("expected an array, got %T", raw)
+	}
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a string, got %T", item)
+		}
+		result = append(result, s)
+	}
+	return result, nil
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+func sortedKeys(m reflect.Value) []string {
+	keys := make([]string, 0, m.Len())
+	for _, k := range m.MapKeys() {
+		keys = append(keys, k.String())
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// isRangePort reports whether the port string is written as a port list or a port range.
+func isRangePort(s string) bool {
+	return strings.ContainsAny(s, ",-")
+}
+
+func formatPort(port int) string {
+	if port == 0 {
+		return ""
+	}
+	return strconv.Itoa(port)
+}
+
+// splitList splits a comma-separated list, ignoring empty items.
+func splitList(s string) []string {
+	return lo.Filter(lo.Map(strings.Split(s, ","), func(item string, i int) string {
+		return strings.TrimSpace(item)
+	}), func(item string, i int) bool {
+		return item != ""
+	})
+}
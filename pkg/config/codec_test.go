@@ -0,0 +1,122 @@
+package config
+
+import (
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hzcrv1911/frpcgui/pkg/consts"
+)
+
+// fillRandom sets every exported field of the struct to a random non-zero value.
+func fillRandom(r *rand.Rand, v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fv := v.Field(i)
+		if field.Type == timeType {
+			fv.Set(reflect.ValueOf(time.Unix(r.Int63n(1<<32), 0).UTC()))
+			continue
+		}
+		switch fv.Kind() {
+		case reflect.Struct:
+			fillRandom(r, fv)
+		case reflect.String:
+			fv.SetString(randomWord(r))
+		case reflect.Bool:
+			fv.SetBool(r.Intn(2) == 0)
+		case reflect.Int, reflect.Int64:
+			fv.SetInt(r.Int63n(65535) + 1)
+		case reflect.Slice:
+			fv.Set(reflect.ValueOf([]string{randomWord(r), randomWord(r)}))
+		case reflect.Map:
+			m := make(map[string]string)
+			for j := r.Intn(3) + 1; j > 0; j-- {
+				m[randomWord(r)] = randomWord(r) + " \"quoted\" value"
+			}
+			fv.Set(reflect.ValueOf(m))
+		}
+	}
+}
+
+func randomWord(r *rand.Rand) string {
+	const letters = "abcdefghijklmnopqrstuvwxyz0123456789-."
+	b := make([]byte, r.Intn(8)+4)
+	for i := range b {
+		b[i] = letters[r.Intn(len(letters))]
+	}
+	return "w" + string(b)
+}
+
+func pick(r *rand.Rand, items ...string) string {
+	return items[r.Intn(len(items))]
+}
+
+func randomClientConfig(r *rand.Rand) *ClientConfig {
+	conf := NewDefaultClientConfig()
+	fillRandom(r, reflect.ValueOf(&conf.ClientCommon).Elem())
+	conf.AuthMethod = pick(r, consts.AuthToken, consts.AuthOIDC)
+	conf.TokenSource = pick(r, "", "file")
+	conf.Protocol = pick(r, consts.Protocols...)
+	conf.LogLevel = pick(r, consts.LogLevels...)
+	conf.DeleteMethod = pick(r, consts.DeleteAbsolute, consts.DeleteRelative)
+	conf.Format = pick(r, consts.FormatTOML, consts.FormatYAML, consts.FormatJSON)
+	// Included files are covered by TestIncludes
+	conf.Includes = nil
+	for i := r.Intn(8) + 1; i > 0; i-- {
+		proxy := NewDefaultProxyConfig("")
+		fillRandom(r, reflect.ValueOf(proxy).Elem())
+		proxy.Name = fmt.Sprintf("proxy_%d_%s", i, proxy.Name)
+		proxy.Type = pick(r, consts.ProxyTypes...)
+		proxy.Role = pick(r, "", "visitor")
+		proxy.Plugin = pick(r, append([]string{""}, consts.PluginTypes...)...)
+		proxy.HealthCheckType = pick(r, "", "tcp", "http")
+		proxy.LocalPort = strconv.Itoa(r.Intn(65535) + 1)
+		proxy.RemotePort = strconv.Itoa(r.Intn(65535) + 1)
+		proxy.CustomDomains = strings.Join([]string{randomWord(r), randomWord(r)}, ",")
+		proxy.Locations = "/" + randomWord(r)
+		proxy.AllowUsers = randomWord(r)
+		proxy.Source = ""
+		conf.Proxies = append(conf.Proxies, proxy)
+	}
+	// frpc starts all proxies when the start list is empty
+	conf.Proxies[0].Disabled = false
+	conf.Complete(false)
+	return conf
+}
+
+func TestClientConfigRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	dir := t.TempDir()
+	for i := 0; i < 500; i++ {
+		conf := randomClientConfig(r)
+		path := filepath.Join(dir, "test.conf")
+		if err := conf.Save(path); err != nil {
+			t.Fatal(err)
+		}
+		cc, err := UnmarshalClientConf(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Proxies are written before visitors
+		var expected []*Proxy
+		for _, visitor := range []bool{false, true} {
+			for _, proxy := range conf.Proxies {
+				if proxy.IsVisitor() == visitor {
+					expected = append(expected, proxy)
+				}
+			}
+		}
+		conf.Proxies = expected
+		if !reflect.DeepEqual(cc, conf) {
+			t.Fatalf("Expected: %+v, got: %+v", conf, cc)
+		}
+	}
+}
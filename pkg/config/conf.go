@@ -19,11 +19,11 @@ type AutoDelete struct {
 	// DeleteMethod specifies what delete method to use to delete the config.
 	// If "absolute" is specified, the expiry date is set in config. If "relative" is specified, the expiry date
 	// is calculated by adding the days to the file modification time. If it's empty, the config has no expiry date.
-	DeleteMethod string `ini:"frpcgui_delete_method,omitempty" json:"method,omitempty"`
+	DeleteMethod string `ini:"frpcgui_delete_method,omitempty" toml:"metadatas.frpcgui_delete_method,omitempty" json:"method,omitempty"`
 	// DeleteAfterDays is the number of days a config will be kept, after which it may be stopped and deleted.
-	DeleteAfterDays int64 `ini:"frpcgui_delete_after_days,omitempty" relative:"true" json:"afterDays,omitempty"`
+	DeleteAfterDays int64 `ini:"frpcgui_delete_after_days,omitempty" toml:"metadatas.frpcgui_delete_after_days,omitempty,string" relative:"true" json:"afterDays,omitempty"`
 	// DeleteAfterDate is the last date the config will be valid, after which it may be stopped and deleted.
-	DeleteAfterDate time.Time `ini:"frpcgui_delete_after_date,omitempty" absolute:"true" json:"afterDate,omitempty"`
+	DeleteAfterDate time.Time `ini:"frpcgui_delete_after_date,omitempty" toml:"metadatas.frpcgui_delete_after_date,omitempty" absolute:"true" json:"afterDate,omitempty"`
 }
 
 func (ad AutoDelete) Complete() AutoDelete {
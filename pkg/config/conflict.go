@@ -0,0 +1,235 @@
+package config
+
+import (
+	"cmp"
+	"maps"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hzcrv1911/frpcgui/pkg/consts"
+)
+
+// Kinds of conflicts
+const (
+	// ConflictProxyName means proxies of the same user share a name on a server.
+	ConflictProxyName = "proxy_name"
+	// ConflictRemotePort means proxies listen on the same port of a server.
+	ConflictRemotePort = "remote_port"
+	// ConflictRoute means http, https or tcpmux proxies route the same domain and location.
+	ConflictRoute = "route"
+	// ConflictLocalPort means visitors or admin servers listen on the same local address.
+	ConflictLocalPort = "local_port"
+)
+
+// ConflictClaim is an option claiming a conflicting resource.
+type ConflictClaim struct {
+	// Config is the name of the config.
+	Config string `json:"config"`
+	// Path of the option, named like the paths of diagnostics.
+	Path string `json:"path"`
+}
+
+func (c ConflictClaim) String() string {
+	return c.Config + ": " + c.Path
+}
+
+// Conflict is a resource claimed more than once, which makes all but one of the
+// claiming proxies fail at runtime.
+type Conflict struct {
+	Kind string `json:"kind"`
+	// Server is the address of the frps server owning the resource. It's empty
+	// for addresses of the local machine.
+	Server   string          `json:"server,omitempty"`
+	Resource string          `json:"resource"`
+	Claims   []ConflictClaim `json:"claims"`
+}
+
+func (c Conflict) String() string {
+	var b strings.Builder
+	if c.Server != "" {
+		b.WriteString(c.Server + ": ")
+	}
+	b.WriteString(c.Resource + " is claimed by ")
+	for i, claim := range c.Claims {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(claim.String())
+	}
+	return b.String()
+}
+
+// Involves reports whether the config of the name claims the resource.
+func (c Conflict) Involves(name string) bool {
+	return slices.ContainsFunc(c.Claims, func(claim ConflictClaim) bool { return claim.Config == name })
+}
+
+// conflictKey identifies a claimed resource. The port is kept apart from the resource
+// of remote ports, so that consecutive ports can be reported as a range.
+type conflictKey struct {
+	kind     string
+	server   string
+	resource string
+	port     int
+}
+
+type conflictClaims struct {
+	keys   []conflictKey
+	claims map[conflictKey][]ConflictClaim
+}
+
+func (cc *conflictClaims) add(key conflictKey, claim ConflictClaim) {
+	if _, ok := cc.claims[key]; !ok {
+		cc.keys = append(cc.keys, key)
+	}
+	if !slices.Contains(cc.claims[key], claim) {
+		cc.claims[key] = append(cc.claims[key], claim)
+	}
+}
+
+// FindConflicts reports the resources claimed more than once by the enabled proxies
+// of the configs. Configs connecting to the same server share its ports and routes,
+// and proxy names are shared by the configs of the same user. Visitors and admin
+// servers of all configs share the addresses of the local machine.
+func FindConflicts(confs []*ClientConfig) []Conflict {
+	cc := &conflictClaims{claims: make(map[conflictKey][]ConflictClaim)}
+	for _, conf := range confs {
+		server := net.JoinHostPort(strings.ToLower(conf.ServerAddress), strconv.Itoa(conf.ServerPort))
+		if conf.AdminPort > 0 {
+			cc.add(localKey("tcp", conf.AdminAddr, conf.AdminPort), ConflictClaim{conf.Name(), "admin_port"})
+		}
+		for i, proxy := range conf.Proxies {
+			if proxy.Disabled {
+				continue
+			}
+			claim := func(key string) ConflictClaim {
+				return ConflictClaim{conf.Name(), proxyPath(i, proxy) + "." + key}
+			}
+			if proxy.IsVisitor() {
+				if proxy.BindPort > 0 {
+					proto := "tcp"
+					if proxy.Type == consts.ProxyTypeSUDP {
+						proto = "udp"
+					}
+					cc.add(localKey(proto, proxy.BindAddr, proxy.BindPort), claim("bind_port"))
+				}
+				continue
+			}
+			for _, alias := range proxy.GetAlias() {
+				if conf.User != "" {
+					alias = conf.User + "." + alias
+				}
+				cc.add(conflictKey{kind: ConflictProxyName, server: server, resource: "proxy " + alias}, claim("name"))
+			}
+			switch proxy.Type {
+			case consts.ProxyTypeTCP, consts.ProxyTypeUDP:
+				ports, err := proxy.RemotePorts()
+				if err != nil {
+					continue
+				}
+				for _, port := range ports.Ports() {
+					// Zero asks the server for a random port
+					if port > 0 {
+						cc.add(conflictKey{kind: ConflictRemotePort, server: server, resource: proxy.Type, port: port}, claim("remote_port"))
+					}
+				}
+			case consts.ProxyTypeHTTP, consts.ProxyTypeHTTPS, consts.ProxyTypeTCPMUX:
+				for _, route := range proxy.routes() {
+					cc.add(conflictKey{kind: ConflictRoute, server: server, resource: route}, claim("custom_domains"))
+				}
+			}
+		}
+	}
+	return cc.conflicts()
+}
+
+// localKey returns the key of a local address. All wildcard addresses are written as
+// "0.0.0.0", since Go listens on both IPv4 and IPv6 for "::".
+func localKey(proto, addr string, port int) conflictKey {
+	if addr == "" {
+		addr = "127.0.0.1"
+	} else if isWildcardAddr(addr) {
+		addr = "0.0.0.0"
+	}
+	return conflictKey{kind: ConflictLocalPort, resource: proto + " " + addr, port: port}
+}
+
+func isWildcardAddr(addr string) bool {
+	ip := net.ParseIP(addr)
+	return ip != nil && ip.IsUnspecified()
+}
+
+// routes returns the routes of an http, https or tcpmux proxy. Subdomains are relative
+// to the subdomain host of the server, which is written as "*".
+func (p *Proxy) routes() []string {
+	domains := splitList(p.CustomDomains)
+	if p.SubDomain != "" {
+		domains = append(domains, p.SubDomain+".*")
+	}
+	locations := []string{""}
+	if p.Type == consts.ProxyTypeHTTP && p.Locations != "" {
+		locations = splitList(p.Locations)
+	}
+	var routes []string
+	for _, domain := range domains {
+		for _, location := range locations {
+			route := p.Type + " " + strings.ToLower(domain)
+			if p.Type == consts.ProxyTypeHTTP {
+				route += cmp.Or(location, "/")
+			}
+			if p.RouteByHTTPUser != "" && p.Type != consts.ProxyTypeHTTPS {
+				route += " (user " + p.RouteByHTTPUser + ")"
+			}
+			routes = append(routes, route)
+		}
+	}
+	return routes
+}
+
+// conflicts returns the resources with several claims, sorted by server, kind and resource.
+func (cc *conflictClaims) conflicts() []Conflict {
+	claims := maps.Clone(cc.claims)
+	// A wildcard address overlaps the other addresses of the same port
+	for _, key := range cc.keys {
+		proto, addr, _ := strings.Cut(key.resource, " ")
+		if key.kind != ConflictLocalPort || addr != "0.0.0.0" {
+			continue
+		}
+		for _, other := range cc.keys {
+			if other.kind == ConflictLocalPort && other.port == key.port && other != key &&
+				strings.HasPrefix(other.resource, proto+" ") {
+				claims[other] = append(slices.Clone(claims[other]), cc.claims[key]...)
+			}
+		}
+	}
+	keys := slices.DeleteFunc(slices.Clone(cc.keys), func(key conflictKey) bool { return len(claims[key]) < 2 })
+	slices.SortFunc(keys, func(a, b conflictKey) int {
+		return cmp.Or(cmp.Compare(a.server, b.server), cmp.Compare(a.kind, b.kind),
+			cmp.Compare(a.resource, b.resource), cmp.Compare(a.port, b.port))
+	})
+	var conflicts []Conflict
+	var ports PortRange
+	for i, key := range keys {
+		// Consecutive remote ports claimed by the same options are reported as a range
+		if i > 0 && key.kind == ConflictRemotePort && keys[i-1].kind == ConflictRemotePort &&
+			key.server == keys[i-1].server && key.resource == keys[i-1].resource &&
+			key.port == ports.End+1 && slices.Equal(claims[key], claims[keys[i-1]]) {
+			ports.End = key.port
+			conflicts[len(conflicts)-1].Resource = key.resource + " " + ports.String()
+			continue
+		}
+		resource := key.resource
+		switch key.kind {
+		case ConflictRemotePort:
+			ports = PortRange{key.port, key.port}
+			resource = key.resource + " " + ports.String()
+		case ConflictLocalPort:
+			proto, addr, _ := strings.Cut(key.resource, " ")
+			resource = proto + " " + net.JoinHostPort(addr, strconv.Itoa(key.port))
+		}
+		conflicts = append(conflicts, Conflict{Kind: key.kind, Server: key.server, Resource: resource, Claims: claims[key]})
+	}
+	return conflicts
+}
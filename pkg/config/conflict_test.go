@@ -0,0 +1,121 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindConflicts(t *testing.T) {
+	a := mustUnmarshal(t, `
+		serverAddr = "Example.com"
+		metadatas.frpcgui_name = "a"
+		webServer.port = 7400
+		[[proxies]]
+		name = "ssh"
+		type = "tcp"
+		localPort = 22
+		remotePort = 6000
+		[[proxies]]
+		name = "web"
+		type = "http"
+		localPort = 80
+		customDomains = ["example.com"]
+		locations = ["/api", "/"]
+		[[visitors]]
+		name = "secret"
+		type = "stcp"
+		serverName = "secret"
+		bindAddr = "0.0.0.0"
+		bindPort = 9000
+	`)
+	b := mustUnmarshal(t, `
+		serverAddr = "example.com"
+		metadatas.frpcgui_name = "b"
+		webServer.port = 7400
+		[[proxies]]
+		name = "ssh"
+		type = "tcp"
+		localPort = 22
+		remotePort = 6001
+		[[proxies]]
+		name = "web"
+		type = "http"
+		localPort = 8080
+		customDomains = ["EXAMPLE.com"]
+		[[proxies]]
+		name = "api"
+		type = "http"
+		localPort = 8081
+		customDomains = ["example.com"]
+		locations = ["/api"]
+		routeByHTTPUser = "admin"
+		[[visitors]]
+		name = "secret"
+		type = "stcp"
+		serverName = "secret"
+		bindPort = 9000
+	`)
+	// Another user on another server, with a range proxy
+	c := mustUnmarshal(t, `
+		[common]
+		server_addr = example.com
+		user = alice
+		frpcgui_name = c
+		[range:ports]
+		type = tcp
+		local_port = 6000-6005
+		remote_port = 6000-6005
+		[ssh]
+		type = tcp
+		local_port = 22
+		remote_port = 6003
+	`)
+	c.ServerPort = 7001
+	d := mustUnmarshal(t, `
+		[common]
+		server_addr = example.com
+		server_port = 7001
+		frpcgui_name = d
+		[ports]
+		type = tcp
+		local_port = 22
+		remote_port = 6001
+		[disabled]
+		type = tcp
+		local_port = 22
+		remote_port = 6004
+	`)
+	d.Proxies[1].Disabled = true
+
+	expected := []Conflict{
+		{Kind: ConflictLocalPort, Resource: "tcp 127.0.0.1:7400", Claims: []ConflictClaim{{"a", "admin_port"}, {"b", "admin_port"}}},
+		{Kind: ConflictLocalPort, Resource: "tcp 127.0.0.1:9000", Claims: []ConflictClaim{{"b", "visitors[secret].bind_port"}, {"a", "visitors[secret].bind_port"}}},
+		{Kind: ConflictProxyName, Server: "example.com:7000", Resource: "proxy ssh", Claims: []ConflictClaim{{"a", "proxies[ssh].name"}, {"b", "proxies[ssh].name"}}},
+		{Kind: ConflictProxyName, Server: "example.com:7000", Resource: "proxy web", Claims: []ConflictClaim{{"a", "proxies[web].name"}, {"b", "proxies[web].name"}}},
+		{Kind: ConflictRoute, Server: "example.com:7000", Resource: "http example.com/", Claims: []ConflictClaim{{"a", "proxies[web].custom_domains"}, {"b", "proxies[web].custom_domains"}}},
+		{Kind: ConflictRemotePort, Server: "example.com:7001", Resource: "tcp 6001", Claims: []ConflictClaim{{"c", "proxies[ports].remote_port"}, {"d", "proxies[ports].remote_port"}}},
+		{Kind: ConflictRemotePort, Server: "example.com:7001", Resource: "tcp 6003", Claims: []ConflictClaim{{"c", "proxies[ports].remote_port"}, {"c", "proxies[ssh].remote_port"}}},
+	}
+	if actual := FindConflicts([]*ClientConfig{a, b, c, d}); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected: %v, got: %v", expected, actual)
+	}
+
+	// Consecutive ports are reported as a range
+	d.Proxies[0].RemotePort = "6002-6004"
+	d.Proxies[0].LocalPort = "22-24"
+	expected = []Conflict{
+		{Kind: ConflictRemotePort, Server: "example.com:7001", Resource: "tcp 6002", Claims: []ConflictClaim{{"c", "proxies[ports].remote_port"}, {"d", "proxies[ports].remote_port"}}},
+		{Kind: ConflictRemotePort, Server: "example.com:7001", Resource: "tcp 6003", Claims: []ConflictClaim{{"c", "proxies[ports].remote_port"}, {"c", "proxies[ssh].remote_port"}, {"d", "proxies[ports].remote_port"}}},
+		{Kind: ConflictRemotePort, Server: "example.com:7001", Resource: "tcp 6004", Claims: []ConflictClaim{{"c", "proxies[ports].remote_port"}, {"d", "proxies[ports].remote_port"}}},
+	}
+	if actual := FindConflicts([]*ClientConfig{c, d}); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected: %v, got: %v", expected, actual)
+	}
+	c.Proxies = c.Proxies[:1]
+	expected = []Conflict{
+		{Kind: ConflictRemotePort, Server: "example.com:7001", Resource: "tcp 6002-6004", Claims: []ConflictClaim{{"c", "proxies[ports].remote_port"}, {"d", "proxies[ports].remote_port"}}},
+	}
+	if actual := FindConflicts([]*ClientConfig{c, d}); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected: %v, got: %v", expected, actual)
+	}
+}
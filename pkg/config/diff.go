@@ -0,0 +1,270 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Change is an option whose value differs between two configs.
+type Change struct {
+	// Path of the option, named like the paths of diagnostics.
+	Path string `json:"path"`
+	Old  any    `json:"old"`
+	New  any    `json:"new"`
+}
+
+// String formats the change, with the values of secrets masked.
+func (c Change) String() string {
+	if isSecretPath(c.Path) {
+		return fmt.Sprintf("%s: %s -> %s", c.Path, maskDiffValue(c.Old), maskDiffValue(c.New))
+	}
+	return fmt.Sprintf("%s: %s -> %s", c.Path, formatDiffValue(c.Old), formatDiffValue(c.New))
+}
+
+// ConfigDiff is the semantic difference between two configs. Proxies are matched
+// by name, or by their aliases, so the order of proxies is not a difference.
+type ConfigDiff struct {
+	// Common lists the changed common options.
+	Common []Change `json:"common,omitempty"`
+	// Added lists the paths of the proxies only found in the new config.
+	Added []string `json:"added,omitempty"`
+	// Removed lists the paths of the proxies only found in the old config.
+	Removed []string `json:"removed,omitempty"`
+	// Modified lists the changed options of the proxies found in both configs.
+	Modified []Change `json:"modified,omitempty"`
+}
+
+// Empty reports whether the configs are equivalent.
+func (d *ConfigDiff) Empty() bool {
+	return len(d.Common) == 0 && len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Modified) == 0
+}
+
+// Reloadable reports whether frpc applies the changes by reloading its config, which
+// only reloads the proxies and visitors. The metadata of the config is ignored by frpc.
+func (d *ConfigDiff) Reloadable() bool {
+	return !slices.ContainsFunc(d.Common, func(c Change) bool {
+		return !strings.HasPrefix(c.Path, "frpcgui_") && c.Path != "metadatas"
+	})
+}
+
+func (d *ConfigDiff) String() string {
+	var lines []string
+	for _, c := range d.Common {
+		lines = append(lines, "  "+c.String())
+	}
+	for _, path := range d.Added {
+		lines = append(lines, "+ "+path)
+	}
+	for _, path := range d.Removed {
+		lines = append(lines, "- "+path)
+	}
+	for _, c := range d.Modified {
+		lines = append(lines, "  "+c.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Diff computes the changes turning the old config into the new one.
+func Diff(old, new *ClientConfig) *ConfigDiff {
+	d := &ConfigDiff{}
+	d.Common = diffStruct("", reflect.ValueOf(old.ClientCommon), reflect.ValueOf(new.ClientCommon))
+	matched := matchProxies(old.Proxies, new.Proxies)
+	newMatched := invert(matched)
+	for i, proxy := range old.Proxies {
+		if j, ok := matched[i]; ok {
+			d.Modified = append(d.Modified, diffStruct(proxyPath(j, new.Proxies[j])+".",
+				reflect.ValueOf(*proxy), reflect.ValueOf(*new.Proxies[j]))...)
+		} else {
+			d.Removed = append(d.Removed, proxyPath(i, proxy))
+		}
+	}
+	for j, proxy := range new.Proxies {
+		if _, ok := newMatched[j]; !ok {
+			d.Added = append(d.Added, proxyPath(j, proxy))
+		}
+	}
+	return d
+}
+
+func diffStruct(prefix string, old, new reflect.Value) []Change {
+	var changes []Change
+	oldFields, newFields := comparableFields(old), comparableFields(new)
+	for i, f := range oldFields {
+		if a, b := f.value.Interface(), newFields[i].value.Interface(); !reflect.DeepEqual(a, b) {
+			changes = append(changes, Change{Path: prefix + fieldKey(f.field), Old: a, New: b})
+		}
+	}
+	return changes
+}
+
+// MergeConflict is an option changed differently on both sides of a merge.
+// A proxy removed on one side and modified on the other is reported by its path,
+// with a nil value on the side it's missing from.
+type MergeConflict struct {
+	Path   string `json:"path"`
+	Base   any    `json:"base"`
+	Local  any    `json:"local"`
+	Remote any    `json:"remote"`
+}
+
+func (c MergeConflict) String() string {
+	return fmt.Sprintf("%s: local %s, remote %s (base %s)", c.Path,
+		formatDiffValue(c.Local), formatDiffValue(c.Remote), formatDiffValue(c.Base))
+}
+
+// Merge applies the changes made from base to remote onto local, and returns the merged
+// config. Options changed differently on both sides are conflicts, for which the local
+// value is kept. If base is nil, remote changes win and no proxy is removed.
+// The given configs are not modified.
+func Merge(base, local, remote *ClientConfig) (*ClientConfig, []MergeConflict) {
+	if base == nil {
+		// Proxies found on both sides are considered unchanged locally
+		base = &ClientConfig{ClientCommon: local.ClientCommon}
+		matched := matchProxies(local.Proxies, remote.Proxies)
+		for i, proxy := range local.Proxies {
+			if _, ok := matched[i]; ok {
+				base.Proxies = append(base.Proxies, proxy)
+			}
+		}
+	}
+	var conflicts []MergeConflict
+	merged := &ClientConfig{ClientCommon: local.ClientCommon}
+	mergeStruct("", reflect.ValueOf(base.ClientCommon), reflect.ValueOf(remote.ClientCommon),
+		reflect.ValueOf(&merged.ClientCommon).Elem(), &conflicts)
+
+	baseLocal := matchProxies(base.Proxies, local.Proxies)
+	baseRemote := matchProxies(base.Proxies, remote.Proxies)
+	remoteLocal := matchProxies(remote.Proxies, local.Proxies)
+	localBase, remoteBase, localRemote := invert(baseLocal), invert(baseRemote), invert(remoteLocal)
+	for j, proxy := range local.Proxies {
+		path := proxyPath(j, proxy)
+		p := *proxy
+		if i, ok := localBase[j]; !ok {
+			// Added locally, maybe also remotely
+			if k, ok := localRemote[j]; ok && !reflect.DeepEqual(*remote.Proxies[k], p) {
+				if _, inBase := remoteBase[k]; !inBase {
+					conflicts = append(conflicts, MergeConflict{Path: path, Local: proxy, Remote: remote.Proxies[k]})
+				}
+			}
+		} else if k, ok := baseRemote[i]; !ok {
+			// Removed remotely
+			if reflect.DeepEqual(*base.Proxies[i], p) {
+				continue
+			}
+			conflicts = append(conflicts, MergeConflict{Path: path, Base: base.Proxies[i], Local: proxy})
+		} else {
+			mergeStruct(path+".", reflect.ValueOf(*base.Proxies[i]), reflect.ValueOf(*remote.Proxies[k]),
+				reflect.ValueOf(&p).Elem(), &conflicts)
+		}
+		merged.Proxies = append(merged.Proxies, &p)
+	}
+	for i, proxy := range base.Proxies {
+		if _, ok := baseLocal[i]; ok {
+			continue
+		}
+		// Removed locally
+		if k, ok := baseRemote[i]; ok && !reflect.DeepEqual(*proxy, *remote.Proxies[k]) {
+			conflicts = append(conflicts, MergeConflict{Path: proxyPath(i, proxy), Base: proxy, Remote: remote.Proxies[k]})
+		}
+	}
+	for k, proxy := range remote.Proxies {
+		_, inBase := remoteBase[k]
+		_, inLocal := remoteLocal[k]
+		if !inBase && !inLocal {
+			// Added remotely
+			p := *proxy
+			merged.Proxies = append(merged.Proxies, &p)
+		}
+	}
+	merged.Complete(false)
+	return merged, conflicts
+}
+
+// mergeStruct applies the changes from base to remote onto the fields of dst,
+// which holds the local values.
+func mergeStruct(prefix string, base, remote, dst reflect.Value, conflicts *[]MergeConflict) {
+	baseFields, remoteFields, dstFields := comparableFields(base), comparableFields(remote), comparableFields(dst)
+	for i, f := range dstFields {
+		b, r, l := baseFields[i].value.Interface(), remoteFields[i].value.Interface(), f.value.Interface()
+		switch {
+		case reflect.DeepEqual(r, b), reflect.DeepEqual(l, r):
+		case reflect.DeepEqual(l, b):
+			f.value.Set(remoteFields[i].value)
+		default:
+			*conflicts = append(*conflicts, MergeConflict{Path: prefix + fieldKey(f.field), Base: b, Local: l, Remote: r})
+		}
+	}
+}
+
+// comparableFields returns the options of the struct compared by Diff and Merge.
+func comparableFields(v reflect.Value) []structField {
+	var fields []structField
+	for _, field := range reflect.VisibleFields(v.Type()) {
+		if field.Anonymous || !field.IsExported() {
+			continue
+		}
+		// The start list follows the proxy states, and the format and the source file
+		// of a proxy don't change the meaning
+		if field.Name == "Start" || field.Name == "Format" || field.Name == "Source" {
+			continue
+		}
+		fields = append(fields, structField{field, v.FieldByIndex(field.Index)})
+	}
+	return fields
+}
+
+// matchProxies pairs the proxies of two lists by name, then by shared aliases.
+// The result maps the indexes of a to the indexes of b.
+func matchProxies(a, b []*Proxy) map[int]int {
+	matched := make(map[int]int)
+	used := make(map[int]bool)
+	for i, p := range a {
+		if j := slices.IndexFunc(b, func(q *Proxy) bool { return q.Name == p.Name }); j >= 0 && !used[j] {
+			matched[i], used[j] = j, true
+		}
+	}
+	for i, p := range a {
+		if _, ok := matched[i]; ok {
+			continue
+		}
+		for j, q := range b {
+			if !used[j] && slices.ContainsFunc(p.GetAlias(), func(alias string) bool {
+				return slices.Contains(q.GetAlias(), alias)
+			}) {
+				matched[i], used[j] = j, true
+				break
+			}
+		}
+	}
+	return matched
+}
+
+func invert(m map[int]int) map[int]int {
+	r := make(map[int]int, len(m))
+	for k, v := range m {
+		r[v] = k
+	}
+	return r
+}
+
+func formatDiffValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "(none)"
+	case *Proxy:
+		return "(proxy)"
+	case string:
+		return fmt.Sprintf("%q", v)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// maskDiffValue formats a secret, only telling whether it's set.
+func maskDiffValue(v any) string {
+	if v == "" {
+		return `""`
+	}
+	return `"***"`
+}
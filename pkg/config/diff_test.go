@@ -0,0 +1,184 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func mustUnmarshal(t *testing.T, input string) *ClientConfig {
+	t.Helper()
+	conf, err := UnmarshalClientConf([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conf
+}
+
+const diffBase = `
+serverAddr = "example.com"
+serverPort = 7000
+start = ["ssh", "web"]
+
+[[proxies]]
+name = "ssh"
+type = "tcp"
+localPort = 22
+remotePort = 6000
+
+[[proxies]]
+name = "web"
+type = "http"
+localPort = 80
+subdomain = "web"
+
+[[proxies]]
+name = "ftp"
+type = "tcp"
+localPort = 21
+remotePort = 6021
+`
+
+func TestDiff(t *testing.T) {
+	old := mustUnmarshal(t, diffBase)
+	new := mustUnmarshal(t, `
+serverAddr = "example.org"
+serverPort = 7000
+
+[[proxies]]
+name = "web"
+type = "http"
+localPort = 8080
+subdomain = "web"
+
+[[proxies]]
+name = "ssh"
+type = "tcp"
+localPort = 22
+remotePort = 6000
+
+[[proxies]]
+name = "db"
+type = "tcp"
+localPort = 5432
+`)
+	expected := &ConfigDiff{
+		Common:  []Change{{Path: "server_addr", Old: "example.com", New: "example.org"}},
+		Added:   []string{"proxies[db]"},
+		Removed: []string{"proxies[ftp]"},
+		Modified: []Change{
+			{Path: "proxies[web].local_port", Old: "80", New: "8080"},
+		},
+	}
+	if d := Diff(old, new); !reflect.DeepEqual(d, expected) {
+		t.Errorf("Expected: %v, got: %v", expected, d)
+	}
+	if d := Diff(old, old); !d.Empty() {
+		t.Errorf("Expected: %v, got: %v", "no changes", d)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	base := mustUnmarshal(t, diffBase)
+	// Local tweaks: a proxy port, a new proxy and the ftp proxy disabled
+	local := mustUnmarshal(t, diffBase)
+	local.Proxies[0].LocalPort = "2222"
+	local.Proxies[2].Disabled = false
+	local.Proxies = append(local.Proxies, &Proxy{
+		BaseProxyConf: BaseProxyConf{Name: "mine", Type: "tcp", LocalPort: "3000"},
+	})
+	local.Complete(false)
+	remote := mustUnmarshal(t, `
+serverAddr = "example.com"
+serverPort = 7001
+
+[[proxies]]
+name = "ssh"
+type = "tcp"
+localPort = 22
+remotePort = 6022
+
+[[proxies]]
+name = "web"
+type = "http"
+localPort = 81
+subdomain = "web"
+
+[[proxies]]
+name = "db"
+type = "tcp"
+localPort = 5432
+`)
+	remote.Proxies[1].LocalPort = "81"
+	local.Proxies[1].LocalPort = "82"
+
+	merged, conflicts := Merge(base, local, remote)
+	if merged.ServerPort != 7001 {
+		t.Errorf("Expected: %v, got: %v", 7001, merged.ServerPort)
+	}
+	var names, ports []string
+	for _, proxy := range merged.Proxies {
+		names = append(names, proxy.Name)
+		ports = append(ports, proxy.LocalPort+":"+proxy.RemotePort)
+	}
+	// ftp is removed remotely but was enabled locally, so it's kept
+	expectedNames := []string{"ssh", "web", "ftp", "mine", "db"}
+	expectedPorts := []string{"2222:6022", "82:", "21:6021", "3000:", "5432:"}
+	if !reflect.DeepEqual(names, expectedNames) {
+		t.Errorf("Expected: %v, got: %v", expectedNames, names)
+	}
+	if !reflect.DeepEqual(ports, expectedPorts) {
+		t.Errorf("Expected: %v, got: %v", expectedPorts, ports)
+	}
+	var paths []string
+	for _, c := range conflicts {
+		paths = append(paths, c.Path)
+	}
+	if expected := []string{"proxies[web].local_port", "proxies[ftp]"}; !reflect.DeepEqual(paths, expected) {
+		t.Errorf("Expected: %v, got: %v", expected, paths)
+	}
+	if merged.Start != nil {
+		t.Errorf("Expected: %v, got: %v", nil, merged.Start)
+	}
+}
+
+func TestMergeWithoutBase(t *testing.T) {
+	local := mustUnmarshal(t, diffBase)
+	remote := mustUnmarshal(t, `
+serverAddr = "example.com"
+serverPort = 7001
+
+[[proxies]]
+name = "ssh"
+type = "tcp"
+localPort = 22
+remotePort = 6022
+`)
+	merged, conflicts := Merge(nil, local, remote)
+	if len(conflicts) != 0 {
+		t.Errorf("Expected: %v, got: %v", nil, conflicts)
+	}
+	if merged.ServerPort != 7001 || len(merged.Proxies) != 3 || merged.Proxies[0].RemotePort != "6022" {
+		t.Errorf("Expected: %v, got: %v", "remote changes without removals", merged)
+	}
+}
+
+func TestDiffReloadable(t *testing.T) {
+	old := mustUnmarshal(t, diffBase)
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{input: diffBase, expected: true},
+		{input: strings.Replace(diffBase, "localPort = 80", "localPort = 8080", 1), expected: true},
+		{input: strings.Replace(diffBase, `start = ["ssh", "web"]`, `start = ["ssh"]`, 1), expected: true},
+		{input: "metadatas.frpcgui_name = \"home\"\nmetadatas.team = \"ops\"\n" + diffBase, expected: true},
+		{input: strings.Replace(diffBase, "serverPort = 7000", "serverPort = 7001", 1), expected: false},
+		{input: "webServer.port = 7400\n" + diffBase, expected: false},
+	}
+	for i, test := range tests {
+		if output := Diff(old, mustUnmarshal(t, test.input)).Reloadable(); output != test.expected {
+			t.Errorf("Test %d: expected: %v, got: %v", i, test.expected, output)
+		}
+	}
+}
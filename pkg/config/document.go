@@ -0,0 +1,236 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/ini.v1"
+
+	"github.com/hzcrv1911/frpcgui/pkg/consts"
+	"github.com/hzcrv1911/frpcgui/pkg/util"
+)
+
+// Patch renders the config on top of the original content of its file. Only the edited
+// options are written back, so comments, the order of keys and sections, and keys that
+// are not modeled by the config survive. This applies to the INI and TOML formats; other
+// formats, an empty original and an original in another format are rendered from scratch.
+func (conf *ClientConfig) Patch(original []byte) ([]byte, error) {
+	switch conf.Format {
+	case consts.FormatINI:
+		fresh, err := conf.marshalINI()
+		if err != nil || len(original) == 0 || DetectFormat(original) != consts.FormatINI {
+			return fresh, err
+		}
+		return patchDocument(fresh, func() ([]byte, error) { return conf.patchINI(original) }, UnmarshalClientConfFromIni)
+	case consts.FormatYAML:
+		return conf.marshalYAML()
+	case consts.FormatJSON:
+		return conf.marshalJSON()
+	}
+	fresh, err := conf.marshalTOML()
+	if err != nil || len(original) == 0 || DetectFormat(original) != consts.FormatTOML {
+		return fresh, err
+	}
+	return patchDocument(fresh, func() ([]byte, error) {
+		doc, err := parseTOMLDocument(original)
+		if err != nil {
+			return nil, err
+		}
+		t, err := encodeClientConfig(conf)
+		if err != nil {
+			return nil, err
+		}
+		origConf, err := UnmarshalClientConf(original)
+		if err != nil {
+			return nil, err
+		}
+		origConf.proxyFile = conf.proxyFile
+		implied, err := encodeClientConfig(origConf)
+		if err != nil {
+			return nil, err
+		}
+		doc.patch(t, implied)
+		return doc.bytes(), nil
+	}, UnmarshalClientConf)
+}
+
+// patchDocument returns the patched document if it loads to the same config as the
+// fresh rendering, which is used otherwise.
+func patchDocument(fresh []byte, patch func() ([]byte, error), unmarshal func(source interface{}) (*ClientConfig, error)) ([]byte, error) {
+	patched, err := patch()
+	if err != nil {
+		return fresh, nil
+	}
+	want, err := unmarshal(fresh)
+	if err != nil {
+		return nil, err
+	}
+	if got, err := unmarshal(patched); err != nil || !reflect.DeepEqual(got, want) {
+		return fresh, nil
+	}
+	return patched, nil
+}
+
+// iniKeys returns the names of the ini keys of the struct, including embedded structs.
+func iniKeys(t reflect.Type) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, opts, _ := strings.Cut(field.Tag.Get("ini"), ",")
+		if field.Anonymous || opts == "extends" {
+			keys = append(keys, iniKeys(field.Type)...)
+		} else if name != "" && name != "-" {
+			keys = append(keys, name)
+		}
+	}
+	return keys
+}
+
+// knownINIKey returns a function reporting whether a key is managed by the config.
+func knownINIKey(v any, prefixes ...string) func(key string) bool {
+	keys := iniKeys(reflect.TypeOf(v))
+	return func(key string) bool {
+		return slices.Contains(keys, key) || slices.ContainsFunc(prefixes, func(prefix string) bool {
+			return strings.HasPrefix(key, prefix)
+		})
+	}
+}
+
+var (
+	knownCommonKey = knownINIKey(ClientCommon{}, "meta_", "oidc_additional_")
+	knownProxyKey  = knownINIKey(Proxy{}, "meta_", "header_", "plugin_header_")
+)
+
+// patchINI applies the config onto the original ini file. Proxy sections are matched
+// by name. A section left at the same position with the same type is considered renamed.
+func (conf *ClientConfig) patchINI(original []byte) ([]byte, error) {
+	fresh, err := conf.iniFile()
+	if err != nil {
+		return nil, err
+	}
+	orig, err := ini.LoadSources(ini.LoadOptions{
+		IgnoreInlineComment: true,
+		AllowBooleanKeys:    true,
+	}, original)
+	if err != nil {
+		return nil, err
+	}
+	// The original file as the config sees it holds the values implied by missing keys
+	origConf, err := UnmarshalClientConfFromIni(original)
+	if err != nil {
+		return nil, err
+	}
+	implied, err := origConf.iniFile()
+	if err != nil {
+		return nil, err
+	}
+	proxyName := func(section *ini.Section) string {
+		return strings.TrimPrefix(section.Name(), consts.RangePrefix)
+	}
+	proxyType := func(section *ini.Section) string {
+		if key, err := section.GetKey("type"); err == nil {
+			return key.String()
+		}
+		return ""
+	}
+	isProxy := func(section *ini.Section) bool {
+		return section.Name() != ini.DefaultSection && section.Name() != "common"
+	}
+	oldProxies := slices.DeleteFunc(orig.Sections(), func(section *ini.Section) bool { return !isProxy(section) })
+	newProxies := slices.DeleteFunc(fresh.Sections(), func(section *ini.Section) bool { return !isProxy(section) })
+	matched := make([]*ini.Section, len(newProxies))
+	for i, section := range newProxies {
+		for j, old := range oldProxies {
+			if old != nil && proxyName(old) == proxyName(section) {
+				matched[i], oldProxies[j] = old, nil
+				break
+			}
+		}
+	}
+	for i, section := range newProxies {
+		if matched[i] == nil && i < len(oldProxies) && oldProxies[i] != nil &&
+			proxyType(oldProxies[i]) == proxyType(section) {
+			matched[i], oldProxies[i] = oldProxies[i], nil
+		}
+	}
+
+	out := ini.Empty()
+	if err = copyINISection(out, orig.Section(ini.DefaultSection), nil, nil, nil); err != nil {
+		return nil, err
+	}
+	if err = copyINISection(out, fresh.Section("common"), orig.Section("common"), implied.Section("common"), knownCommonKey); err != nil {
+		return nil, err
+	}
+	for i, section := range newProxies {
+		var impliedSection *ini.Section
+		if matched[i] != nil {
+			impliedSection, _ = implied.GetSection(matched[i].Name())
+		}
+		if err = copyINISection(out, section, matched[i], impliedSection, knownProxyKey); err != nil {
+			return nil, err
+		}
+	}
+	var b bytes.Buffer
+	if _, err = out.WriteTo(&b); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// copyINISection adds the section to the file. If the original section is given, its
+// comments, key order and unknown keys are kept, and new keys holding the values implied
+// by their absence are skipped.
+func copyINISection(f *ini.File, section, orig, implied *ini.Section, known func(key string) bool) error {
+	dst, err := f.NewSection(section.Name())
+	if err != nil {
+		return err
+	}
+	dst.Comment = section.Comment
+	if orig != nil {
+		dst.Comment = orig.Comment
+		for _, key := range orig.Keys() {
+			value := key.Value()
+			if section.HasKey(key.Name()) {
+				value = section.Key(key.Name()).Value()
+			} else if known(key.Name()) {
+				continue
+			}
+			k, err := dst.NewKey(key.Name(), value)
+			if err != nil {
+				return err
+			}
+			k.Comment = key.Comment
+		}
+	}
+	for _, key := range section.Keys() {
+		if dst.HasKey(key.Name()) {
+			continue
+		}
+		if implied != nil {
+			if k, err := implied.GetKey(key.Name()); err == nil && k.Value() == key.Value() {
+				continue
+			}
+		}
+		if _, err = dst.NewKey(key.Name(), key.Value()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFile writes the config to the path, keeping the content of an existing file.
+func (conf *ClientConfig) writeFile(path string) error {
+	original, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	b, err := conf.Patch(original)
+	if err != nil {
+		return err
+	}
+	return util.WriteFileAtomic(path, b, 0666)
+}
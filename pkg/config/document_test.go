@@ -0,0 +1,178 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestClientConfigPatchTOML(t *testing.T) {
+	input := `# Main server
+serverAddr = "example.com" # production
+serverPort = 7000
+featureGates = { VirtualNet = true }
+
+[auth]
+# keep me secret
+token = "123456"
+
+[transport]
+protocol = "tcp"
+wireProtocol = "v2"
+
+# SSH access
+[[proxies]]
+name = "ssh"
+type = "tcp"
+localPort = 22
+remotePort = 6000 # public port
+enabled = true
+
+# Old web
+[[proxies]]
+name = "web"
+type = "http"
+localPort = 80
+customDomains = ["example.com"]
+
+[[visitors]]
+name = "secret_ssh_visitor"
+type = "stcp"
+serverName = "secret_ssh"
+secretKey = "abcdefg"
+bindPort = 9000
+`
+	conf, err := UnmarshalClientConf([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.ServerPort = 7001
+	conf.Token = ""
+	conf.LogLevel = "debug"
+	conf.Proxies[0].RemotePort = "6001"
+	conf.Proxies[0].UseEncryption = true
+	conf.DeleteProxy(1)
+	conf.AddProxy(&Proxy{BaseProxyConf: BaseProxyConf{Name: "dns", Type: "udp", LocalIP: "8.8.8.8", LocalPort: "53"}, RemotePort: "6002"})
+	conf.Complete(false)
+	output, err := conf.Patch([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `# Main server
+serverAddr = "example.com" # production
+serverPort = 7001
+featureGates = { VirtualNet = true }
+
+[auth]
+# keep me secret
+
+[transport]
+protocol = "tcp"
+wireProtocol = "v2"
+
+[log]
+level = "debug"
+
+# SSH access
+[[proxies]]
+name = "ssh"
+type = "tcp"
+localPort = 22
+remotePort = 6001 # public port
+enabled = true
+transport.useEncryption = true
+
+[[proxies]]
+name = "dns"
+type = "udp"
+localIP = "8.8.8.8"
+localPort = 53
+remotePort = 6002
+
+[[visitors]]
+name = "secret_ssh_visitor"
+type = "stcp"
+serverName = "secret_ssh"
+secretKey = "abcdefg"
+bindPort = 9000
+`
+	if string(output) != expected {
+		t.Errorf("Expected: %s, got: %s", expected, output)
+	}
+}
+
+func TestClientConfigPatchINI(t *testing.T) {
+	input := `; Main server
+[common]
+server_addr = example.com
+server_port = 7000
+; keep me secret
+token = 123456
+new_option = true
+
+[web]
+type = http
+local_port = 80
+custom_domains = example.com
+
+; SSH access
+[ssh]
+type = tcp
+local_port = 22
+remote_port = 6000
+`
+	conf, err := UnmarshalClientConf([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.ServerPort = 7001
+	conf.Proxies[1].RemotePort = "6001"
+	conf.Proxies[0].Name = "site"
+	conf.Proxies[0].SubDomain = "test"
+	conf.Complete(false)
+	output, err := conf.Patch([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `; Main server
+[common]
+server_addr = example.com
+server_port = 7001
+; keep me secret
+token = 123456
+new_option = true
+
+[site]
+type = http
+local_port = 80
+custom_domains = example.com
+subdomain = test
+
+; SSH access
+[ssh]
+type = tcp
+local_port = 22
+remote_port = 6001
+`
+	if string(output) != expected {
+		t.Errorf("Expected: %s, got: %s", expected, output)
+	}
+}
+
+func TestClientConfigPatchUnchanged(t *testing.T) {
+	for _, input := range []string{
+		"# comment\r\nserverAddr = 'example.com'\r\n\r\n[[proxies]]\r\nname = \"ssh\" # ssh\r\nlocalPort = 0x16\r\nremotePort = 6000\r\n",
+		"[common]\nserver_addr = example.com\n\n# test\n[ssh]\nlocal_port = 22\nremote_port = 6000\n",
+	} {
+		conf, err := UnmarshalClientConf([]byte(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		conf.Complete(false)
+		output, err := conf.Patch([]byte(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(output) != input {
+			t.Errorf("Expected: %q, got: %q", input, output)
+		}
+	}
+}
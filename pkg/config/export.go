@@ -0,0 +1,26 @@
+package config
+
+import (
+	"github.com/hzcrv1911/frpcgui/pkg/util"
+)
+
+// ExportClientConfs writes the config files to a zip file. Each config is rendered with
+// the proxies of its included files and the secrets as stored, and named by the name of
+// the config, with a number appended if the name is taken by another config.
+func ExportClientConfs(filename string, paths []string) error {
+	entries := make([]util.ZipEntry, 0, len(paths))
+	names := make(map[string]bool)
+	for _, path := range paths {
+		conf, err := UnmarshalClientConf(path)
+		if err != nil {
+			return err
+		}
+		b, err := RenderSealedClientConf(path)
+		if err != nil {
+			return err
+		}
+		name := util.UniqueName(conf.Name(), names) + conf.Ext()
+		entries = append(entries, util.ZipEntry{Name: name, Data: b})
+	}
+	return util.WriteZip(filename, entries)
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExportClientConfs(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.conf": `serverAddr = "example.com"
+includes = ["./proxies.toml"]
+
+[metadatas]
+frpcgui_name = "test"
+`,
+		"b.conf": `serverAddr = "example.org"
+
+[metadatas]
+frpcgui_name = "test"
+`,
+		"proxies.toml": `[[proxies]]
+name = "ssh"
+type = "tcp"
+localPort = 22
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	filename := filepath.Join(dir, "export.zip")
+	err := ExportClientConfs(filename, []string{filepath.Join(dir, "a.conf"), filepath.Join(dir, "b.conf")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := zip.OpenReader(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	var names []string
+	contents := make(map[string]string)
+	for _, f := range r.File {
+		names = append(names, f.Name)
+		b, err := readZipFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		contents[f.Name] = string(b)
+	}
+	expected := []string{"test.toml", "test-2.toml"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected: %v, got: %v", expected, names)
+	}
+	if c := contents["test.toml"]; !strings.Contains(c, `name = "ssh"`) || strings.Contains(c, "includes") {
+		t.Errorf("Expected: %v, got: %v", "included proxies", c)
+	}
+	if c := contents["test-2.toml"]; !strings.Contains(c, "example.org") {
+		t.Errorf("Expected: %v, got: %v", "example.org", c)
+	}
+}
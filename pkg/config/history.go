@@ -0,0 +1,224 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hzcrv1911/frpcgui/pkg/sec"
+	"github.com/hzcrv1911/frpcgui/pkg/util"
+)
+
+// snapshotLayout names the snapshot files. It sorts in time order and is valid in file names.
+const snapshotLayout = "20060102T150405.000000000Z"
+
+// Retention limits the snapshots kept for each config. A zero limit keeps snapshots
+// without limit. The latest snapshot is always kept.
+type Retention struct {
+	// Count is the maximum number of snapshots.
+	Count int `json:"count"`
+	// Days is the maximum age of snapshots.
+	Days int `json:"days"`
+}
+
+// DefaultRetention is the retention of the history if none is configured.
+var DefaultRetention = Retention{Count: 20, Days: 30}
+
+// Snapshot is a version of a config kept by the history.
+type Snapshot struct {
+	// Version identifies the snapshot among those of the config.
+	Version string
+	Time    time.Time
+	Size    int64
+}
+
+// History is a store keeping a timestamped snapshot of every content saved to the
+// underlying store, so that configs can be rolled back.
+type History struct {
+	ConfigStore
+	Retention Retention
+	// Vault opens the sealed secrets when snapshots are compared. Sealing a secret
+	// twice gives different content, so the secrets are compared in plain text.
+	Vault *sec.Vault
+
+	dir string
+}
+
+// NewHistory returns a history keeping the snapshots of the store in the directory.
+func NewHistory(store ConfigStore, dir string, retention Retention) *History {
+	return &History{ConfigStore: store, Retention: retention, dir: dir}
+}
+
+// Save writes the content to the store and records it as a snapshot. The content
+// stored before the first snapshot of a config is recorded first, so that it can
+// be restored too.
+func (h *History) Save(id string, b []byte) error {
+	if err := h.Snapshot(id); err != nil {
+		return err
+	}
+	if err := h.ConfigStore.Save(id, b); err != nil {
+		return err
+	}
+	return h.record(id, b)
+}
+
+// Delete removes the config along with its snapshots.
+func (h *History) Delete(id string) error {
+	if err := h.ConfigStore.Delete(id); err != nil {
+		return err
+	}
+	return os.RemoveAll(filepath.Join(h.dir, id))
+}
+
+// Snapshot records the current content of the config, unless it holds the config of the
+// latest snapshot. It's used before the config is changed outside the store.
+func (h *History) Snapshot(id string) error {
+	b, err := h.ConfigStore.Load(id)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	return h.record(id, b)
+}
+
+// Snapshots returns the snapshots of the config, newest first.
+func (h *History) Snapshots(id string) ([]Snapshot, error) {
+	if err := checkID(id); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(filepath.Join(h.dir, id))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var snapshots []Snapshot
+	for _, entry := range entries {
+		version, ok := strings.CutSuffix(entry.Name(), storeExt)
+		if !ok {
+			continue
+		}
+		t, err := time.Parse(snapshotLayout, version)
+		if err != nil {
+			continue
+		}
+		snapshot := Snapshot{Version: version, Time: t}
+		if info, err := entry.Info(); err == nil {
+			snapshot.Size = info.Size()
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	slices.Reverse(snapshots)
+	return snapshots, nil
+}
+
+// LoadSnapshot returns the content of a snapshot. An empty version stands for the
+// current content of the config.
+func (h *History) LoadSnapshot(id, version string) ([]byte, error) {
+	if version == "" {
+		return h.ConfigStore.Load(id)
+	}
+	if err := checkID(id); err != nil {
+		return nil, err
+	}
+	if _, err := time.Parse(snapshotLayout, version); err != nil {
+		return nil, &os.PathError{Op: "open", Path: version, Err: os.ErrNotExist}
+	}
+	return os.ReadFile(h.snapshotPath(id, version))
+}
+
+// Diff returns the changes from one snapshot of the config to another. An empty
+// version stands for the current content of the config. The secrets are compared
+// after being opened by the vault, and those failed to open are compared sealed.
+func (h *History) Diff(id, from, to string) (*ConfigDiff, error) {
+	confs := make([]*ClientConfig, 2)
+	for i, version := range []string{from, to} {
+		b, err := h.LoadSnapshot(id, version)
+		if err != nil {
+			return nil, err
+		}
+		if confs[i], err = UnmarshalClientConf(b); err != nil {
+			return nil, err
+		}
+		confs[i].OpenSecrets(h.Vault)
+	}
+	return Diff(confs[0], confs[1]), nil
+}
+
+// equal reports whether two contents hold the same config in the same format, with the
+// secrets opened by the vault. Contents that can't be parsed or opened are compared
+// byte by byte.
+func (h *History) equal(a, b []byte) bool {
+	if bytes.Equal(a, b) {
+		return true
+	}
+	confs := make([]*ClientConfig, 2)
+	for i, content := range [][]byte{a, b} {
+		conf, err := UnmarshalClientConf(content)
+		if err != nil || conf.OpenSecrets(h.Vault) != nil {
+			return false
+		}
+		confs[i] = conf
+	}
+	return confs[0].Format == confs[1].Format && Diff(confs[0], confs[1]).Empty()
+}
+
+// Restore replaces the config with the content of a snapshot. The restored content
+// becomes the latest snapshot, so a restore can be rolled back as well.
+func (h *History) Restore(id, version string) error {
+	b, err := h.LoadSnapshot(id, version)
+	if err != nil {
+		return err
+	}
+	return h.Save(id, b)
+}
+
+func (h *History) snapshotPath(id, version string) string {
+	return filepath.Join(h.dir, id, version+storeExt)
+}
+
+// record writes a new snapshot of the content, unless it holds the same config as the
+// latest snapshot, and removes the snapshots out of retention.
+func (h *History) record(id string, b []byte) error {
+	snapshots, err := h.Snapshots(id)
+	if err != nil {
+		return err
+	}
+	if len(snapshots) > 0 {
+		if latest, err := h.LoadSnapshot(id, snapshots[0].Version); err == nil && h.equal(latest, b) {
+			return nil
+		}
+	}
+	if err = os.MkdirAll(filepath.Join(h.dir, id), os.ModePerm); err != nil {
+		return err
+	}
+	// The clock may be too coarse to tell apart two saves
+	now := time.Now().UTC()
+	if len(snapshots) > 0 && !now.After(snapshots[0].Time) {
+		now = snapshots[0].Time.Add(time.Nanosecond)
+	}
+	version := now.Format(snapshotLayout)
+	if err = util.WriteFileAtomic(h.snapshotPath(id, version), b, 0666); err != nil {
+		return err
+	}
+	h.prune(id, append([]Snapshot{{Version: version, Time: now}}, snapshots...))
+	return nil
+}
+
+// prune removes the snapshots out of retention. The snapshots are sorted newest first.
+func (h *History) prune(id string, snapshots []Snapshot) {
+	cutoff := time.Now().AddDate(0, 0, -h.Retention.Days)
+	for i, snapshot := range snapshots[1:] {
+		if (h.Retention.Count > 0 && i+1 >= h.Retention.Count) ||
+			(h.Retention.Days > 0 && snapshot.Time.Before(cutoff)) {
+			os.Remove(h.snapshotPath(id, snapshot.Version))
+		}
+	}
+}
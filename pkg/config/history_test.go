@@ -0,0 +1,98 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hzcrv1911/frpcgui/pkg/sec"
+)
+
+func TestHistory(t *testing.T) {
+	dir := t.TempDir()
+	store := NewMemoryStore()
+	// The content stored before the history is kept as the first snapshot
+	if err := store.Save("home", []byte("serverAddr = \"a.com\"\n")); err != nil {
+		t.Fatal(err)
+	}
+	h := NewHistory(store, dir, Retention{Count: 3})
+	contents := []string{"serverAddr = \"b.com\"\n", "serverAddr = \"b.com\"\n", "serverAddr = \"c.com\"\n"}
+	for _, content := range contents {
+		if err := h.Save("home", []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	snapshots, err := h.Snapshots("home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual []string
+	for _, snapshot := range snapshots {
+		b, err := h.LoadSnapshot("home", snapshot.Version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual = append(actual, string(b))
+	}
+	expected := []string{"serverAddr = \"c.com\"\n", "serverAddr = \"b.com\"\n", "serverAddr = \"a.com\"\n"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected: %v, got: %v", expected, actual)
+	}
+
+	diff, err := h.Diff("home", snapshots[2].Version, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `  server_addr: "a.com" -> "c.com"`; diff.String() != expected {
+		t.Errorf("Expected: %v, got: %v", expected, diff.String())
+	}
+
+	// The restored content becomes the latest snapshot, and the oldest one is pruned
+	if err = h.Restore("home", snapshots[2].Version); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := store.Load("home"); string(b) != "serverAddr = \"a.com\"\n" {
+		t.Errorf("Expected: %v, got: %v", "serverAddr = \"a.com\"\n", string(b))
+	}
+	if snapshots, _ = h.Snapshots("home"); len(snapshots) != 3 {
+		t.Errorf("Expected: %v, got: %v", 3, len(snapshots))
+	}
+
+	if err = h.Delete("home"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "home")); !os.IsNotExist(err) {
+		t.Errorf("Expected: %v, got: %v", os.ErrNotExist, err)
+	}
+}
+
+func TestHistorySecrets(t *testing.T) {
+	key, _ := sec.GenerateKey()
+	v, err := sec.NewVault(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHistory(NewMemoryStore(), t.TempDir(), Retention{})
+	h.Vault = v
+	// Sealing the same secrets again gives a new content, but the same config
+	for _, content := range []string{"serverAddr = \"a.com\"\nauth.token = \"t1\"\n", "serverAddr = \"a.com\"\nauth.token = \"t1\"\n", "serverAddr = \"b.com\"\nauth.token = \"t2\"\n"} {
+		if err = h.Save("home", SealContent([]byte(content), v)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	snapshots, err := h.Snapshots("home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 2 {
+		t.Fatalf("Expected: %v, got: %v", 2, len(snapshots))
+	}
+	diff, err := h.Diff("home", snapshots[1].Version, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "  token: \"***\" -> \"***\"\n  server_addr: \"a.com\" -> \"b.com\""; diff.String() != expected {
+		t.Errorf("Expected: %v, got: %v", expected, diff.String())
+	}
+}
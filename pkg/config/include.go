@@ -0,0 +1,195 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/ini.v1"
+
+	"github.com/hzcrv1911/frpcgui/pkg/consts"
+)
+
+// IncludeFiles returns the files matched by the include patterns of the config file
+// at the path. Relative patterns are resolved against the directory of the config.
+// The returned paths are absolute, and never contain the config file itself.
+func (conf *ClientConfig) IncludeFiles(path string) ([]string, error) {
+	self, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, pattern := range conf.Includes {
+		matches, err := filepath.Glob(includePattern(self, pattern))
+		if err != nil {
+			return nil, fmt.Errorf("includes: %w", err)
+		}
+		for _, file := range matches {
+			if file == self || slices.Contains(files, file) {
+				continue
+			}
+			if fi, err := os.Stat(file); err != nil || fi.IsDir() {
+				continue
+			}
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// Include makes sure the file is matched by the include patterns of the config file
+// at the path. If no pattern matches it, the file is added to the includes, relative
+// to the directory of the config if possible.
+func (conf *ClientConfig) Include(path, file string) error {
+	self, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if file, err = filepath.Abs(file); err != nil {
+		return err
+	}
+	for _, pattern := range conf.Includes {
+		if ok, _ := filepath.Match(includePattern(self, pattern), file); ok {
+			return nil
+		}
+	}
+	if rel, err := filepath.Rel(filepath.Dir(self), file); err == nil && !strings.HasPrefix(rel, "..") {
+		file = "./" + filepath.ToSlash(rel)
+	}
+	conf.Includes = append(conf.Includes, file)
+	return nil
+}
+
+// AbsIncludes returns the content of the config file at the path, with its relative
+// include patterns made absolute. The content then matches the same files once it's
+// moved to another directory. Content without relative patterns is returned as is.
+func AbsIncludes(path string, b []byte) ([]byte, error) {
+	self, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	conf, err := UnmarshalClientConf(b)
+	if err != nil {
+		return nil, err
+	}
+	moved := false
+	for i, pattern := range conf.Includes {
+		if !filepath.IsAbs(filepath.FromSlash(pattern)) {
+			conf.Includes[i] = includePattern(self, pattern)
+			moved = true
+		}
+	}
+	if !moved {
+		return b, nil
+	}
+	return conf.Patch(b)
+}
+
+// includePattern returns the absolute pattern of an include of the config file.
+func includePattern(self, pattern string) string {
+	pattern = filepath.FromSlash(pattern)
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(filepath.Dir(self), pattern)
+	}
+	return filepath.Clean(pattern)
+}
+
+// includeFormat returns the format of an included file by its extension. Files without
+// a known extension are assumed to be in the format of the including config.
+func includeFormat(file, format string) string {
+	switch ext := strings.ToLower(filepath.Ext(file)); ext {
+	case ".yml":
+		return consts.FormatYAML
+	case ".ini", ".toml", ".yaml", ".json":
+		return ext[1:]
+	}
+	return format
+}
+
+// loadIncludes appends the proxies of the included files to the config, tagging each
+// proxy with the file it comes from.
+func (conf *ClientConfig) loadIncludes(path string) error {
+	files, err := conf.IncludeFiles(path)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		proxies, err := parseProxyFile(b, includeFormat(file, conf.Format))
+		if err != nil {
+			return fmt.Errorf("%s: %w", filepath.Base(file), err)
+		}
+		for _, proxy := range proxies {
+			proxy.Source = file
+		}
+		conf.Proxies = append(conf.Proxies, proxies...)
+	}
+	conf.includeFiles = files
+	return nil
+}
+
+// parseProxyFile decodes the proxies of an included file. Legacy INI files hold
+// proxy sections only, while other formats use the proxies and visitors arrays.
+func parseProxyFile(b []byte, format string) ([]*Proxy, error) {
+	var conf *ClientConfig
+	var err error
+	switch format {
+	case consts.FormatINI:
+		cfg, err := ini.LoadSources(ini.LoadOptions{
+			IgnoreInlineComment: true,
+			AllowBooleanKeys:    true,
+		}, b)
+		if err != nil {
+			return nil, err
+		}
+		return proxiesFromIni(cfg)
+	case consts.FormatYAML:
+		conf, err = parseClientConfFromYAML(b)
+	case consts.FormatJSON:
+		conf, err = parseClientConfFromJSON(b)
+	default:
+		conf, err = parseClientConfFromTOML(b)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return conf.Proxies, nil
+}
+
+// saveIncludes writes the proxies of included files back to their files. Files
+// loaded with the config but left without proxies are emptied.
+func (conf *ClientConfig) saveIncludes() error {
+	var files []string
+	groups := make(map[string][]*Proxy)
+	for _, proxy := range conf.Proxies {
+		if proxy.Source == "" {
+			continue
+		}
+		if _, ok := groups[proxy.Source]; !ok {
+			files = append(files, proxy.Source)
+		}
+		groups[proxy.Source] = append(groups[proxy.Source], proxy)
+	}
+	for _, file := range conf.includeFiles {
+		if _, ok := groups[file]; !ok {
+			files = append(files, file)
+		}
+	}
+	for _, file := range files {
+		f := &ClientConfig{Proxies: groups[file], proxyFile: true}
+		f.Format = includeFormat(file, conf.Format)
+		if err := os.MkdirAll(filepath.Dir(file), os.ModePerm); err != nil {
+			return err
+		}
+		if err := f.writeFile(file); err != nil {
+			return fmt.Errorf("%s: %w", filepath.Base(file), err)
+		}
+	}
+	conf.includeFiles = files
+	return nil
+}
@@ -0,0 +1,169 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIncludes(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.conf")
+	files := map[string]string{
+		path: `serverAddr = "example.com"
+includes = ["./confd/*.toml", "web.yaml"]
+
+[[proxies]]
+name = "ssh"
+type = "tcp"
+localPort = 22
+`,
+		filepath.Join(dir, "confd", "a.toml"): `# Remote desktop
+[[proxies]]
+name = "rdp"
+type = "tcp"
+localPort = 3389
+
+[[visitors]]
+name = "secret"
+type = "stcp"
+serverName = "secret"
+bindPort = 9000
+`,
+		filepath.Join(dir, "confd", "b.toml"): `[[proxies]]
+name = "vnc"
+type = "tcp"
+localPort = 5900
+`,
+		filepath.Join(dir, "web.yaml"): `proxies:
+  - name: web
+    type: http
+    localPort: 80
+    subdomain: web
+`,
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	conf, err := UnmarshalClientConf(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sources := func(conf *ClientConfig) map[string]string {
+		m := make(map[string]string)
+		for _, proxy := range conf.Proxies {
+			m[proxy.Name] = "."
+			if proxy.Source != "" {
+				rel, _ := filepath.Rel(dir, proxy.Source)
+				m[proxy.Name] = filepath.ToSlash(rel)
+			}
+		}
+		return m
+	}
+	expected := map[string]string{"ssh": ".", "rdp": "confd/a.toml", "secret": "confd/a.toml", "vnc": "confd/b.toml", "web": "web.yaml"}
+	if actual := sources(conf); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected: %v, got: %v", expected, actual)
+	}
+
+	// Edit a proxy, remove the only proxy of a file and move a proxy to a new file
+	newFile := filepath.Join(dir, "extra", "ftp.toml")
+	conf.Proxies[1].LocalPort = "3390"
+	conf.Proxies = append(conf.Proxies[:3], conf.Proxies[4:]...)
+	conf.Proxies[0].Source = newFile
+	if err = conf.Include(path, newFile); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"./confd/*.toml", "web.yaml", "./extra/ftp.toml"}; !reflect.DeepEqual(conf.Includes, expected) {
+		t.Errorf("Expected: %v, got: %v", expected, conf.Includes)
+	}
+	if err = conf.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "confd", "a.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content := string(b); !strings.HasPrefix(content, "# Remote desktop\n") || !strings.Contains(content, "localPort = 3390") ||
+		strings.Contains(content, "serverAddr") || strings.Contains(content, "loginFailExit") {
+		t.Errorf("Expected: %v, got: %v", "the patched proxy file", content)
+	}
+	if b, err = os.ReadFile(path); err != nil || strings.Contains(string(b), "[[proxies]]") {
+		t.Errorf("Expected: %v, got: %v", "no proxies in the config file", string(b))
+	}
+	conf, err = UnmarshalClientConf(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = map[string]string{"ssh": "extra/ftp.toml", "rdp": "confd/a.toml", "secret": "confd/a.toml", "web": "web.yaml"}
+	if actual := sources(conf); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected: %v, got: %v", expected, actual)
+	}
+}
+
+func TestIncludeINI(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.conf")
+	if err := os.WriteFile(path, []byte("[common]\nserver_addr = example.com\nincludes = ./*.ini\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "ssh.ini"), []byte("[ssh]\ntype = tcp\nlocal_port = 22\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	conf, err := UnmarshalClientConf(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conf.Proxies) != 1 || conf.Proxies[0].Name != "ssh" || conf.Proxies[0].LocalPort != "22" {
+		t.Fatalf("Expected: %v, got: %v", "proxy ssh", conf.Proxies)
+	}
+	conf.Proxies[0].LocalPort = "2222"
+	if err = conf.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := os.ReadFile(filepath.Join(dir, "ssh.ini")); err != nil || string(b) != "[ssh]\ntype = tcp\nlocal_port = 2222\n" {
+		t.Errorf("Expected: %v, got: %v", "[ssh] with local_port = 2222", string(b))
+	}
+}
+
+func TestAbsIncludes(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "R_test", "test.conf")
+	if err := os.MkdirAll(filepath.Join(dir, "R_test", "confd"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "R_test", "confd", "ssh.toml"), []byte("[[proxies]]\nname = \"ssh\"\ntype = \"tcp\"\nlocalPort = 22\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	content := []byte("# Home\nserverAddr = \"example.com\"\nincludes = [\"./confd/*.toml\"]\n")
+	b, err := AbsIncludes(path, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(b), "# Home\n") {
+		t.Errorf("Expected: %v, got: %v", "the comment kept", string(b))
+	}
+	// The moved config still includes the proxies
+	moved := filepath.Join(dir, "test.conf")
+	if err = os.WriteFile(moved, b, 0666); err != nil {
+		t.Fatal(err)
+	}
+	conf, err := UnmarshalClientConf(moved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conf.Proxies) != 1 || conf.Proxies[0].Name != "ssh" {
+		t.Errorf("Expected: %v, got: %v", "proxy ssh", conf.Proxies)
+	}
+
+	content = []byte("serverAddr = \"example.com\"\n")
+	if b, err = AbsIncludes(path, content); err != nil || string(b) != string(content) {
+		t.Errorf("Expected: %v, got: %v", string(content), string(b))
+	}
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/hzcrv1911/frpcgui/pkg/consts"
+)
+
+// marshalJSON renders the tree as an indented JSON document, keeping the order of keys.
+func (t *table) marshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	if err := writeJSONValue(&b, t, ""); err != nil {
+		return nil, err
+	}
+	b.WriteByte('\n')
+	return b.Bytes(), nil
+}
+
+func writeJSONValue(b *bytes.Buffer, value any, indent string) error {
+	switch v := value.(type) {
+	case *table:
+		if len(v.keys) == 0 {
+			b.WriteString("{}")
+			return nil
+		}
+		b.WriteString("{\n")
+		for i, key := range v.keys {
+			b.WriteString(indent + "  ")
+			if err := writeJSONValue(b, key, ""); err != nil {
+				return err
+			}
+			b.WriteString(": ")
+			if err := writeJSONValue(b, v.values[key], indent+"  "); err != nil {
+				return err
+			}
+			if i < len(v.keys)-1 {
+				b.WriteByte(',')
+			}
+			b.WriteByte('\n')
+		}
+		b.WriteString(indent + "}")
+	case []any:
+		if len(v) == 0 {
+			b.WriteString("[]")
+			return nil
+		}
+		b.WriteString("[\n")
+		for i, item := range v {
+			b.WriteString(indent + "  ")
+			if err := writeJSONValue(b, item, indent+"  "); err != nil {
+				return err
+			}
+			if i < len(v)-1 {
+				b.WriteByte(',')
+			}
+			b.WriteByte('\n')
+		}
+		b.WriteString(indent + "]")
+	default:
+		enc := json.NewEncoder(b)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		// Remove the newline added by the encoder
+		b.Truncate(b.Len() - 1)
+	}
+	return nil
+}
+
+// marshalJSON encodes the config with the frp v0.52+ schema in JSON.
+func (conf *ClientConfig) marshalJSON() ([]byte, error) {
+	doc, err := encodeClientConfig(conf)
+	if err != nil {
+		return nil, err
+	}
+	return doc.marshalJSON()
+}
+
+// parseClientConfFromJSON decodes a JSON config written with the frp v0.52+ schema.
+func parseClientConfFromJSON(b []byte) (*ClientConfig, error) {
+	var doc map[string]any
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	conf, err := decodeClientConfig(doc)
+	if err != nil {
+		return nil, err
+	}
+	conf.Format = consts.FormatJSON
+	return conf, nil
+}
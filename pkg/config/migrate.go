@@ -0,0 +1,293 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/ini.v1"
+
+	"github.com/hzcrv1911/frpcgui/pkg/consts"
+	"github.com/hzcrv1911/frpcgui/pkg/util"
+)
+
+// ErrNotLegacyFormat is returned when migrating a config that isn't in the INI format.
+var ErrNotLegacyFormat = errors.New("the config is not in the legacy INI format")
+
+// Kinds of migration changes
+const (
+	// MigrationRenamed means the key has a different name in the target format.
+	MigrationRenamed = "renamed"
+	// MigrationDefaulted means a missing key is written explicitly, because the
+	// target format has a different default.
+	MigrationDefaulted = "defaulted"
+	// MigrationDropped means the key is not written to the target format.
+	MigrationDropped = "dropped"
+	// MigrationUnsupported means the target format has no equivalent of the setting,
+	// so it was converted into something else.
+	MigrationUnsupported = "unsupported"
+)
+
+// MigrationChange describes what became of a key of a legacy INI config.
+type MigrationChange struct {
+	// Section is the name of the INI section holding the key.
+	Section string `json:"section"`
+	// Key is the INI key. It's empty for options having no INI key.
+	Key  string `json:"key,omitempty"`
+	Kind string `json:"kind"`
+	// Target is the path of the option in the target format, if it's written.
+	Target string `json:"target,omitempty"`
+	Detail string `json:"detail,omitempty"`
+}
+
+func (c MigrationChange) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s]", c.Section)
+	if c.Key != "" {
+		b.WriteString(" " + c.Key)
+	}
+	b.WriteString(": " + c.Kind)
+	if c.Target != "" {
+		b.WriteString(" as " + c.Target)
+	}
+	if c.Detail != "" {
+		b.WriteString(", " + c.Detail)
+	}
+	return b.String()
+}
+
+// MigrationReport lists the changes made by migrating a config.
+type MigrationReport struct {
+	// Path of the migrated file, if any.
+	Path string `json:"path,omitempty"`
+	// Backup is the path of the copy of the original file, if any.
+	Backup string `json:"backup,omitempty"`
+	// Format is the target format.
+	Format  string            `json:"format"`
+	Changes []MigrationChange `json:"changes"`
+}
+
+func (r *MigrationReport) String() string {
+	var b strings.Builder
+	if r.Path != "" {
+		fmt.Fprintf(&b, "%s -> %s", r.Path, r.Format)
+		if r.Backup != "" {
+			fmt.Fprintf(&b, " (backup: %s)", r.Backup)
+		}
+		b.WriteString("\n")
+	}
+	for _, c := range r.Changes {
+		b.WriteString("  " + c.String() + "\n")
+	}
+	return b.String()
+}
+
+func (r *MigrationReport) add(section, key, kind, target, detail string) {
+	r.Changes = append(r.Changes, MigrationChange{Section: section, Key: key, Kind: kind, Target: target, Detail: detail})
+}
+
+// MigrateINI converts a legacy INI config to the format, one of TOML, YAML or JSON.
+// Range proxies have no equivalent in the other formats, so they are expanded into
+// one proxy per port. The source is left untouched.
+func MigrateINI(source []byte, format string) (*ClientConfig, *MigrationReport, error) {
+	if format == consts.FormatINI || !slices.Contains(consts.Formats, format) {
+		return nil, nil, fmt.Errorf("unsupported target format %q", format)
+	}
+	f, err := ini.LoadSources(ini.LoadOptions{
+		IgnoreInlineComment: true,
+		AllowBooleanKeys:    true,
+	}, source)
+	if err != nil {
+		return nil, nil, err
+	}
+	raw, err := parseClientConfFromIni(source)
+	if err != nil {
+		return nil, nil, err
+	}
+	report := &MigrationReport{Format: format}
+	conf := &ClientConfig{ClientCommon: raw.ClientCommon}
+	conf.Format = format
+
+	for _, key := range f.Section(ini.DefaultSection).Keys() {
+		report.add(ini.DefaultSection, key.Name(), MigrationDropped, "", "keys outside of a section are ignored")
+	}
+	// Common options
+	common := f.Section("common")
+	completed := ClientConfig{ClientCommon: raw.ClientCommon}
+	completed.Complete(true)
+	for _, key := range common.Keys() {
+		name := key.Name()
+		switch {
+		case strings.HasPrefix(name, "meta_"):
+			report.add("common", name, MigrationRenamed, "metadatas."+strings.TrimPrefix(name, "meta_"), "")
+		case strings.HasPrefix(name, "oidc_additional_"):
+			report.add("common", name, MigrationRenamed, "auth.oidc.additionalEndpointParams."+strings.TrimPrefix(name, "oidc_additional_"), "")
+		case name == "includes":
+			report.add("common", name, MigrationUnsupported, name, "the included files are not migrated, and have to be converted separately")
+		default:
+			migrateKey(report, "common", "", name, &raw.ClientCommon, &completed.ClientCommon, raw.ClientCommon.ignoredBy)
+		}
+	}
+	reportDefaulted(report, "common", "", common, reflect.ValueOf(&completed.ClientCommon).Elem(), nil)
+
+	// Proxies
+	sections := slices.DeleteFunc(f.Sections(), func(section *ini.Section) bool {
+		return section.Name() == ini.DefaultSection || section.Name() == "common"
+	})
+	for i, section := range sections {
+		proxy := raw.Proxies[i]
+		completed := *proxy
+		completed.Complete()
+		prefix := proxyPath(i, proxy)
+		proxies := []*Proxy{proxy}
+		if proxy.IsRange() {
+			if proxies, err = expandRange(proxy); err != nil {
+				return nil, nil, fmt.Errorf("proxy [%s]: %w", proxy.Name, err)
+			}
+			prefix = strings.TrimSuffix(prefix, "]") + "_*]"
+			report.add(section.Name(), "", MigrationUnsupported, "",
+				fmt.Sprintf("range proxies are expanded into %d proxies: %s", len(proxies), strings.Join(proxy.GetAlias(), ", ")))
+		}
+		conf.Proxies = append(conf.Proxies, proxies...)
+		for _, key := range section.Keys() {
+			name := key.Name()
+			switch {
+			case strings.HasPrefix(name, "meta_"):
+				report.add(section.Name(), name, MigrationRenamed, prefix+".metadatas."+strings.TrimPrefix(name, "meta_"), "")
+			case strings.HasPrefix(name, "plugin_header_"):
+				if completed.Plugin == "" || completed.PluginHeaders == nil {
+					report.add(section.Name(), name, MigrationDropped, "", "plugin headers are only used by the http2http, http2https, https2http and https2https plugins")
+				} else {
+					report.add(section.Name(), name, MigrationRenamed, prefix+".plugin.requestHeaders.set."+strings.TrimPrefix(name, "plugin_header_"), "")
+				}
+			case strings.HasPrefix(name, "header_"):
+				if completed.Headers == nil {
+					report.add(section.Name(), name, MigrationDropped, "", "request headers are only used by http proxies")
+				} else {
+					report.add(section.Name(), name, MigrationRenamed, prefix+".requestHeaders.set."+strings.TrimPrefix(name, "header_"), "")
+				}
+			case name == "role":
+				switch {
+				case proxy.IsVisitor():
+					report.add(section.Name(), name, MigrationRenamed, prefix, "visitors are listed separately from proxies")
+				case key.String() == "server" || key.String() == "":
+					report.add(section.Name(), name, MigrationDropped, "", "proxies have the server role")
+				default:
+					report.add(section.Name(), name, MigrationDropped, "", fmt.Sprintf("unknown role %q", key.String()))
+				}
+			default:
+				migrateKey(report, section.Name(), prefix+".", name, proxy, &completed, proxy.ignoredBy)
+			}
+		}
+		reportDefaulted(report, section.Name(), prefix+".", section, reflect.ValueOf(&completed).Elem(), pluginFilter(completed.Plugin))
+	}
+	conf.Complete(true)
+	return conf, report, nil
+}
+
+// migrateKey reports the change of a key mapped to a field of the struct.
+func migrateKey(report *MigrationReport, section, prefix, key string, raw, completed any, ignoredBy func(field reflect.StructField) string) {
+	field, ok := iniFields(reflect.TypeOf(raw).Elem())[key]
+	if !ok {
+		report.add(section, key, MigrationDropped, "", "unknown option")
+		return
+	}
+	if !isEmptyValue(reflect.ValueOf(raw).Elem().FieldByIndex(field.Index)) &&
+		isEmptyValue(reflect.ValueOf(completed).Elem().FieldByIndex(field.Index)) {
+		report.add(section, key, MigrationDropped, "", "not used by "+ignoredBy(field))
+		return
+	}
+	if path := parseFieldOptions(field.Tag.Get("toml")).path; path != key {
+		report.add(section, key, MigrationRenamed, prefix+path, "")
+	}
+}
+
+// reportDefaulted reports the options missing from the section whose value differs
+// from their default in the target format, so that they have to be written.
+func reportDefaulted(report *MigrationReport, section, prefix string, s *ini.Section, v reflect.Value, filter fieldFilter) {
+	walkFields(v, filter, func(field reflect.StructField, fv reflect.Value, opts fieldOptions) error {
+		if opts.def == "" || fv.Kind() != reflect.Bool || strconv.FormatBool(fv.Bool()) == opts.def {
+			return nil
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("ini"), ",")
+		if name == "-" {
+			name = ""
+		} else if s.HasKey(name) {
+			return nil
+		}
+		report.add(section, name, MigrationDefaulted, prefix+opts.path,
+			fmt.Sprintf("written as %t since it defaults to %s in the new format", fv.Bool(), opts.def))
+		return nil
+	})
+}
+
+// iniFields returns the fields of the struct by their ini keys, including embedded structs.
+func iniFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for _, field := range reflect.VisibleFields(t) {
+		if name, _, _ := strings.Cut(field.Tag.Get("ini"), ","); name != "" && name != "-" && !field.Anonymous {
+			fields[name] = field
+		}
+	}
+	return fields
+}
+
+// expandRange splits a range proxy into one proxy per port, named after its aliases.
+func expandRange(proxy *Proxy) ([]*Proxy, error) {
+	localPorts, err := proxy.LocalPorts()
+	if err != nil {
+		return nil, err
+	}
+	remotePorts, err := proxy.RemotePorts()
+	if err != nil {
+		return nil, err
+	}
+	if localPorts.Count() != remotePorts.Count() {
+		return nil, fmt.Errorf("%d remote ports are given for %d local ports", remotePorts.Count(), localPorts.Count())
+	}
+	aliases := localPorts.Aliases(proxy.Name)
+	local, remote := localPorts.Ports(), remotePorts.Ports()
+	proxies := make([]*Proxy, len(local))
+	for i := range local {
+		p := *proxy
+		p.Name = aliases[i]
+		p.LocalPort = strconv.Itoa(local[i])
+		p.RemotePort = strconv.Itoa(remote[i])
+		proxies[i] = &p
+	}
+	return proxies, nil
+}
+
+// MigrateConf converts the legacy INI config of the store to the format. The original
+// content is kept as a backup next to the config file, named with an ".ini.bak" suffix,
+// which is timestamped if the name is taken. The migrated config is returned to be saved
+// by the caller, so that a History keeps the original content as a version too.
+func MigrateConf(store ConfigStore, id, format string) (*ClientConfig, *MigrationReport, error) {
+	original, err := store.Load(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if DetectFormat(original) != consts.FormatINI {
+		return nil, nil, ErrNotLegacyFormat
+	}
+	conf, report, err := MigrateINI(original, format)
+	if err != nil {
+		return nil, nil, err
+	}
+	if report.Path = store.Path(id); report.Path != "" {
+		backup := report.Path + ".ini.bak"
+		if _, err = os.Stat(backup); err == nil {
+			backup = report.Path + "." + time.Now().Format("20060102150405") + ".ini.bak"
+		}
+		if err = util.WriteFileAtomic(backup, original, 0666); err != nil {
+			return nil, nil, err
+		}
+		report.Backup = backup
+	}
+	return conf, report, nil
+}
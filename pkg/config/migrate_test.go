@@ -0,0 +1,126 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hzcrv1911/frpcgui/pkg/consts"
+)
+
+func TestMigrateINI(t *testing.T) {
+	input := `
+		debug = true
+
+		[common]
+		server_addr = example.com
+		token = 123456
+		admin_user = admin
+		meta_env = prod
+		unknown_option = 1
+
+		[web]
+		type = http
+		local_port = 80
+		subdomain = web
+		header_X-From-Where = frp
+		remote_port = 8080
+
+		[range:ports]
+		type = tcp
+		local_port = 6000-6001
+		remote_port = 7000-7001
+
+		[secret_visitor]
+		type = stcp
+		role = visitor
+		server_name = secret
+		bind_port = 9000
+		local_ip = 127.0.0.1
+	`
+	conf, report, err := MigrateINI([]byte(input), consts.FormatTOML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []MigrationChange{
+		{Section: "DEFAULT", Key: "debug", Kind: MigrationDropped, Detail: "keys outside of a section are ignored"},
+		{Section: "common", Key: "server_addr", Kind: MigrationRenamed, Target: "serverAddr"},
+		{Section: "common", Key: "token", Kind: MigrationRenamed, Target: "auth.token"},
+		{Section: "common", Key: "admin_user", Kind: MigrationDropped, Detail: "not used by configs without admin_port"},
+		{Section: "common", Key: "meta_env", Kind: MigrationRenamed, Target: "metadatas.env"},
+		{Section: "common", Key: "unknown_option", Kind: MigrationDropped, Detail: "unknown option"},
+		{Section: "common", Kind: MigrationDefaulted, Key: "login_fail_exit", Target: "loginFailExit", Detail: "written as false since it defaults to true in the new format"},
+		{Section: "web", Key: "local_port", Kind: MigrationRenamed, Target: "proxies[web].localPort"},
+		{Section: "web", Key: "header_X-From-Where", Kind: MigrationRenamed, Target: "proxies[web].requestHeaders.set.X-From-Where"},
+		{Section: "web", Key: "remote_port", Kind: MigrationDropped, Detail: "not used by http proxies"},
+		{Section: "range:ports", Kind: MigrationUnsupported, Detail: "range proxies are expanded into 2 proxies: ports_0, ports_1"},
+		{Section: "range:ports", Key: "local_port", Kind: MigrationRenamed, Target: "proxies[ports_*].localPort"},
+		{Section: "range:ports", Key: "remote_port", Kind: MigrationRenamed, Target: "proxies[ports_*].remotePort"},
+		{Section: "secret_visitor", Key: "role", Kind: MigrationRenamed, Target: "visitors[secret_visitor]", Detail: "visitors are listed separately from proxies"},
+		{Section: "secret_visitor", Key: "server_name", Kind: MigrationRenamed, Target: "visitors[secret_visitor].serverName"},
+		{Section: "secret_visitor", Key: "bind_port", Kind: MigrationRenamed, Target: "visitors[secret_visitor].bindPort"},
+		{Section: "secret_visitor", Key: "local_ip", Kind: MigrationDropped, Detail: "not used by stcp visitors"},
+	}
+	if !reflect.DeepEqual(report.Changes, expected) {
+		t.Errorf("Expected: %v, got: %v", expected, report.Changes)
+	}
+	names := make([]string, len(conf.Proxies))
+	for i, proxy := range conf.Proxies {
+		names[i] = proxy.Name
+	}
+	if expectedNames := []string{"web", "ports_0", "ports_1", "secret_visitor"}; !reflect.DeepEqual(names, expectedNames) {
+		t.Errorf("Expected: %v, got: %v", expectedNames, names)
+	}
+	if conf.Proxies[2].LocalPort != "6001" || conf.Proxies[2].RemotePort != "7001" {
+		t.Errorf("Expected: %v, got: %v", "6001/7001", conf.Proxies[2].LocalPort+"/"+conf.Proxies[2].RemotePort)
+	}
+	if conf.Format != consts.FormatTOML {
+		t.Errorf("Expected: %v, got: %v", consts.FormatTOML, conf.Format)
+	}
+}
+
+func TestMigrateConf(t *testing.T) {
+	dir := t.TempDir()
+	store := NewHistory(NewFileStore(dir), filepath.Join(dir, ".history"), Retention{})
+	input := "[common]\nserver_addr = example.com\n\n[ssh]\ntype = tcp\nlocal_port = 22\n"
+	if err := store.Save("test", []byte(input)); err != nil {
+		t.Fatal(err)
+	}
+	migrated, report, err := MigrateConf(store, "test", consts.FormatYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Path != store.Path("test") || report.Backup != store.Path("test")+".ini.bak" {
+		t.Errorf("Expected: %v, got: %v", store.Path("test"), report)
+	}
+	if b, err := os.ReadFile(report.Backup); err != nil || string(b) != input {
+		t.Errorf("Expected: %v, got: %v", input, string(b))
+	}
+	// The config is left to be saved by the caller
+	if b, _ := store.Load("test"); string(b) != input {
+		t.Errorf("Expected: %v, got: %v", input, string(b))
+	}
+	if err = migrated.SaveTo(store, "test"); err != nil {
+		t.Fatal(err)
+	}
+	conf, err := LoadClientConf(store, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Format != consts.FormatYAML || conf.ServerAddress != "example.com" || len(conf.Proxies) != 1 {
+		t.Errorf("Expected: %v, got: %v", "a YAML config", conf)
+	}
+	// The original content is kept in the history
+	snapshots, err := store.Snapshots("test")
+	if err != nil || len(snapshots) != 2 {
+		t.Fatalf("Expected: %v, got: %v", 2, snapshots)
+	}
+	if b, err := store.LoadSnapshot("test", snapshots[1].Version); err != nil || string(b) != input {
+		t.Errorf("Expected: %v, got: %v", input, string(b))
+	}
+	if _, _, err = MigrateConf(store, "test", consts.FormatTOML); !errors.Is(err, ErrNotLegacyFormat) {
+		t.Errorf("Expected: %v, got: %v", ErrNotLegacyFormat, err)
+	}
+}
@@ -0,0 +1,156 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidPort is returned by PortSpec.Validate for ports out of the valid range.
+	ErrInvalidPort = errors.New("invalid port")
+	// ErrPortOverlap is returned by PortSpec.Validate for ports listed more than once.
+	ErrPortOverlap = errors.New("overlapping ports")
+)
+
+// PortRange is an inclusive range of ports. A single port has equal bounds.
+type PortRange struct {
+	Start int
+	End   int
+}
+
+// Count returns the number of ports in the range.
+func (r PortRange) Count() int {
+	return r.End - r.Start + 1
+}
+
+func (r PortRange) String() string {
+	if r.Start == r.End {
+		return strconv.Itoa(r.Start)
+	}
+	return fmt.Sprintf("%d-%d", r.Start, r.End)
+}
+
+// PortSpec is a list of ports and port ranges, like "6000-6006,6008". The order of
+// the ports matters, as the local ports of a range proxy are paired with its remote
+// ports by position.
+type PortSpec []PortRange
+
+// maxPort is the greatest port number.
+const maxPort = 65535
+
+// ParsePortSpec parses a comma-separated list of ports and port ranges. Spaces and
+// empty items are ignored, so an empty string yields an empty spec. Ports greater than
+// 65535, and specs of more ports than there are, are rejected, as a spec is expanded
+// port by port before it's validated.
+func ParsePortSpec(s string) (PortSpec, error) {
+	var spec PortSpec
+	count := 0
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		start, end, isRange := strings.Cut(item, "-")
+		r := PortRange{}
+		var err error
+		if r.Start, err = parsePortNumber(start); err != nil {
+			return nil, err
+		}
+		r.End = r.Start
+		if isRange {
+			if r.End, err = parsePortNumber(end); err != nil {
+				return nil, err
+			}
+			if r.Start > r.End {
+				return nil, fmt.Errorf("invalid port range %q: the start is greater than the end", item)
+			}
+		}
+		if count += r.Count(); count > maxPort+1 {
+			return nil, fmt.Errorf("too many ports, more than %d", maxPort+1)
+		}
+		spec = append(spec, r)
+	}
+	return spec, nil
+}
+
+func parsePortNumber(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	port, err := strconv.Atoi(s)
+	if err != nil || port < 0 || port > maxPort {
+		return 0, fmt.Errorf("invalid port number %q", s)
+	}
+	return port, nil
+}
+
+// Count returns the number of ports.
+func (s PortSpec) Count() int {
+	count := 0
+	for _, r := range s {
+		count += r.Count()
+	}
+	return count
+}
+
+// Ports returns every port of the spec, in order.
+func (s PortSpec) Ports() []int {
+	ports := make([]int, 0, s.Count())
+	for _, r := range s {
+		for port := r.Start; port <= r.End; port++ {
+			ports = append(ports, port)
+		}
+	}
+	return ports
+}
+
+// IsRange reports whether the spec holds more than one port.
+func (s PortSpec) IsRange() bool {
+	return s.Count() > 1
+}
+
+// Normalize merges the ranges that continue each other, keeping the order of ports.
+func (s PortSpec) Normalize() PortSpec {
+	var result PortSpec
+	for _, r := range s {
+		if n := len(result); n > 0 && result[n-1].End+1 == r.Start {
+			result[n-1].End = r.End
+			continue
+		}
+		result = append(result, r)
+	}
+	return result
+}
+
+// Validate checks that every port is within min and 65535, and that no port is listed twice.
+func (s PortSpec) Validate(min int) error {
+	for i, r := range s {
+		if r.Start < min || r.End > maxPort {
+			return fmt.Errorf("%w %s", ErrInvalidPort, r)
+		}
+		for _, other := range s[:i] {
+			if r.Start <= other.End && other.Start <= r.End {
+				return fmt.Errorf("%w: %s and %s", ErrPortOverlap, other, r)
+			}
+		}
+	}
+	return nil
+}
+
+// Aliases returns the names of the proxies a range proxy of the name is expanded
+// into by frpc, one per port.
+func (s PortSpec) Aliases(name string) []string {
+	aliases := make([]string, s.Count())
+	for i := range aliases {
+		aliases[i] = fmt.Sprintf("%s_%d", name, i)
+	}
+	return aliases
+}
+
+func (s PortSpec) String() string {
+	items := make([]string, len(s))
+	for i, r := range s {
+		items[i] = r.String()
+	}
+	return strings.Join(items, ",")
+}
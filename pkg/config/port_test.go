@@ -0,0 +1,75 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParsePortSpec(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected PortSpec
+		count    int
+		str      string
+		fail     bool
+	}{
+		{input: "", expected: nil, count: 0, str: ""},
+		{input: "6000", expected: PortSpec{{6000, 6000}}, count: 1, str: "6000"},
+		{input: " 6000 - 6002 , 6010,", expected: PortSpec{{6000, 6002}, {6010, 6010}}, count: 4, str: "6000-6002,6010"},
+		{input: "6005-6000", fail: true},
+		{input: "6000-", fail: true},
+		{input: "-1", fail: true},
+		{input: "1-2000000000", fail: true},
+		{input: "65536", fail: true},
+		{input: "0-65535", expected: PortSpec{{0, 65535}}, count: 65536, str: "0-65535"},
+		{input: "0-65535,1", fail: true},
+		{input: "ssh", fail: true},
+	}
+	for _, test := range tests {
+		spec, err := ParsePortSpec(test.input)
+		if test.fail {
+			if err == nil {
+				t.Errorf("Expected: an error for %q, got: %v", test.input, spec)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(spec, test.expected) {
+			t.Errorf("Expected: %v, got: %v", test.expected, spec)
+		}
+		if spec.Count() != test.count {
+			t.Errorf("Expected: %v, got: %v", test.count, spec.Count())
+		}
+		if spec.String() != test.str {
+			t.Errorf("Expected: %v, got: %v", test.str, spec.String())
+		}
+	}
+}
+
+func TestPortSpec(t *testing.T) {
+	spec := PortSpec{{6000, 6001}, {6002, 6002}, {6010, 6011}, {5000, 5000}}
+	if expected := "6000-6002,6010-6011,5000"; spec.Normalize().String() != expected {
+		t.Errorf("Expected: %v, got: %v", expected, spec.Normalize())
+	}
+	if expected := []int{6000, 6001, 6002, 6010, 6011, 5000}; !reflect.DeepEqual(spec.Ports(), expected) {
+		t.Errorf("Expected: %v, got: %v", expected, spec.Ports())
+	}
+	if expected := []string{"web_0", "web_1", "web_2", "web_3", "web_4", "web_5"}; !reflect.DeepEqual(spec.Aliases("web"), expected) {
+		t.Errorf("Expected: %v, got: %v", expected, spec.Aliases("web"))
+	}
+	if err := spec.Validate(1); err != nil {
+		t.Errorf("Expected: %v, got: %v", nil, err)
+	}
+	if err := (PortSpec{{0, 0}}).Validate(1); !errors.Is(err, ErrInvalidPort) {
+		t.Errorf("Expected: %v, got: %v", ErrInvalidPort, err)
+	}
+	if err := (PortSpec{{65530, 65536}}).Validate(0); !errors.Is(err, ErrInvalidPort) {
+		t.Errorf("Expected: %v, got: %v", ErrInvalidPort, err)
+	}
+	if err := (PortSpec{{6000, 6005}, {7000, 7000}, {6005, 6006}}).Validate(1); !errors.Is(err, ErrPortOverlap) {
+		t.Errorf("Expected: %v, got: %v", ErrPortOverlap, err)
+	}
+}
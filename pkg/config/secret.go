@@ -0,0 +1,172 @@
+package config
+
+import (
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/hzcrv1911/frpcgui/pkg/sec"
+	"github.com/hzcrv1911/frpcgui/pkg/util"
+)
+
+// secrets returns the options of the config holding secrets.
+func (conf *ClientConfig) secrets() []*string {
+	secrets := []*string{&conf.Token, &conf.OIDCClientSecret, &conf.AdminPwd}
+	for _, proxy := range conf.Proxies {
+		secrets = append(secrets, &proxy.SK, &proxy.HTTPPwd, &proxy.PluginPasswd, &proxy.PluginHttpPasswd)
+	}
+	return secrets
+}
+
+// secretKeys are the keys of the options returned by secrets.
+var secretKeys = []string{"token", "oidc_client_secret", "admin_pwd", "sk", "http_pwd", "plugin_passwd", "plugin_http_passwd"}
+
+// isSecretPath reports whether the option path, like "proxies[ssh].sk", holds a secret.
+func isSecretPath(path string) bool {
+	return slices.Contains(secretKeys, path[strings.LastIndexByte(path, '.')+1:])
+}
+
+// SealSecrets encrypts the secrets of the config with the vault.
+func (conf *ClientConfig) SealSecrets(v *sec.Vault) error {
+	for _, s := range conf.secrets() {
+		sealed, err := v.Seal(*s)
+		if err != nil {
+			return err
+		}
+		*s = sealed
+	}
+	return nil
+}
+
+// OpenSecrets decrypts the sealed secrets of the config with the vault. A nil vault
+// opens nothing. The secrets failed to open are kept sealed, and the first error is returned.
+func (conf *ClientConfig) OpenSecrets(v *sec.Vault) error {
+	var firstErr error
+	for _, s := range conf.secrets() {
+		if !sec.IsSealed(*s) {
+			continue
+		}
+		if v == nil {
+			firstErr = sec.ErrVaultKey
+			continue
+		}
+		plain, err := v.Open(*s)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		*s = plain
+	}
+	return firstErr
+}
+
+// SealContent returns the config content with its secrets sealed by the vault. A nil vault
+// seals nothing, and content that can't be parsed is returned as is.
+func SealContent(src []byte, v *sec.Vault) []byte {
+	if v == nil {
+		return src
+	}
+	conf, err := ParseClientConf(src)
+	if err != nil || conf.SealSecrets(v) != nil {
+		return src
+	}
+	if b, err := conf.Patch(src); err == nil {
+		return b
+	}
+	return src
+}
+
+// RedactSecrets clears the secrets of the config.
+func (conf *ClientConfig) RedactSecrets() {
+	for _, s := range conf.secrets() {
+		*s = ""
+	}
+}
+
+// RedactContent returns the config content with its secrets removed.
+func RedactContent(src []byte) ([]byte, error) {
+	conf, err := ParseClientConf(src)
+	if err != nil {
+		return nil, err
+	}
+	conf.RedactSecrets()
+	return conf.Patch(src)
+}
+
+// WithSealedSecrets calls save with the secrets of the config encrypted by the vault,
+// so that they are written sealed. The secrets are back in plain text after the call.
+func (conf *ClientConfig) WithSealedSecrets(v *sec.Vault, save func() error) error {
+	secrets := conf.secrets()
+	plain := make([]string, len(secrets))
+	for i, s := range secrets {
+		plain[i] = *s
+	}
+	defer func() {
+		for i, s := range secrets {
+			*s = plain[i]
+		}
+	}()
+	if err := conf.SealSecrets(v); err != nil {
+		return err
+	}
+	return save()
+}
+
+// RenderClientConf writes the copy of the config file handed to frpc. The secrets sealed
+// by the vault are opened, and the proxies of included files are written into the copy,
+// so that it doesn't depend on the files it's loaded from.
+func RenderClientConf(path, dst string, v *sec.Vault) error {
+	b, err := RenderClientConfContent(path, v)
+	if err != nil {
+		return err
+	}
+	return util.WriteFileAtomic(dst, b, 0600)
+}
+
+// RenderClientConfContent returns the content of the copy written by RenderClientConf.
+func RenderClientConfContent(path string, v *sec.Vault) ([]byte, error) {
+	return renderClientConf(path, func(conf *ClientConfig) error {
+		return conf.OpenSecrets(v)
+	})
+}
+
+// RenderRedactedClientConf returns the content of the copy written by RenderClientConf,
+// with the secrets removed instead of opened, so that no vault is needed.
+func RenderRedactedClientConf(path string) ([]byte, error) {
+	return renderClientConf(path, func(conf *ClientConfig) error {
+		conf.RedactSecrets()
+		return nil
+	})
+}
+
+// RenderSealedClientConf returns the content of the copy written by RenderClientConf,
+// with the secrets kept as stored, so that sealed secrets stay sealed.
+func RenderSealedClientConf(path string) ([]byte, error) {
+	return renderClientConf(path, func(conf *ClientConfig) error {
+		return nil
+	})
+}
+
+// renderClientConf renders the config file with the proxies of included files, after
+// the secrets are processed by the given function.
+func renderClientConf(path string, secrets func(conf *ClientConfig) error) ([]byte, error) {
+	original, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	conf, err := UnmarshalClientConf(path)
+	if err != nil {
+		return nil, err
+	}
+	if err = secrets(conf); err != nil {
+		return nil, err
+	}
+	conf.Includes = nil
+	for _, proxy := range conf.Proxies {
+		proxy.Source = ""
+	}
+	conf.Complete(false)
+	return conf.Patch(original)
+}
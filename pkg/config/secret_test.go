@@ -0,0 +1,122 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hzcrv1911/frpcgui/pkg/sec"
+)
+
+func TestSecrets(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.conf")
+	files := map[string]string{
+		path: `serverAddr = "example.com"
+auth.token = "server-token"
+includes = ["./secret.toml"]
+
+[[proxies]]
+name = "web"
+type = "http"
+localPort = 80
+subdomain = "web"
+httpUser = "admin"
+httpPassword = "http-password"
+`,
+		filepath.Join(dir, "secret.toml"): `[[proxies]]
+name = "secret"
+type = "stcp"
+localPort = 22
+secretKey = "secret-key"
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(name, []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	key, _ := sec.GenerateKey()
+	v, err := sec.NewVault(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf, err := UnmarshalClientConf(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.Complete(false)
+	if err = conf.WithSealedSecrets(v, func() error { return conf.Save(path) }); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Token != "server-token" || conf.Proxies[1].SK != "secret-key" {
+		t.Errorf("Expected: %v, got: %v", "secrets in plain text", conf.Token)
+	}
+	plain := []string{"server-token", "http-password", "secret-key"}
+	for name := range files {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range plain {
+			if strings.Contains(string(b), s) {
+				t.Errorf("Expected: %v, got: %v", "no "+s, string(b))
+			}
+		}
+	}
+
+	conf, err = UnmarshalClientConf(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _ := sec.GenerateKey()
+	ov, _ := sec.NewVault(other)
+	if err = conf.OpenSecrets(ov); !errors.Is(err, sec.ErrVaultKey) || !sec.IsSealed(conf.Token) {
+		t.Errorf("Expected: %v, got: %v", sec.ErrVaultKey, err)
+	}
+	if err = conf.OpenSecrets(v); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Token != "server-token" || conf.Proxies[0].HTTPPwd != "http-password" || conf.Proxies[1].SK != "secret-key" {
+		t.Errorf("Expected: %v, got: %v", plain, []string{conf.Token, conf.Proxies[0].HTTPPwd, conf.Proxies[1].SK})
+	}
+
+	dst := filepath.Join(dir, "frpc.toml")
+	if err = RenderClientConf(path, dst, v); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := string(b)
+	for _, s := range append(plain, `name = "secret"`) {
+		if !strings.Contains(content, s) {
+			t.Errorf("Expected: %v, got: %v", s, content)
+		}
+	}
+	if strings.Contains(content, "includes") || strings.Contains(content, sec.SealedPrefix) {
+		t.Errorf("Expected: %v, got: %v", "a self-contained copy", content)
+	}
+
+	conf.RedactSecrets()
+	for _, s := range conf.secrets() {
+		if *s != "" {
+			t.Errorf("Expected: %v, got: %v", "", *s)
+		}
+	}
+
+	if b, err = RedactContent([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range plain {
+		if strings.Contains(string(b), s) {
+			t.Errorf("Expected: %v, got: %v", "no "+s, string(b))
+		}
+	}
+	if !strings.Contains(string(b), `serverAddr = "example.com"`) {
+		t.Errorf("Expected: %v, got: %v", "the other options kept", string(b))
+	}
+}
@@ -0,0 +1,267 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hzcrv1911/frpcgui/pkg/util"
+)
+
+// ErrInvalidID is returned by stores for IDs that can't name a config.
+var ErrInvalidID = errors.New("invalid config id")
+
+// ConfigStore persists the content of configs, addressed by stable IDs. An ID never
+// changes once a config is created, whatever the name or server of the config is.
+type ConfigStore interface {
+	// List returns the IDs of the stored configs.
+	List() ([]string, error)
+	// Load returns the content of the config. An unknown ID yields an error
+	// matching os.ErrNotExist.
+	Load(id string) ([]byte, error)
+	// Save replaces the content of the config atomically.
+	Save(id string, b []byte) error
+	// Delete removes the config. Deleting an unknown ID is not an error.
+	Delete(id string) error
+	// Path returns the file of the config, which is handed to frpc and against which
+	// relative includes are resolved. Stores not backed by files return an empty string.
+	Path(id string) string
+}
+
+// NewConfigID returns a new random ID for a config.
+func NewConfigID() string {
+	id, err := util.RandToken(8)
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
+
+func checkID(id string) error {
+	if id == "" || strings.HasPrefix(id, ".") || strings.ContainsAny(id, `/\:*?"<>|`) {
+		return fmt.Errorf("%w %q", ErrInvalidID, id)
+	}
+	return nil
+}
+
+// LoadClientConf loads a config from the store like UnmarshalClientConf. The proxies
+// of included files are only loaded if the store is backed by files.
+func LoadClientConf(store ConfigStore, id string) (*ClientConfig, error) {
+	b, err := store.Load(id)
+	if err != nil {
+		return nil, err
+	}
+	conf, err := parseClientConfContent(b)
+	if err != nil {
+		return nil, err
+	}
+	if path := store.Path(id); path != "" {
+		if err = conf.loadIncludes(path); err != nil {
+			return nil, err
+		}
+	}
+	conf.Complete(true)
+	return conf, nil
+}
+
+// SaveTo writes the config to the store like Save. An existing config of the id is
+// updated in place, and proxies loaded from included files are written back to the
+// files they come from.
+func (conf *ClientConfig) SaveTo(store ConfigStore, id string) error {
+	original, err := store.Load(id)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	main := *conf
+	main.Proxies = slices.DeleteFunc(slices.Clone(conf.Proxies), func(proxy *Proxy) bool { return proxy.Source != "" })
+	if err = conf.saveIncludes(); err != nil {
+		return err
+	}
+	b, err := main.Patch(original)
+	if err != nil {
+		return err
+	}
+	return store.Save(id, b)
+}
+
+// LoadBase returns the merge base of the config, which is the content it was last
+// imported from, and the common base of a three-way merge with a newer import.
+// Stores not backed by files keep no base, and yield an error matching os.ErrNotExist.
+func LoadBase(store ConfigStore, id string) ([]byte, error) {
+	path, err := basePath(store, id)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+// SaveBase replaces the merge base of the config atomically, under the lock of the
+// store. Stores not backed by files keep no base, so nothing is written.
+func SaveBase(store ConfigStore, id string, b []byte) error {
+	path, err := basePath(store, id)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	unlock, err := util.LockFile(filepath.Join(filepath.Dir(path), storeLockFile), storeLockTimeout)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return util.WriteFileAtomic(path, b, 0666)
+}
+
+// basePath returns the file of the merge base, next to the file of the config.
+func basePath(store ConfigStore, id string) (string, error) {
+	if err := checkID(id); err != nil {
+		return "", err
+	}
+	path := store.Path(id)
+	if path == "" {
+		return "", &fs.PathError{Op: "open", Path: id + baseExt, Err: fs.ErrNotExist}
+	}
+	return path + baseExt, nil
+}
+
+const (
+	storeExt      = ".conf"
+	storeLockFile = ".lock"
+	baseExt       = ".base"
+	// storeLockTimeout is how long a write waits for another instance to finish its own.
+	storeLockTimeout = 5 * time.Second
+)
+
+// FileStore keeps each config in a file named by its ID in a directory. Writes are
+// atomic, and guarded by a lock file against other instances of the program.
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore returns a store of the config files in the directory.
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir}
+}
+
+func (s *FileStore) Path(id string) string {
+	return filepath.Join(s.dir, id+storeExt)
+}
+
+func (s *FileStore) List() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var ids []string
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), storeExt)
+		if ok && entry.Type().IsRegular() && checkID(id) == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (s *FileStore) Load(id string) ([]byte, error) {
+	if err := checkID(id); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(s.Path(id))
+}
+
+func (s *FileStore) Save(id string, b []byte) error {
+	if err := checkID(id); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, os.ModePerm); err != nil {
+		return err
+	}
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return util.WriteFileAtomic(s.Path(id), b, 0666)
+}
+
+func (s *FileStore) Delete(id string) error {
+	if err := checkID(id); err != nil {
+		return err
+	}
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err = os.Remove(s.Path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *FileStore) lock() (func(), error) {
+	return util.LockFile(filepath.Join(s.dir, storeLockFile), storeLockTimeout)
+}
+
+// MemoryStore keeps configs in memory. It's safe for concurrent use.
+type MemoryStore struct {
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+// NewMemoryStore returns an empty store in memory.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{files: make(map[string][]byte)}
+}
+
+func (s *MemoryStore) Path(id string) string {
+	return ""
+}
+
+func (s *MemoryStore) List() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := make([]string, 0, len(s.files))
+	for id := range s.files {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+func (s *MemoryStore) Load(id string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.files[id]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: id, Err: fs.ErrNotExist}
+	}
+	return slices.Clone(b), nil
+}
+
+func (s *MemoryStore) Save(id string, b []byte) error {
+	if err := checkID(id); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[id] = slices.Clone(b)
+	return nil
+}
+
+func (s *MemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.files, id)
+	return nil
+}
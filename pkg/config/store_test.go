@@ -0,0 +1,113 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConfigStore(t *testing.T) {
+	dir := t.TempDir()
+	stores := map[string]ConfigStore{
+		"file":   NewFileStore(filepath.Join(dir, "profiles")),
+		"memory": NewMemoryStore(),
+	}
+	for name, store := range stores {
+		if ids, err := store.List(); err != nil || len(ids) != 0 {
+			t.Errorf("%s: expected: %v, got: %v %v", name, "no configs", ids, err)
+		}
+		if _, err := store.Load("missing"); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s: expected: %v, got: %v", name, os.ErrNotExist, err)
+		}
+		for _, id := range []string{"", "../a", ".lock"} {
+			if err := store.Save(id, nil); !errors.Is(err, ErrInvalidID) {
+				t.Errorf("%s: expected: %v, got: %v", name, ErrInvalidID, err)
+			}
+		}
+		for _, id := range []string{"b", "a", "b"} {
+			if err := store.Save(id, []byte("content of "+id)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if ids, _ := store.List(); !reflect.DeepEqual(ids, []string{"a", "b"}) {
+			t.Errorf("%s: expected: %v, got: %v", name, []string{"a", "b"}, ids)
+		}
+		if b, err := store.Load("b"); err != nil || string(b) != "content of b" {
+			t.Errorf("%s: expected: %v, got: %v %v", name, "content of b", string(b), err)
+		}
+		if err := store.Delete("a"); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Delete("a"); err != nil {
+			t.Errorf("%s: expected: %v, got: %v", name, nil, err)
+		}
+		if ids, _ := store.List(); !reflect.DeepEqual(ids, []string{"b"}) {
+			t.Errorf("%s: expected: %v, got: %v", name, []string{"b"}, ids)
+		}
+	}
+	// Only the config files are left, without temporary or lock files
+	entries, err := os.ReadDir(filepath.Join(dir, "profiles"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "b.conf" {
+		t.Errorf("Expected: %v, got: %v", "b.conf", entries)
+	}
+}
+
+func TestSaveToStore(t *testing.T) {
+	store := NewMemoryStore()
+	original := "# Home server\nserverAddr = \"example.com\"\n\n[[proxies]]\nname = \"ssh\"\ntype = \"tcp\"\nlocalPort = 22\n"
+	if err := store.Save("home", []byte(original)); err != nil {
+		t.Fatal(err)
+	}
+	conf, err := LoadClientConf(store, "home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.ServerAddress != "example.com" || len(conf.Proxies) != 1 {
+		t.Fatalf("Expected: %v, got: %v", "the stored config", conf)
+	}
+	conf.Proxies[0].LocalPort = "2222"
+	conf.Complete(false)
+	if err = conf.SaveTo(store, "home"); err != nil {
+		t.Fatal(err)
+	}
+	b, err := store.Load("home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content := string(b); !strings.HasPrefix(content, "# Home server\n") || !strings.Contains(content, "localPort = 2222") {
+		t.Errorf("Expected: %v, got: %v", "the patched config", content)
+	}
+}
+
+func TestBase(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileStore(dir)
+	if _, err := LoadBase(store, "home"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected: %v, got: %v", os.ErrNotExist, err)
+	}
+	if err := SaveBase(store, "home", []byte("serverAddr = \"a.com\"\n")); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := LoadBase(store, "home"); err != nil || string(b) != "serverAddr = \"a.com\"\n" {
+		t.Errorf("Expected: %v, got: %v %v", "serverAddr = \"a.com\"\n", string(b), err)
+	}
+	// The base is not a config of the store, and the lock is released
+	if ids, err := store.List(); err != nil || len(ids) != 0 {
+		t.Errorf("Expected: %v, got: %v %v", "no configs", ids, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, storeLockFile)); !os.IsNotExist(err) {
+		t.Errorf("Expected: %v, got: %v", os.ErrNotExist, err)
+	}
+	if err := SaveBase(NewMemoryStore(), "home", []byte("serverAddr = \"a.com\"\n")); err != nil {
+		t.Errorf("Expected: %v, got: %v", nil, err)
+	}
+	if err := SaveBase(store, "../home", nil); !errors.Is(err, ErrInvalidID) {
+		t.Errorf("Expected: %v, got: %v", ErrInvalidID, err)
+	}
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	// DefaultSubscriptionInterval is the refresh interval of subscriptions, in minutes.
+	DefaultSubscriptionInterval = 60
+	// MinSubscriptionInterval is the shortest refresh interval of subscriptions, in minutes.
+	MinSubscriptionInterval = 5
+	// maxSubscriptionSize limits the content downloaded for a subscription.
+	maxSubscriptionSize = 4 << 20
+)
+
+var (
+	// ErrSubscriptionTooLarge is returned if the content of a subscription exceeds the size limit.
+	ErrSubscriptionTooLarge = errors.New("the subscribed content is too large")
+	// ErrUpdateDeclined is passed to Checked for an update the user chose not to apply,
+	// which is offered again by the next refresh.
+	ErrUpdateDeclined = errors.New("the update is declined")
+)
+
+// Subscription keeps a config up to date with the content published at its source URL.
+type Subscription struct {
+	URL string `json:"url"`
+	// Interval between refreshes, in minutes.
+	Interval int `json:"interval,omitempty"`
+	// AutoApply applies the changes without asking.
+	AutoApply bool `json:"autoApply,omitempty"`
+	// ETag and LastModified are the validators of the content applied last,
+	// which are sent with the next refresh to skip unchanged content.
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	// LastCheck is the time of the last refresh, successful or not.
+	LastCheck time.Time `json:"lastCheck,omitzero"`
+	// LastError is the error of the last refresh, if it failed.
+	LastError string `json:"lastError,omitempty"`
+}
+
+// SubscriptionUpdate is the changed content fetched for a subscription.
+type SubscriptionUpdate struct {
+	Data         []byte
+	ETag         string
+	LastModified string
+}
+
+// RefreshInterval returns the interval between refreshes.
+func (s *Subscription) RefreshInterval() time.Duration {
+	minutes := s.Interval
+	if minutes <= 0 {
+		minutes = DefaultSubscriptionInterval
+	}
+	return time.Duration(max(minutes, MinSubscriptionInterval)) * time.Minute
+}
+
+// Due reports whether the subscription should be refreshed.
+func (s *Subscription) Due(now time.Time) bool {
+	return !now.Before(s.LastCheck.Add(s.RefreshInterval()))
+}
+
+// Fetch downloads the content published at the URL of the subscription. A nil update
+// is returned if the content is unchanged since the update applied last.
+func (s *Subscription) Fetch(ctx context.Context) (*SubscriptionUpdate, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if s.ETag != "" {
+		req.Header.Set("If-None-Match", s.ETag)
+	}
+	if s.LastModified != "" {
+		req.Header.Set("If-Modified-Since", s.LastModified)
+	}
+	client := http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("bad status: %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSubscriptionSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxSubscriptionSize {
+		return nil, ErrSubscriptionTooLarge
+	}
+	return &SubscriptionUpdate{
+		Data:         data,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}, nil
+}
+
+// Checked records the result of a refresh. The validators of the update are kept
+// only if it's handled, so that a failed or declined update is fetched again next time.
+func (s *Subscription) Checked(now time.Time, update *SubscriptionUpdate, err error) {
+	s.LastCheck = now
+	if errors.Is(err, ErrUpdateDeclined) {
+		s.LastError = ""
+		return
+	}
+	if err != nil {
+		s.LastError = err.Error()
+		return
+	}
+	s.LastError = ""
+	if update != nil {
+		s.ETag = update.ETag
+		s.LastModified = update.LastModified
+	}
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSubscriptionDue(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		input    Subscription
+		expected bool
+	}{
+		{input: Subscription{}, expected: true},
+		{input: Subscription{LastCheck: now.Add(-30 * time.Minute)}, expected: false},
+		{input: Subscription{LastCheck: now.Add(-time.Hour)}, expected: true},
+		{input: Subscription{Interval: 10, LastCheck: now.Add(-10 * time.Minute)}, expected: true},
+		{input: Subscription{Interval: 1, LastCheck: now.Add(-time.Minute)}, expected: false},
+	}
+	for i, test := range tests {
+		if output := test.input.Due(now); output != test.expected {
+			t.Errorf("Test %d: expected: %v, got: %v", i, test.expected, output)
+		}
+	}
+}
+
+func TestSubscriptionFetch(t *testing.T) {
+	content := "serverAddr = \"example.com\"\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	sub := Subscription{URL: server.URL + "/home.toml"}
+	update, err := sub.Fetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if update == nil || string(update.Data) != content || update.ETag != `"v1"` {
+		t.Fatalf("Expected: %v, got: %v", content, update)
+	}
+	// A declined update is fetched again
+	sub.Checked(time.Now(), update, ErrUpdateDeclined)
+	if sub.ETag != "" || sub.LastError != "" {
+		t.Errorf("Expected: %v, got: %v", "no validators and no error", sub)
+	}
+	// The validators are kept once the update is handled
+	sub.Checked(time.Now(), update, nil)
+	if update, err = sub.Fetch(context.Background()); err != nil || update != nil {
+		t.Errorf("Expected: %v, got: %v %v", "not modified", update, err)
+	}
+
+	sub = Subscription{URL: server.URL + "/missing"}
+	_, err = sub.Fetch(context.Background())
+	if err == nil {
+		t.Fatalf("Expected: %v, got: %v", "an error", err)
+	}
+	sub.Checked(time.Now(), nil, err)
+	if sub.LastError == "" || sub.ETag != "" {
+		t.Errorf("Expected: %v, got: %v", "a recorded failure", sub)
+	}
+}
@@ -0,0 +1,206 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// table is an ordered document tree. Values are strings, booleans, integers,
+// arrays ([]any) or nested tables.
+type table struct {
+	keys   []string
+	values map[string]any
+}
+
+func newTable() *table {
+	return &table{values: make(map[string]any)}
+}
+
+// put sets the value of a direct key of the table.
+func (t *table) put(key string, value any) {
+	if _, ok := t.values[key]; !ok {
+		t.keys = append(t.keys, key)
+	}
+	t.values[key] = value
+}
+
+// sub returns the nested table of the key, creating it if necessary.
+func (t *table) sub(key string) *table {
+	if s, ok := t.values[key].(*table); ok {
+		return s
+	}
+	s := newTable()
+	t.put(key, s)
+	return s
+}
+
+// set sets the value of a dotted key. A table value is merged into the existing table.
+func (t *table) set(path string, value any) {
+	keys := strings.Split(path, ".")
+	cur := t
+	for _, key := range keys[:len(keys)-1] {
+		cur = cur.sub(key)
+	}
+	last := keys[len(keys)-1]
+	if s, ok := value.(*table); ok {
+		dst := cur.sub(last)
+		for _, key := range s.keys {
+			dst.put(key, s.values[key])
+		}
+		return
+	}
+	cur.put(last, value)
+}
+
+// append adds the value to the array of a dotted key.
+func (t *table) append(path string, value any) {
+	keys := strings.Split(path, ".")
+	cur := t
+	for _, key := range keys[:len(keys)-1] {
+		cur = cur.sub(key)
+	}
+	last := keys[len(keys)-1]
+	items, _ := cur.values[last].([]any)
+	cur.put(last, append(items, value))
+}
+
+// isTableArray reports whether the value is a non-empty array of tables.
+func isTableArray(value any) bool {
+	items, ok := value.([]any)
+	if !ok || len(items) == 0 {
+		return false
+	}
+	for _, item := range items {
+		if _, ok := item.(*table); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// marshalTOML renders the tree as a TOML document. Top-level tables become sections,
+// top-level arrays of tables become [[array]] sections and everything deeper is written
+// as dotted keys.
+func (t *table) marshalTOML() []byte {
+	var b bytes.Buffer
+	for _, key := range t.keys {
+		value := t.values[key]
+		if _, ok := value.(*table); ok || isTableArray(value) {
+			continue
+		}
+		fmt.Fprintf(&b, "%s = %s\n", quoteKey(key), formatTOMLValue(value))
+	}
+	for _, key := range t.keys {
+		if s, ok := t.values[key].(*table); ok {
+			fmt.Fprintf(&b, "\n[%s]\n", quoteKey(key))
+			writeDottedKeys(&b, "", s)
+		}
+	}
+	for _, key := range t.keys {
+		if value := t.values[key]; isTableArray(value) {
+			for _, item := range value.([]any) {
+				fmt.Fprintf(&b, "\n[[%s]]\n", quoteKey(key))
+				writeDottedKeys(&b, "", item.(*table))
+			}
+		}
+	}
+	return bytes.TrimPrefix(b.Bytes(), []byte("\n"))
+}
+
+func writeDottedKeys(b *bytes.Buffer, prefix string, t *table) {
+	for _, key := range t.keys {
+		if s, ok := t.values[key].(*table); ok {
+			writeDottedKeys(b, prefix+quoteKey(key)+".", s)
+		} else {
+			fmt.Fprintf(b, "%s%s = %s\n", prefix, quoteKey(key), formatTOMLValue(t.values[key]))
+		}
+	}
+}
+
+func formatTOMLValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return quoteString(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = formatTOMLValue(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case *table:
+		items := make([]string, len(v.keys))
+		for i, key := range v.keys {
+			items[i] = quoteKey(key) + " = " + formatTOMLValue(v.values[key])
+		}
+		return "{" + strings.Join(items, ", ") + "}"
+	}
+	panic(fmt.Sprintf("unsupported value type %T", value))
+}
+
+var bareKeyRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func quoteKey(key string) string {
+	if bareKeyRegexp.MatchString(key) {
+		return key
+	}
+	return quoteString(key)
+}
+
+// quoteString returns a TOML basic string.
+func quoteString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// marshalTOML encodes the config with the frp v0.52+ schema.
+func (conf *ClientConfig) marshalTOML() ([]byte, error) {
+	doc, err := encodeClientConfig(conf)
+	if err != nil {
+		return nil, err
+	}
+	return doc.marshalTOML(), nil
+}
+
+// parseClientConfFromTOML decodes a config written with the frp v0.52+ schema.
+func parseClientConfFromTOML(b []byte) (*ClientConfig, error) {
+	var doc map[string]any
+	if err := toml.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	return decodeClientConfig(doc)
+}
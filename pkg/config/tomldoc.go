@@ -0,0 +1,645 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+var errMalformedTOML = errors.New("malformed toml document")
+
+// tomlLine is a piece of a TOML document: a blank line, a comment, a table header or
+// a key/value pair. The value of a pair may span several physical lines.
+type tomlLine struct {
+	text string
+	// key is the dotted key of a key/value pair, or nil for other lines.
+	key []string
+	// valueStart and valueEnd are the offsets of the value in text.
+	valueStart, valueEnd int
+}
+
+func (l *tomlLine) isComment() bool {
+	s := strings.TrimSpace(l.text)
+	return s != "" && s[0] == '#'
+}
+
+// value returns the raw text of the value.
+func (l *tomlLine) value() string {
+	return l.text[l.valueStart:l.valueEnd]
+}
+
+// setValue replaces the value text, keeping the key and any trailing comment.
+func (l *tomlLine) setValue(value string) {
+	l.text = l.text[:l.valueStart] + value + l.text[l.valueEnd:]
+	l.valueEnd = l.valueStart + len(value)
+}
+
+// tomlBlock is a table header with the lines following it. The root block has no header.
+type tomlBlock struct {
+	// lead holds the comments directly above the header.
+	lead   []*tomlLine
+	header *tomlLine
+	path   []string
+	array  bool
+	lines  []*tomlLine
+	// rel is the path of the table relative to the scope of the block.
+	rel []string
+	// opaque blocks are never changed, e.g. nested arrays of tables.
+	opaque bool
+	// prev is the block preceding this one in the original document.
+	prev *tomlBlock
+}
+
+func (b *tomlBlock) hasKeys() bool {
+	return slices.ContainsFunc(b.lines, func(l *tomlLine) bool { return l.key != nil })
+}
+
+func (b *tomlBlock) hasComments() bool {
+	return slices.ContainsFunc(b.lines, (*tomlLine).isComment)
+}
+
+// tomlScope is a group of blocks whose keys are relative to the same table: the root
+// table, or an element of an array of tables. The first block is the main block.
+type tomlScope struct {
+	array  string
+	blocks []*tomlBlock
+}
+
+// getString returns the string value of a key in the main block.
+func (s *tomlScope) getString(key string) string {
+	for _, line := range s.blocks[0].lines {
+		if slices.Equal(line.key, []string{key}) {
+			if v, err := decodeTOMLValue(line.value()); err == nil {
+				value, _ := v.(string)
+				return value
+			}
+		}
+	}
+	return ""
+}
+
+// tomlDocument is an editable TOML document that keeps the original text of
+// everything it doesn't change.
+type tomlDocument struct {
+	blocks []*tomlBlock
+	crlf   bool
+}
+
+func parseTOMLDocument(b []byte) (*tomlDocument, error) {
+	var v map[string]any
+	if err := toml.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	s := string(b)
+	doc := &tomlDocument{crlf: strings.Contains(s, "\r\n")}
+	s = strings.TrimSuffix(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	cur := &tomlBlock{}
+	doc.blocks = append(doc.blocks, cur)
+	for pos := 0; pos <= len(s); {
+		end := strings.IndexByte(s[pos:], '\n')
+		if end < 0 {
+			end = len(s)
+		} else {
+			end += pos
+		}
+		start := pos + len(s[pos:end]) - len(strings.TrimLeft(s[pos:end], " \t"))
+		switch {
+		case start == end || s[start] == '#':
+			cur.lines = append(cur.lines, &tomlLine{text: s[pos:end]})
+		case s[start] == '[':
+			block := &tomlBlock{header: &tomlLine{text: s[pos:end]}, array: strings.HasPrefix(s[start:], "[[")}
+			i := start + 1
+			if block.array {
+				i++
+			}
+			path, _, err := scanTOMLKey(s, i)
+			if err != nil {
+				return nil, err
+			}
+			block.path = path
+			// Comments directly above the header belong to it
+			i = len(cur.lines)
+			for i > 0 && cur.lines[i-1].isComment() {
+				i--
+			}
+			block.lead, cur.lines = slices.Clone(cur.lines[i:]), cur.lines[:i:i]
+			block.prev, cur = cur, block
+			doc.blocks = append(doc.blocks, cur)
+		default:
+			key, i, err := scanTOMLKey(s, start)
+			if err != nil {
+				return nil, err
+			}
+			if i >= len(s) || s[i] != '=' {
+				return nil, errMalformedTOML
+			}
+			i = skipSpaces(s, i+1)
+			valueEnd, err := scanTOMLValue(s, i)
+			if err != nil {
+				return nil, err
+			}
+			if end = strings.IndexByte(s[valueEnd:], '\n'); end < 0 {
+				end = len(s)
+			} else {
+				end += valueEnd
+			}
+			cur.lines = append(cur.lines, &tomlLine{text: s[pos:end], key: key, valueStart: i - pos, valueEnd: valueEnd - pos})
+		}
+		pos = end + 1
+	}
+	return doc, nil
+}
+
+func skipSpaces(s string, i int) int {
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+		i++
+	}
+	return i
+}
+
+// scanTOMLKey reads a dotted key starting at i. It returns the key parts and the offset
+// of the first character after the key and its trailing whitespace.
+func scanTOMLKey(s string, i int) ([]string, int, error) {
+	var keys []string
+	for {
+		i = skipSpaces(s, i)
+		if i >= len(s) {
+			return nil, i, errMalformedTOML
+		}
+		switch s[i] {
+		case '"':
+			j := i + 1
+			for j < len(s) && s[j] != '"' {
+				if s[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(s) {
+				return nil, j, errMalformedTOML
+			}
+			key, err := strconv.Unquote(s[i : j+1])
+			if err != nil {
+				return nil, j, err
+			}
+			keys = append(keys, key)
+			i = j + 1
+		case '\'':
+			j := strings.IndexByte(s[i+1:], '\'')
+			if j < 0 {
+				return nil, i, errMalformedTOML
+			}
+			keys = append(keys, s[i+1:i+1+j])
+			i += j + 2
+		default:
+			j := i
+			for j < len(s) && isBareKeyChar(s[j]) {
+				j++
+			}
+			if j == i {
+				return nil, i, errMalformedTOML
+			}
+			keys = append(keys, s[i:j])
+			i = j
+		}
+		i = skipSpaces(s, i)
+		if i >= len(s) || s[i] != '.' {
+			return keys, i, nil
+		}
+		i++
+	}
+}
+
+func isBareKeyChar(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+// scanTOMLValue returns the end offset of the value starting at i.
+// Multi-line strings, arrays and inline tables may span several lines.
+func scanTOMLValue(s string, i int) (int, error) {
+	depth := 0
+	end := i
+	for i < len(s) {
+		switch c := s[i]; {
+		case strings.HasPrefix(s[i:], `"""`), strings.HasPrefix(s[i:], "'''"):
+			delim := s[i : i+3]
+			j := i + 3
+			for j < len(s) && !strings.HasPrefix(s[j:], delim) {
+				if delim[0] == '"' && s[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(s) {
+				return 0, errMalformedTOML
+			}
+			// Up to two quotes are allowed right before the closing delimiter
+			j += 3
+			for k := 0; k < 2 && j < len(s) && s[j] == delim[0]; k++ {
+				j++
+			}
+			i, end = j, j
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(s) && s[j] != c && s[j] != '\n' {
+				if c == '"' && s[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(s) || s[j] != c {
+				return 0, errMalformedTOML
+			}
+			i, end = j+1, j+1
+		case c == '#' || c == '\n':
+			if depth == 0 {
+				return end, nil
+			}
+			if c == '#' {
+				if j := strings.IndexByte(s[i:], '\n'); j >= 0 {
+					i += j
+					continue
+				}
+				return 0, errMalformedTOML
+			}
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		default:
+			if c == '[' || c == '{' {
+				depth++
+			} else if c == ']' || c == '}' {
+				depth--
+			}
+			i++
+			end = i
+		}
+	}
+	if depth != 0 {
+		return 0, errMalformedTOML
+	}
+	return end, nil
+}
+
+// decodeTOMLValue decodes the raw text of a single value.
+func decodeTOMLValue(raw string) (any, error) {
+	var v map[string]any
+	if err := toml.Unmarshal([]byte("v = "+raw), &v); err != nil {
+		return nil, err
+	}
+	return v["v"], nil
+}
+
+// plainValue converts a value of the document tree to the types produced by the TOML decoder.
+func plainValue(value any) any {
+	switch v := value.(type) {
+	case *table:
+		m := make(map[string]any, len(v.keys))
+		for _, key := range v.keys {
+			m[key] = plainValue(v.values[key])
+		}
+		return m
+	case []any:
+		items := make([]any, len(v))
+		for i, item := range v {
+			items[i] = plainValue(item)
+		}
+		return items
+	}
+	return value
+}
+
+func formatTOMLKey(keys []string) string {
+	quoted := make([]string, len(keys))
+	for i, key := range keys {
+		quoted[i] = quoteKey(key)
+	}
+	return strings.Join(quoted, ".")
+}
+
+func (d *tomlDocument) bytes() []byte {
+	var lines []string
+	var prev *tomlBlock
+	for _, block := range d.blocks {
+		// Separate tables that were not adjacent in the original document
+		if block.header != nil && block.prev != prev && len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) != "" {
+			lines = append(lines, "")
+		}
+		prev = block
+		for _, line := range block.lead {
+			lines = append(lines, line.text)
+		}
+		if block.header != nil {
+			lines = append(lines, block.header.text)
+		}
+		for _, line := range block.lines {
+			lines = append(lines, line.text)
+		}
+	}
+	s := strings.Join(lines, "\n") + "\n"
+	if d.crlf {
+		s = strings.ReplaceAll(s, "\n", "\r\n")
+	}
+	return []byte(s)
+}
+
+// scopes groups the blocks into the root scope and one scope per element of the
+// top-level arrays of tables.
+func (d *tomlDocument) scopes() (root *tomlScope, elements []*tomlScope) {
+	root = &tomlScope{}
+	var cur *tomlScope
+	for _, block := range d.blocks {
+		switch {
+		case block.header == nil:
+			root.blocks = append(root.blocks, block)
+		case block.array && len(block.path) == 1:
+			cur = &tomlScope{array: block.path[0], blocks: []*tomlBlock{block}}
+			elements = append(elements, cur)
+		case cur != nil && len(block.path) > 1 && block.path[0] == cur.array:
+			block.rel = block.path[1:]
+			block.opaque = block.array
+			cur.blocks = append(cur.blocks, block)
+		default:
+			block.rel = block.path
+			block.opaque = block.array
+			root.blocks = append(root.blocks, block)
+		}
+	}
+	return
+}
+
+// tomlLeaf is a non-table value of the document tree.
+type tomlLeaf struct {
+	path  []string
+	value any
+	used  bool
+}
+
+func flattenTable(t *table, prefix []string, leaves []*tomlLeaf) []*tomlLeaf {
+	for _, key := range t.keys {
+		path := append(slices.Clip(prefix), key)
+		if s, ok := t.values[key].(*table); ok {
+			leaves = flattenTable(s, path, leaves)
+		} else {
+			leaves = append(leaves, &tomlLeaf{path: path, value: t.values[key]})
+		}
+	}
+	return leaves
+}
+
+// subTable returns the nested table at the path.
+func subTable(t *table, path []string) (*table, bool) {
+	for _, key := range path {
+		s, ok := t.values[key].(*table)
+		if !ok {
+			return nil, false
+		}
+		t = s
+	}
+	return t, true
+}
+
+func hasPrefix(path, prefix []string) bool {
+	return len(path) >= len(prefix) && slices.Equal(path[:len(prefix)], prefix)
+}
+
+// knownPaths returns a function reporting whether a key path is managed by the codec:
+// the key of a field, a key nested under it, or a table containing fields.
+func knownPaths(v any, extra ...string) func(path []string) bool {
+	var fields [][]string
+	walkFields(reflect.ValueOf(v).Elem(), nil, func(field reflect.StructField, fv reflect.Value, opts fieldOptions) error {
+		fields = append(fields, strings.Split(opts.path, "."))
+		return nil
+	})
+	for _, key := range extra {
+		fields = append(fields, []string{key})
+	}
+	return func(path []string) bool {
+		return slices.ContainsFunc(fields, func(field []string) bool {
+			return hasPrefix(path, field) || hasPrefix(field, path)
+		})
+	}
+}
+
+var (
+	knownCommonPath = knownPaths(&ClientCommon{}, "proxies", "visitors")
+	knownProxyPath  = knownPaths(&Proxy{})
+)
+
+// patchScope applies the table to the keys of the scope. Keys with unchanged values are
+// kept as they are, keys managed by the codec but absent from the table are removed and
+// new keys are inserted next to their siblings. It returns the blocks created for new tables.
+// Values implied by missing keys are not inserted.
+func (d *tomlDocument) patchScope(s *tomlScope, t, implied *table, known func([]string) bool) []*tomlBlock {
+	leaves := flattenTable(t, nil, nil)
+	if s.array == "" {
+		leaves = slices.DeleteFunc(leaves, func(leaf *tomlLeaf) bool {
+			return leaf.path[0] == "proxies" || leaf.path[0] == "visitors"
+		})
+	}
+	for _, block := range s.blocks {
+		if block.opaque {
+			continue
+		}
+		block.lines = slices.DeleteFunc(block.lines, func(line *tomlLine) bool {
+			if line.key == nil {
+				return false
+			}
+			path := append(slices.Clip(block.rel), line.key...)
+			var value any
+			for _, leaf := range leaves {
+				if slices.Equal(leaf.path, path) {
+					leaf.used = true
+					value = leaf.value
+				} else if hasPrefix(leaf.path, path) {
+					// An inline table covering several fields
+					leaf.used = true
+					value, _ = subTable(t, path)
+				}
+			}
+			if value == nil {
+				return known(path)
+			}
+			if old, err := decodeTOMLValue(line.value()); err != nil || !reflect.DeepEqual(old, plainValue(value)) {
+				line.setValue(formatTOMLValue(value))
+			}
+			return false
+		})
+	}
+	var created []*tomlBlock
+	for _, leaf := range leaves {
+		if leaf.used {
+			continue
+		}
+		if values, ok := subTable(implied, leaf.path[:len(leaf.path)-1]); ok &&
+			reflect.DeepEqual(plainValue(values.values[leaf.path[len(leaf.path)-1]]), plainValue(leaf.value)) {
+			continue
+		}
+		// Find the deepest table containing the key
+		var target *tomlBlock
+		for _, block := range s.blocks {
+			if !block.opaque && len(block.rel) < len(leaf.path) && hasPrefix(leaf.path, block.rel) &&
+				(target == nil || len(block.rel) > len(target.rel)) {
+				target = block
+			}
+		}
+		if len(target.rel) == 0 && s.array == "" && len(leaf.path) > 1 {
+			// Start a new table for the top-level table of the key
+			target = &tomlBlock{
+				header: &tomlLine{text: "[" + quoteKey(leaf.path[0]) + "]"},
+				path:   leaf.path[:1],
+				rel:    leaf.path[:1],
+			}
+			s.blocks = append(s.blocks, target)
+			created = append(created, target)
+		}
+		line := &tomlLine{key: leaf.path[len(target.rel):]}
+		line.text = formatTOMLKey(line.key) + " = "
+		line.valueStart = len(line.text)
+		line.text += formatTOMLValue(leaf.value)
+		line.valueEnd = len(line.text)
+		// Insert after the key sharing the longest prefix, or after the last key.
+		// A table without keys gets it after its last non-blank line.
+		pos, best := len(target.lines), -1
+		for pos > 0 && strings.TrimSpace(target.lines[pos-1].text) == "" {
+			pos--
+		}
+		for i, l := range target.lines {
+			if l.key == nil {
+				continue
+			}
+			n := 0
+			for path := append(slices.Clip(target.rel), l.key...); n < len(path) && n < len(leaf.path) && path[n] == leaf.path[n]; n++ {
+			}
+			if n >= best {
+				pos, best = i+1, n
+			}
+		}
+		target.lines = slices.Insert(target.lines, pos, line)
+	}
+	// Drop tables left without any content
+	s.blocks = slices.DeleteFunc(s.blocks, func(block *tomlBlock) bool {
+		return block.header != nil && block != s.blocks[0] && !block.opaque &&
+			!block.hasKeys() && !block.hasComments() && known(block.rel)
+	})
+	return created
+}
+
+// newElementScope renders an array element from scratch.
+func newElementScope(array string, t *table) *tomlScope {
+	var b bytes.Buffer
+	writeDottedKeys(&b, "", t)
+	block := &tomlBlock{
+		header: &tomlLine{text: "[[" + quoteKey(array) + "]]"},
+		path:   []string{array},
+		array:  true,
+	}
+	for _, text := range strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n") {
+		key, i, err := scanTOMLKey(text, 0)
+		if err != nil {
+			panic(err)
+		}
+		i = skipSpaces(text, i+1)
+		block.lines = append(block.lines, &tomlLine{text: text, key: key, valueStart: i, valueEnd: len(text)})
+	}
+	return &tomlScope{array: array, blocks: []*tomlBlock{block}}
+}
+
+// patch applies the document tree onto the document.
+// The implied tree is the original document as the codec sees it, which holds the values
+// implied by the keys missing from it.
+func (d *tomlDocument) patch(t, implied *table) {
+	root, elements := d.scopes()
+	created := d.patchScope(root, t, implied, knownCommonPath)
+	// Elements of the managed arrays are matched by name. An element left at the same
+	// position with the same type is considered renamed.
+	groups := make(map[string][]*tomlScope)
+	for _, array := range []string{"proxies", "visitors"} {
+		var items []*table
+		if values, ok := t.values[array].([]any); ok {
+			for _, item := range values {
+				items = append(items, item.(*table))
+			}
+		}
+		all := slices.DeleteFunc(slices.Clone(elements), func(s *tomlScope) bool { return s.array != array })
+		old := slices.Clone(all)
+		impliedItems, _ := implied.values[array].([]any)
+		matched := make([]*tomlScope, len(items))
+		for i, item := range items {
+			name, _ := item.values["name"].(string)
+			for j, s := range old {
+				if s != nil && s.getString("name") == name {
+					matched[i], old[j] = s, nil
+					break
+				}
+			}
+		}
+		for i, item := range items {
+			if matched[i] == nil && i < len(old) && old[i] != nil && old[i].getString("type") == item.values["type"] {
+				matched[i], old[i] = old[i], nil
+			}
+		}
+		for i, item := range items {
+			if matched[i] == nil {
+				matched[i] = newElementScope(array, item)
+			} else {
+				impliedItem := newTable()
+				if j := slices.Index(all, matched[i]); len(impliedItems) == len(all) {
+					impliedItem = impliedItems[j].(*table)
+				}
+				d.patchScope(matched[i], item, impliedItem, knownProxyPath)
+			}
+		}
+		groups[array] = matched
+	}
+	// Rebuild the block list with the elements in their new order
+	var blocks []*tomlBlock
+	placed := make(map[string]bool)
+	place := func(array string) {
+		if !placed[array] {
+			placed[array] = true
+			for _, s := range groups[array] {
+				blocks = append(blocks, s.blocks...)
+			}
+		}
+	}
+	for _, block := range d.blocks {
+		if slices.Contains(root.blocks, block) {
+			blocks = append(blocks, block)
+			continue
+		}
+		switch array := elementArray(elements, block); array {
+		case "proxies", "visitors":
+			place(array)
+		case "":
+			// A table removed from the root scope
+		default:
+			// Elements of other arrays of tables are kept at their position
+			blocks = append(blocks, block)
+		}
+	}
+	// New tables of the root scope go before the first array element
+	i := slices.IndexFunc(blocks, func(block *tomlBlock) bool { return block.array && len(block.path) == 1 })
+	if i < 0 {
+		i = len(blocks)
+	}
+	blocks = slices.Insert(blocks, i, created...)
+	place("proxies")
+	place("visitors")
+	d.blocks = blocks
+}
+
+// elementArray returns the array name of the element containing the block.
+func elementArray(elements []*tomlScope, block *tomlBlock) string {
+	for _, s := range elements {
+		if slices.Contains(s.blocks, block) {
+			return s.array
+		}
+	}
+	return ""
+}
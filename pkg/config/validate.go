@@ -0,0 +1,521 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/hzcrv1911/frpcgui/pkg/consts"
+)
+
+// Severity tells how serious a diagnostic is.
+type Severity string
+
+const (
+	// SeverityError marks a problem that prevents frpc from using the config.
+	SeverityError Severity = "error"
+	// SeverityWarning marks a suspicious setting that frpc accepts.
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic codes
+const (
+	CodeRequired      = "required"
+	CodeInvalidValue  = "invalid_value"
+	CodeInvalidPort   = "invalid_port"
+	CodePortMismatch  = "port_mismatch"
+	CodePortOverlap   = "port_overlap"
+	CodeDuplicateName = "duplicate_name"
+	CodeIncomplete    = "incomplete"
+	CodeUnknownRef    = "unknown_reference"
+	CodeInsecure      = "insecure"
+	CodeIgnored       = "ignored"
+	CodeNoProxies     = "no_proxies"
+)
+
+// Diagnostic describes a single problem found in a config.
+type Diagnostic struct {
+	// Path of the offending option, e.g. "proxies[ssh].remote_port". Options are named
+	// after their ini keys, or their TOML paths if they have no ini equivalent.
+	Path     string   `json:"path"`
+	Severity Severity `json:"severity"`
+	// Code is a machine-readable kind of the problem, one of the Code* constants.
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	if d.Path == "" {
+		return d.Message
+	}
+	return d.Path + ": " + d.Message
+}
+
+// Diagnostics is a list of problems. It can be used as an error listing all of them.
+type Diagnostics []Diagnostic
+
+func (ds Diagnostics) Error() string {
+	lines := make([]string, len(ds))
+	for i, d := range ds {
+		lines[i] = d.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Errors returns the diagnostics with the error severity.
+func (ds Diagnostics) Errors() Diagnostics {
+	return ds.filter(SeverityError)
+}
+
+// Warnings returns the diagnostics with the warning severity.
+func (ds Diagnostics) Warnings() Diagnostics {
+	return ds.filter(SeverityWarning)
+}
+
+// HasErrors reports whether any diagnostic has the error severity.
+func (ds Diagnostics) HasErrors() bool {
+	return slices.ContainsFunc(ds, func(d Diagnostic) bool { return d.Severity == SeverityError })
+}
+
+// Err returns the error diagnostics as an error, or nil if there are none.
+func (ds Diagnostics) Err() error {
+	if errs := ds.Errors(); len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (ds Diagnostics) filter(severity Severity) Diagnostics {
+	var result Diagnostics
+	for _, d := range ds {
+		if d.Severity == severity {
+			result = append(result, d)
+		}
+	}
+	return result
+}
+
+func (ds *Diagnostics) errorf(path, code, format string, a ...any) {
+	*ds = append(*ds, Diagnostic{Path: path, Severity: SeverityError, Code: code, Message: fmt.Sprintf(format, a...)})
+}
+
+func (ds *Diagnostics) warnf(path, code, format string, a ...any) {
+	*ds = append(*ds, Diagnostic{Path: path, Severity: SeverityWarning, Code: code, Message: fmt.Sprintf(format, a...)})
+}
+
+// add appends the diagnostics with their paths nested under the prefix.
+func (ds *Diagnostics) add(prefix string, other Diagnostics) {
+	for _, d := range other {
+		d.Path = prefix + "." + d.Path
+		*ds = append(*ds, d)
+	}
+}
+
+// ValidateClientConf loads a config from a path or its content and validates it.
+// The error is only returned if the config can't be read or decoded.
+func ValidateClientConf(source interface{}) (Diagnostics, error) {
+	conf, err := ParseClientConf(source)
+	if err != nil {
+		return nil, err
+	}
+	return conf.Validate(), nil
+}
+
+// Validate checks the whole config and returns every problem found. Options which have
+// no effect are reported as well, unless the config is already completed.
+func (conf *ClientConfig) Validate() Diagnostics {
+	ds := conf.ClientCommon.Validate()
+	if len(conf.Proxies) == 0 {
+		ds.errorf("proxies", CodeNoProxies, "at least one proxy must be defined")
+	}
+	names := make(map[string]bool)
+	visitors := make(map[string]bool)
+	for _, proxy := range conf.Proxies {
+		if proxy.IsVisitor() {
+			visitors[proxy.Name] = true
+		}
+	}
+	aliases := make(map[string]bool)
+	for i, proxy := range conf.Proxies {
+		prefix := proxyPath(i, proxy)
+		ds.add(prefix, proxy.Validate())
+		if proxy.Name != "" {
+			if names[proxy.Name] {
+				ds.errorf(prefix+".name", CodeDuplicateName, "proxy name %q is used more than once", proxy.Name)
+			} else {
+				for _, alias := range proxy.GetAlias() {
+					if aliases[alias] {
+						ds.errorf(prefix+".name", CodeDuplicateName, "proxy name %q is used more than once", alias)
+						break
+					}
+					aliases[alias] = true
+				}
+			}
+			names[proxy.Name] = true
+		}
+		if proxy.IsVisitor() && proxy.FallbackTo != "" && (proxy.FallbackTo == proxy.Name || !visitors[proxy.FallbackTo]) {
+			ds.errorf(prefix+".fallback_to", CodeUnknownRef, "fallback visitor %q is not defined", proxy.FallbackTo)
+		}
+	}
+	for _, name := range conf.Start {
+		if !aliases[name] {
+			ds.warnf("start", CodeUnknownRef, "proxy %q is not defined", name)
+		}
+	}
+	return ds
+}
+
+// proxyPath returns the path of the proxy in diagnostics.
+func proxyPath(i int, proxy *Proxy) string {
+	array := "proxies"
+	if proxy.IsVisitor() {
+		array = "visitors"
+	}
+	if proxy.Name == "" {
+		return fmt.Sprintf("%s[%d]", array, i)
+	}
+	return fmt.Sprintf("%s[%s]", array, proxy.Name)
+}
+
+// Validate checks the common options of a config.
+func (cc *ClientCommon) Validate() Diagnostics {
+	var ds Diagnostics
+	if cc.ServerAddress == "" {
+		ds.errorf("server_addr", CodeRequired, "server address is required")
+	}
+	checkPort(&ds, "server_port", cc.ServerPort, 1)
+	checkPort(&ds, "admin_port", cc.AdminPort, 0)
+	checkOneOf(&ds, "authentication_method", cc.AuthMethod, consts.AuthToken, consts.AuthOIDC)
+	switch cc.AuthMethod {
+	case consts.AuthToken:
+		checkOneOf(&ds, "auth.tokenSource.type", cc.TokenSource, "file")
+		if cc.TokenSource == "file" && cc.TokenSourceFile == "" {
+			ds.errorf("auth.tokenSource.file.path", CodeRequired, "token file is required")
+		}
+	case consts.AuthOIDC:
+		if cc.OIDCClientId == "" {
+			ds.errorf("oidc_client_id", CodeRequired, "OIDC client ID is required")
+		}
+		if cc.OIDCTokenEndpoint == "" {
+			ds.errorf("oidc_token_endpoint_url", CodeRequired, "OIDC token endpoint URL is required")
+		}
+	}
+	checkOneOf(&ds, "protocol", cc.Protocol, consts.Protocols...)
+	checkOneOf(&ds, "log_level", cc.LogLevel, consts.LogLevels...)
+	checkOneOf(&ds, "frpcgui_delete_method", cc.DeleteMethod, consts.DeleteAbsolute, consts.DeleteRelative)
+	if cc.TLSEnable {
+		checkPair(&ds, SeverityError, "tls_cert_file", cc.TLSCertFile, "tls_key_file", cc.TLSKeyFile)
+	}
+	// Options cleared by completing the config have no effect
+	completed := ClientConfig{ClientCommon: *cc}
+	completed.Complete(true)
+	defaults := NewDefaultClientConfig().ClientCommon
+	checkIgnored(&ds, cc, &completed.ClientCommon, &defaults, cc.ignoredBy)
+	return ds
+}
+
+// ignoredBy describes what makes a common field have no effect.
+func (cc *ClientCommon) ignoredBy(field reflect.StructField) string {
+	switch {
+	case field.Name == "AuthMethod":
+		return "configs without a token"
+	case field.Name == "Token" && cc.TokenSource != "":
+		return "configs reading the token from a file"
+	case field.Name == "TokenSource" || field.Name == "TokenSourceFile":
+		if cc.Format == consts.FormatINI {
+			return "the INI format"
+		}
+		return "configs without a token source"
+	case strings.HasPrefix(field.Name, "OIDC"), strings.HasPrefix(field.Name, "Authenticate"), field.Name == "Token":
+		if cc.AuthMethod == "" {
+			return "configs without authentication"
+		}
+		return "the " + cc.AuthMethod + " authentication method"
+	case strings.HasPrefix(field.Name, "Admin"), field.Name == "AssetsDir", field.Name == "PprofEnable":
+		return "configs without admin_port"
+	case strings.HasPrefix(field.Name, "TLS"):
+		return "configs with tls_enable disabled"
+	case strings.HasPrefix(field.Name, "QUIC"):
+		return "protocols other than quic"
+	case strings.HasPrefix(field.Name, "DialServer"):
+		return "the quic protocol"
+	case field.Name == "TCPMuxKeepaliveInterval":
+		return "configs with tcp_mux disabled"
+	case field.Name == "DeleteMethod":
+		return "configs without an expiry"
+	case strings.HasPrefix(field.Name, "Delete"):
+		if cc.DeleteMethod == "" {
+			return "configs without a delete method"
+		}
+		return "the " + cc.DeleteMethod + " delete method"
+	}
+	return "this config"
+}
+
+// Validate checks the proxy against the rules of its type, plugin and health check.
+// The paths of the diagnostics are relative to the proxy.
+func (p *Proxy) Validate() Diagnostics {
+	var ds Diagnostics
+	if p.Name == "" {
+		ds.errorf("name", CodeRequired, "proxy name is required")
+	}
+	if !slices.Contains(consts.ProxyTypes, p.Type) {
+		ds.errorf("type", CodeInvalidValue, "unsupported proxy type %q", p.Type)
+		return ds
+	}
+	if p.IsVisitor() {
+		p.validateVisitor(&ds)
+	} else {
+		p.validateProxy(&ds)
+	}
+	completed := *p
+	completed.Complete()
+	checkIgnored(&ds, p, &completed, nil, p.ignoredBy)
+	return ds
+}
+
+func (p *Proxy) validateVisitor(ds *Diagnostics) {
+	if p.ServerName == "" {
+		ds.errorf("server_name", CodeRequired, "server name is required")
+	}
+	// A negative port only creates the visitor without listening
+	if p.BindPort == 0 {
+		ds.errorf("bind_port", CodeRequired, "bind port is required")
+	} else if p.BindPort < -1 || p.BindPort > 65535 {
+		ds.errorf("bind_port", CodeInvalidPort, "invalid port %d", p.BindPort)
+	}
+	if p.SK == "" {
+		ds.warnf("sk", CodeInsecure, "secret key is empty")
+	}
+	if p.Type == consts.ProxyTypeXTCP {
+		checkOneOf(ds, "protocol", p.Protocol, "kcp", "quic")
+	}
+}
+
+func (p *Proxy) validateProxy(ds *Diagnostics) {
+	switch p.Type {
+	case consts.ProxyTypeSTCP, consts.ProxyTypeXTCP, consts.ProxyTypeSUDP:
+		checkOneOf(ds, "role", p.Role, "server", "visitor")
+		if p.SK == "" {
+			ds.warnf("sk", CodeInsecure, "secret key is empty, anyone knowing the proxy name can visit it")
+		}
+	case consts.ProxyTypeHTTP, consts.ProxyTypeHTTPS, consts.ProxyTypeTCPMUX:
+		if p.CustomDomains == "" && p.SubDomain == "" {
+			ds.errorf("custom_domains", CodeRequired, "custom domains or subdomain is required")
+		}
+		if p.Type == consts.ProxyTypeTCPMUX {
+			if p.Multiplexer == "" {
+				ds.errorf("multiplexer", CodeRequired, "multiplexer is required")
+			} else {
+				checkOneOf(ds, "multiplexer", p.Multiplexer, "httpconnect")
+			}
+		}
+	}
+	if p.Plugin == "" {
+		if p.LocalPort == "" {
+			ds.errorf("local_port", CodeRequired, "local port or plugin is required")
+		}
+	} else if !slices.Contains(consts.PluginTypes, p.Plugin) {
+		ds.errorf("plugin", CodeInvalidValue, "unsupported plugin %q", p.Plugin)
+	} else {
+		p.validatePlugin(ds)
+	}
+	p.validatePorts(ds)
+	if p.BandwidthLimit != "" && !bandwidthRegexp.MatchString(p.BandwidthLimit) {
+		ds.errorf("bandwidth_limit", CodeInvalidValue, "invalid bandwidth %q, expected a number followed by MB or KB", p.BandwidthLimit)
+	}
+	checkOneOf(ds, "bandwidth_limit_mode", p.BandwidthLimitMode, consts.BandwidthMode...)
+	checkOneOf(ds, "proxy_protocol_version", p.ProxyProtocolVersion, "v1", "v2")
+	if p.GroupKey != "" && p.Group == "" {
+		ds.warnf("group_key", CodeIgnored, "group key has no effect without a group")
+	}
+	checkOneOf(ds, "health_check_type", p.HealthCheckType, "tcp", "http")
+	if p.HealthCheckType == "http" && p.HealthCheckURL == "" {
+		ds.errorf("health_check_url", CodeRequired, "health check URL is required")
+	}
+	for _, field := range []struct {
+		key   string
+		value int
+	}{
+		{"health_check_timeout_s", p.HealthCheckTimeoutS},
+		{"health_check_max_failed", p.HealthCheckMaxFailed},
+		{"health_check_interval_s", p.HealthCheckIntervalS},
+	} {
+		if field.value < 0 {
+			ds.errorf(field.key, CodeInvalidValue, "value must not be negative")
+		}
+	}
+}
+
+func (p *Proxy) validatePlugin(ds *Diagnostics) {
+	switch p.Plugin {
+	case consts.PluginHttp2Https, consts.PluginHttp2Http, consts.PluginHttps2Http, consts.PluginHttps2Https, consts.PluginTLS2Raw:
+		if p.PluginLocalAddr == "" {
+			ds.errorf("plugin_local_addr", CodeRequired, "local address is required by the %s plugin", p.Plugin)
+		}
+	case consts.PluginStaticFile:
+		if p.PluginLocalPath == "" {
+			ds.errorf("plugin_local_path", CodeRequired, "local path is required by the %s plugin", p.Plugin)
+		}
+	case consts.PluginUnixDomain:
+		if p.PluginUnixPath == "" {
+			ds.errorf("plugin_unix_path", CodeRequired, "unix path is required by the %s plugin", p.Plugin)
+		}
+	}
+	switch p.Plugin {
+	case consts.PluginHttps2Http, consts.PluginHttps2Https, consts.PluginTLS2Raw:
+		checkPair(ds, SeverityError, "plugin_crt_path", p.PluginCrtPath, "plugin_key_path", p.PluginKeyPath)
+	case consts.PluginSocks5:
+		checkPair(ds, SeverityWarning, "plugin_user", p.PluginUser, "plugin_passwd", p.PluginPasswd)
+	case consts.PluginHttpProxy, consts.PluginStaticFile:
+		checkPair(ds, SeverityWarning, "plugin_http_user", p.PluginHttpUser, "plugin_http_passwd", p.PluginHttpPasswd)
+	}
+}
+
+func (p *Proxy) validatePorts(ds *Diagnostics) {
+	var localPorts PortSpec
+	if p.Plugin == "" && p.LocalPort != "" {
+		localPorts = checkPorts(ds, "local_port", p.LocalPort, p.IsRange(), 1)
+	}
+	if p.Type != consts.ProxyTypeTCP && p.Type != consts.ProxyTypeUDP {
+		return
+	}
+	remotePorts := checkPorts(ds, "remote_port", p.RemotePort, p.IsRange(), 0)
+	if p.IsRange() && localPorts != nil && remotePorts != nil && localPorts.Count() != remotePorts.Count() {
+		ds.errorf("remote_port", CodePortMismatch, "%d remote ports are given for %d local ports", remotePorts.Count(), localPorts.Count())
+	}
+}
+
+// ignoredBy describes what makes a field of the proxy have no effect.
+func (p *Proxy) ignoredBy(field reflect.StructField) string {
+	switch {
+	case p.IsVisitor():
+		switch field.Name {
+		case "MaxRetriesAnHour", "MinRetryInterval":
+			return "visitors without keep_tunnel_open"
+		case "FallbackTimeoutMs":
+			return "visitors without fallback_to"
+		}
+		return p.Type + " visitors"
+	case field.Name == "LocalIP" || field.Name == "LocalPort":
+		return "plugin proxies"
+	case strings.HasPrefix(field.Name, "Plugin"):
+		if p.Plugin == "" {
+			return "proxies without a plugin"
+		}
+		return "the " + p.Plugin + " plugin"
+	case strings.HasPrefix(field.Name, "HealthCheck"):
+		if p.HealthCheckType == "" {
+			return "proxies without a health check"
+		}
+		return p.HealthCheckType + " health checks"
+	}
+	return p.Type + " proxies"
+}
+
+var bandwidthRegexp = regexp.MustCompile(`^\d+(MB|KB)$`)
+
+// checkIgnored reports the options which are set but cleared by completing the struct.
+// Options left at their defaults are not reported.
+func checkIgnored(ds *Diagnostics, raw, completed, defaults any, reason func(field reflect.StructField) string) {
+	rawFields := structFields(reflect.ValueOf(raw).Elem())
+	completedFields := structFields(reflect.ValueOf(completed).Elem())
+	var defaultFields []structField
+	if defaults != nil {
+		defaultFields = structFields(reflect.ValueOf(defaults).Elem())
+	}
+	for i, f := range rawFields {
+		value := f.value.Interface()
+		if isEmptyValue(f.value) || reflect.DeepEqual(value, completedFields[i].value.Interface()) {
+			continue
+		}
+		if defaultFields != nil && reflect.DeepEqual(value, defaultFields[i].value.Interface()) {
+			continue
+		}
+		ds.warnf(fieldKey(f.field), CodeIgnored, "option is not used by %s and will be ignored", reason(f.field))
+	}
+}
+
+type structField struct {
+	field reflect.StructField
+	value reflect.Value
+}
+
+// structFields returns the fields the codec handles, in a stable order.
+func structFields(v reflect.Value) []structField {
+	var fields []structField
+	walkFields(v, nil, func(field reflect.StructField, fv reflect.Value, opts fieldOptions) error {
+		fields = append(fields, structField{field, fv})
+		return nil
+	})
+	return fields
+}
+
+// fieldKey returns the name of the field in diagnostics.
+func fieldKey(field reflect.StructField) string {
+	if name, _, _ := strings.Cut(field.Tag.Get("ini"), ","); name != "" && name != "-" {
+		return name
+	}
+	if path := parseFieldOptions(field.Tag.Get("toml")).path; path != "" && path != "-" {
+		return path
+	}
+	return strings.ToLower(field.Name)
+}
+
+func checkPort(ds *Diagnostics, key string, port, min int) {
+	if port < min || port > 65535 {
+		ds.errorf(key, CodeInvalidPort, "invalid port %d", port)
+	}
+}
+
+// checkPorts validates a port list and returns it, or nil if it is invalid.
+func checkPorts(ds *Diagnostics, key, value string, multiple bool, min int) PortSpec {
+	ports, err := ParsePortSpec(value)
+	if err != nil {
+		ds.errorf(key, CodeInvalidPort, "%v", err)
+		return nil
+	}
+	switch {
+	case multiple && len(ports) == 0:
+		ds.errorf(key, CodeRequired, "ports are required by range proxies")
+		return nil
+	case !multiple && len(ports) == 0:
+		ports = PortSpec{{0, 0}}
+	case !multiple && ports.IsRange():
+		ds.errorf(key, CodeInvalidPort, "invalid port %q", value)
+		return nil
+	}
+	if err = ports.Validate(min); errors.Is(err, ErrPortOverlap) {
+		ds.errorf(key, CodePortOverlap, "%v", err)
+		return nil
+	} else if err != nil {
+		ds.errorf(key, CodeInvalidPort, "%v", err)
+		return nil
+	}
+	return ports
+}
+
+// checkOneOf reports a non-empty value which is not one of the allowed values.
+func checkOneOf(ds *Diagnostics, key, value string, allowed ...string) {
+	if value != "" && !slices.Contains(allowed, value) {
+		ds.errorf(key, CodeInvalidValue, "unsupported value %q, expected one of: %s", value, strings.Join(allowed, ", "))
+	}
+}
+
+// checkPair reports one of two options that must be set together being set alone.
+func checkPair(ds *Diagnostics, severity Severity, key1, value1, key2, value2 string) {
+	if (value1 == "") == (value2 == "") {
+		return
+	}
+	key, other := key2, key1
+	if value1 == "" {
+		key, other = key1, key2
+	}
+	*ds = append(*ds, Diagnostic{
+		Path: key, Severity: severity, Code: CodeIncomplete,
+		Message: fmt.Sprintf("must be set together with %s", other),
+	})
+}
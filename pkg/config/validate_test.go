@@ -0,0 +1,228 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateClientConf(t *testing.T) {
+	type issue struct {
+		path     string
+		severity Severity
+		code     string
+	}
+	tests := []struct {
+		name     string
+		input    string
+		expected []issue
+	}{
+		{
+			name: "valid",
+			input: `
+				serverAddr = "example.com"
+				auth.token = "123456"
+				[[proxies]]
+				name = "ssh"
+				type = "tcp"
+				localPort = 22
+				remotePort = 6000
+			`,
+		},
+		{
+			name: "common",
+			input: `
+				serverPort = 70000
+				log.level = "verbose"
+				transport.tls.certFile = "client.crt"
+				transport.quic.maxIdleTimeout = 30
+				webServer.user = "admin"
+			`,
+			expected: []issue{
+				{"server_addr", SeverityError, CodeRequired},
+				{"server_port", SeverityError, CodeInvalidPort},
+				{"log_level", SeverityError, CodeInvalidValue},
+				{"tls_key_file", SeverityError, CodeIncomplete},
+				{"admin_user", SeverityWarning, CodeIgnored},
+				{"quic_max_idle_timeout", SeverityWarning, CodeIgnored},
+				{"proxies", SeverityError, CodeNoProxies},
+			},
+		},
+		{
+			name: "oidc",
+			input: `
+				serverAddr = "example.com"
+				auth.method = "oidc"
+				auth.token = "123456"
+				[[proxies]]
+				name = "ssh"
+				type = "tcp"
+				localPort = 22
+			`,
+			expected: []issue{
+				{"oidc_client_id", SeverityError, CodeRequired},
+				{"oidc_token_endpoint_url", SeverityError, CodeRequired},
+				{"token", SeverityWarning, CodeIgnored},
+			},
+		},
+		{
+			name: "proxies",
+			input: `
+				serverAddr = "example.com"
+				start = ["ssh", "ftp"]
+				[[proxies]]
+				name = "ssh"
+				type = "tcp"
+				localPort = 22
+				remotePort = 70000
+				customDomains = ["example.com"]
+				[[proxies]]
+				name = "ssh"
+				type = "http"
+				localPort = 80
+				[[proxies]]
+				name = "mux"
+				type = "tcpmux"
+				localPort = 8080
+				subdomain = "mux"
+				multiplexer = "other"
+				healthCheck.type = "http"
+				healthCheck.timeoutSeconds = -1
+				healthCheck.maxFailed = -1
+				healthCheck.intervalSeconds = -1
+				transport.bandwidthLimit = "1GB"
+				[[proxies]]
+				name = "secret"
+				type = "stcp"
+				localPort = 22
+				[[proxies]]
+				type = "ftp"
+			`,
+			expected: []issue{
+				{"proxies[ssh].remote_port", SeverityError, CodeInvalidPort},
+				{"proxies[ssh].custom_domains", SeverityWarning, CodeIgnored},
+				{"proxies[ssh].custom_domains", SeverityError, CodeRequired},
+				{"proxies[ssh].name", SeverityError, CodeDuplicateName},
+				{"proxies[mux].multiplexer", SeverityError, CodeInvalidValue},
+				{"proxies[mux].bandwidth_limit", SeverityError, CodeInvalidValue},
+				{"proxies[mux].health_check_url", SeverityError, CodeRequired},
+				{"proxies[mux].health_check_timeout_s", SeverityError, CodeInvalidValue},
+				{"proxies[mux].health_check_max_failed", SeverityError, CodeInvalidValue},
+				{"proxies[mux].health_check_interval_s", SeverityError, CodeInvalidValue},
+				{"proxies[secret].sk", SeverityWarning, CodeInsecure},
+				{"proxies[4].name", SeverityError, CodeRequired},
+				{"proxies[4].type", SeverityError, CodeInvalidValue},
+				{"start", SeverityWarning, CodeUnknownRef},
+			},
+		},
+		{
+			name: "plugins",
+			input: `
+				serverAddr = "example.com"
+				[[proxies]]
+				name = "web"
+				type = "https"
+				customDomains = ["example.com"]
+				localPort = 443
+				plugin.type = "https2http"
+				plugin.crtPath = "server.crt"
+				[[proxies]]
+				name = "socks"
+				type = "tcp"
+				plugin.type = "socks5"
+				plugin.username = "user"
+				[[proxies]]
+				name = "unknown"
+				type = "tcp"
+				plugin.type = "ftp"
+			`,
+			expected: []issue{
+				{"proxies[web].plugin_local_addr", SeverityError, CodeRequired},
+				{"proxies[web].plugin_key_path", SeverityError, CodeIncomplete},
+				{"proxies[web].local_port", SeverityWarning, CodeIgnored},
+				{"proxies[socks].plugin_passwd", SeverityWarning, CodeIncomplete},
+				{"proxies[unknown].plugin", SeverityError, CodeInvalidValue},
+			},
+		},
+		{
+			name: "visitors",
+			input: `
+				serverAddr = "example.com"
+				[[visitors]]
+				name = "v1"
+				type = "xtcp"
+				secretKey = "abc"
+				protocol = "tcp"
+				fallbackTo = "v2"
+				[[visitors]]
+				name = "v2"
+				type = "stcp"
+				serverName = "secret"
+				bindPort = 70000
+				maxRetriesAnHour = 8
+			`,
+			expected: []issue{
+				{"visitors[v1].server_name", SeverityError, CodeRequired},
+				{"visitors[v1].bind_port", SeverityError, CodeRequired},
+				{"visitors[v1].protocol", SeverityError, CodeInvalidValue},
+				{"visitors[v2].bind_port", SeverityError, CodeInvalidPort},
+				{"visitors[v2].sk", SeverityWarning, CodeInsecure},
+				{"visitors[v2].max_retries_an_hour", SeverityWarning, CodeIgnored},
+			},
+		},
+		{
+			name: "ini ranges",
+			input: `
+				[common]
+				server_addr = example.com
+				[range:ports]
+				type = tcp
+				local_port = 6000-6002
+				remote_port = 7000-7001
+				[range:bad]
+				type = udp
+				local_port = 6010-6000
+				[range:overlap]
+				type = tcp
+				local_port = 6020-6025,6023
+				remote_port = 7020-7024,70000
+			`,
+			expected: []issue{
+				{"proxies[ports].remote_port", SeverityError, CodePortMismatch},
+				{"proxies[bad].local_port", SeverityError, CodeInvalidPort},
+				{"proxies[bad].remote_port", SeverityError, CodeRequired},
+				{"proxies[overlap].local_port", SeverityError, CodePortOverlap},
+				{"proxies[overlap].remote_port", SeverityError, CodeInvalidPort},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ds, err := ValidateClientConf([]byte(test.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var actual []issue
+			for _, d := range ds {
+				actual = append(actual, issue{d.Path, d.Severity, d.Code})
+			}
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("Expected: %v, got: %v", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDiagnosticsErr(t *testing.T) {
+	ds := Diagnostics{
+		{Path: "server_addr", Severity: SeverityError, Code: CodeRequired, Message: "server address is required"},
+		{Path: "proxies[ssh].sk", Severity: SeverityWarning, Code: CodeInsecure, Message: "secret key is empty"},
+		{Path: "proxies[ssh].local_port", Severity: SeverityError, Code: CodeRequired, Message: "local port or plugin is required"},
+	}
+	expected := "server_addr: server address is required\nproxies[ssh].local_port: local port or plugin is required"
+	if err := ds.Err(); err == nil || err.Error() != expected {
+		t.Errorf("Expected: %v, got: %v", expected, err)
+	}
+	if err := ds.Warnings().Err(); err != nil {
+		t.Errorf("Expected: %v, got: %v", nil, err)
+	}
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/hzcrv1911/frpcgui/pkg/consts"
+)
+
+// yamlNode converts the value of the tree to a YAML node, keeping the order of keys.
+func yamlNode(value any) *yaml.Node {
+	switch v := value.(type) {
+	case *table:
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, key := range v.keys {
+			node.Content = append(node.Content, yamlNode(key), yamlNode(v.values[key]))
+		}
+		return node
+	case []any:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, item := range v {
+			node.Content = append(node.Content, yamlNode(item))
+		}
+		return node
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v}
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(v)}
+	case int64:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.FormatInt(v, 10)}
+	}
+	panic(fmt.Sprintf("unsupported value type %T", value))
+}
+
+// marshalYAML renders the tree as a YAML document.
+func (t *table) marshalYAML() ([]byte, error) {
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(yamlNode(t)); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// marshalYAML encodes the config with the frp v0.52+ schema in YAML.
+func (conf *ClientConfig) marshalYAML() ([]byte, error) {
+	doc, err := encodeClientConfig(conf)
+	if err != nil {
+		return nil, err
+	}
+	return doc.marshalYAML()
+}
+
+// parseClientConfFromYAML decodes a YAML config written with the frp v0.52+ schema.
+func parseClientConfFromYAML(b []byte) (*ClientConfig, error) {
+	var doc map[string]any
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	conf, err := decodeClientConfig(doc)
+	if err != nil {
+		return nil, err
+	}
+	conf.Format = consts.FormatYAML
+	return conf, nil
+}
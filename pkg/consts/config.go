@@ -6,6 +6,16 @@ const (
 	DefaultServerPort = 7000
 )
 
+// Config file formats
+const (
+	FormatTOML = "toml"
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+	FormatINI  = "ini"
+)
+
+var Formats = []string{FormatTOML, FormatYAML, FormatJSON, FormatINI}
+
 // Protocols
 const (
 	ProtoTCP       = "tcp"
@@ -21,3 +21,35 @@ const (
 	ProxyStateError
 	ProxyStateStopped
 )
+
+// String returns the name of the state used in the output of commands and the API.
+func (s ConfigState) String() string {
+	switch s {
+	case ConfigStateStarted:
+		return "started"
+	case ConfigStateStopped:
+		return "stopped"
+	case ConfigStateStarting:
+		return "starting"
+	case ConfigStateStopping:
+		return "stopping"
+	case ConfigStateNotInstalled:
+		return "not_installed"
+	default:
+		return "unknown"
+	}
+}
+
+// String returns the name of the state used in the output of the API.
+func (s ProxyState) String() string {
+	switch s {
+	case ProxyStateRunning:
+		return "running"
+	case ProxyStateError:
+		return "error"
+	case ProxyStateStopped:
+		return "stopped"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,263 @@
+// Package diag exports the diagnostics of configs into a zip file, which is handed to
+// support when a tunnel breaks.
+//
+// The bundle holds a manifest.json describing what was collected, and the following
+// files for every config:
+//
+//	configs/<name><ext>      the config, with included proxies and without secrets
+//	logs/<name>/...          the recent frpc logs
+//	services/<name>/...      the files of the service, like winsw.xml and its logs
+//
+// The failures of collecting a file are recorded in the manifest instead of failing the
+// export, as a partial bundle is still useful.
+package diag
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/hzcrv1911/frpcgui/pkg/config"
+	"github.com/hzcrv1911/frpcgui/pkg/util"
+	"github.com/hzcrv1911/frpcgui/pkg/version"
+)
+
+const (
+	// maxLogFiles is the number of log files collected per config, including the
+	// current one.
+	maxLogFiles = 3
+	// maxFileSize limits the content collected from a log file, which is cut from the end.
+	maxFileSize = 4 << 20
+)
+
+// ManifestName is the name of the manifest in the bundle.
+const ManifestName = "manifest.json"
+
+// Source is a config to collect.
+type Source struct {
+	// Path is the config file.
+	Path string
+	// Data is the parsed config, which provides the name, log file and addresses.
+	Data *config.ClientConfig
+}
+
+// Service is the state of the service of a config.
+type Service struct {
+	Name string `json:"name"`
+	// Status is the status reported by the service manager, like "Running".
+	Status string `json:"status"`
+	// Files are the files of the service to collect.
+	Files []string `json:"-"`
+}
+
+// System provides the information depending on how frpc is installed.
+type System interface {
+	// FrpcVersion returns the version of frpc.
+	FrpcVersion() (string, error)
+	// Service returns the service of the config.
+	Service(configPath string) (Service, error)
+}
+
+// Manifest describes the content of a bundle.
+type Manifest struct {
+	Created     time.Time    `json:"created"`
+	Version     string       `json:"version"`
+	FrpcVersion string       `json:"frpcVersion,omitempty"`
+	OS          string       `json:"os"`
+	Arch        string       `json:"arch"`
+	Configs     []ConfigInfo `json:"configs"`
+	Errors      []string     `json:"errors,omitempty"`
+}
+
+// ConfigInfo describes what was collected for a config.
+type ConfigInfo struct {
+	Name    string   `json:"name"`
+	Path    string   `json:"path"`
+	Service *Service `json:"service,omitempty"`
+	// Files are the names of the files of the config in the bundle.
+	Files  []string `json:"files"`
+	Checks []Check  `json:"checks"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+// Export writes the diagnostics of the configs to a zip file, and returns its manifest.
+// A nil system skips the frpc version and the services. An error is returned only if
+// the zip file can't be written.
+func Export(ctx context.Context, filename string, sources []Source, sys System) (*Manifest, error) {
+	m := &Manifest{
+		Created: time.Now(),
+		Version: version.Number,
+		OS:      runtime.GOOS,
+		Arch:    runtime.GOARCH,
+		Configs: make([]ConfigInfo, len(sources)),
+	}
+	if sys != nil {
+		if v, err := sys.FrpcVersion(); err != nil {
+			m.Errors = append(m.Errors, "frpc version: "+err.Error())
+		} else {
+			m.FrpcVersion = v
+		}
+	}
+	// The pre-flights wait for timeouts, so they are run at once
+	var wg sync.WaitGroup
+	for i, src := range sources {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.Configs[i].Checks = Preflight(ctx, src.Data)
+		}()
+	}
+	wg.Wait()
+
+	var entries []util.ZipEntry
+	dirs := make(map[string]bool)
+	for i, src := range sources {
+		info := &m.Configs[i]
+		info.Name, info.Path = src.Data.Name(), src.Path
+		dir := util.UniqueName(info.Name, dirs)
+		add := func(name string, data []byte) {
+			entries = append(entries, util.ZipEntry{Name: name, Data: data})
+			info.Files = append(info.Files, name)
+		}
+		fail := func(what string, err error) {
+			info.Errors = append(info.Errors, what+": "+err.Error())
+		}
+
+		b, err := redactedConfig(src.Path)
+		if err != nil {
+			fail("config", err)
+		}
+		if b != nil {
+			add("configs/"+dir+src.Data.Ext(), b)
+		}
+		logs, err := recentLogs(src.Data.LogFile)
+		if err != nil && !errors.Is(err, os.ErrInvalid) {
+			fail("logs", err)
+		}
+		for _, log := range logs {
+			if b, err := readTail(log, maxFileSize); err != nil {
+				fail("logs", err)
+			} else {
+				add("logs/"+dir+"/"+filepath.Base(log), b)
+			}
+		}
+		if sys == nil {
+			continue
+		}
+		service, err := sys.Service(src.Path)
+		if err != nil {
+			fail("service", err)
+			continue
+		}
+		info.Service = &service
+		for _, file := range service.Files {
+			if b, err := readTail(file, maxFileSize); err != nil {
+				if !errors.Is(err, os.ErrNotExist) {
+					fail("service", err)
+				}
+			} else {
+				add("services/"+dir+"/"+filepath.Base(file), b)
+			}
+		}
+	}
+
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	entries = slices.Insert(entries, 0, util.ZipEntry{Name: ManifestName, Data: manifest})
+	if err = util.WriteZip(filename, entries); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// redactedConfig returns the config content handed to frpc, without secrets. The
+// content of the file itself is used if the included files can't be rendered, and
+// the failure is returned along with it.
+func redactedConfig(path string) ([]byte, error) {
+	b, renderErr := config.RenderRedactedClientConf(path)
+	if renderErr == nil {
+		return b, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if b, err = config.RedactContent(b); err != nil {
+		return nil, err
+	}
+	return b, renderErr
+}
+
+// recentLogs returns the current log file and the most recently rotated ones. The
+// current file is left out if it doesn't exist.
+func recentLogs(path string) ([]string, error) {
+	files, dates, err := util.FindLogFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	var logs []string
+	if util.FileExists(files[0]) {
+		logs = append(logs, files[0])
+	}
+	rotated := make([]int, 0, len(files)-1)
+	for i := 1; i < len(files); i++ {
+		rotated = append(rotated, i)
+	}
+	slices.SortFunc(rotated, func(a, b int) int { return dates[b].Compare(dates[a]) })
+	for _, i := range rotated {
+		if len(logs) == maxLogFiles {
+			break
+		}
+		logs = append(logs, files[i])
+	}
+	return logs, nil
+}
+
+// readTail reads the last n bytes of a file, from the first complete line.
+func readTail(path string, n int64) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() <= n {
+		return io.ReadAll(f)
+	}
+	if _, err = f.Seek(info.Size()-n, io.SeekStart); err != nil {
+		return nil, err
+	}
+	b, err := io.ReadAll(io.LimitReader(f, n))
+	if err != nil {
+		return nil, err
+	}
+	if i := slices.Index(b, '\n'); i >= 0 {
+		b = b[i+1:]
+	}
+	return b, nil
+}
+
+// FailedChecks returns the number of failed checks of all configs.
+func (m *Manifest) FailedChecks() int {
+	n := 0
+	for _, c := range m.Configs {
+		for _, check := range c.Checks {
+			if check.Status == CheckFailed {
+				n++
+			}
+		}
+	}
+	return n
+}
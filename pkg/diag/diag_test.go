@@ -0,0 +1,170 @@
+package diag
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/hzcrv1911/frpcgui/pkg/config"
+	"github.com/hzcrv1911/frpcgui/pkg/sec"
+	"github.com/hzcrv1911/frpcgui/pkg/version"
+)
+
+type fakeSystem struct {
+	dir string
+}
+
+func (s fakeSystem) FrpcVersion() (string, error) {
+	return "0.61.0", nil
+}
+
+func (s fakeSystem) Service(configPath string) (Service, error) {
+	if strings.HasSuffix(configPath, "broken.toml") {
+		return Service{}, errors.New("no service")
+	}
+	return Service{
+		Name:   "frpcgui_" + filepath.Base(configPath),
+		Status: "Running",
+		Files:  []string{filepath.Join(s.dir, "winsw.xml"), filepath.Join(s.dir, "missing.log")},
+	}, nil
+}
+
+func readZip(t *testing.T, filename string) map[string]string {
+	r, err := zip.OpenReader(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	files := make(map[string]string)
+	for _, f := range r.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = string(b)
+	}
+	return files
+}
+
+func TestExport(t *testing.T) {
+	dir := t.TempDir()
+	server, closed := listen(t)
+	host, port, _ := net.SplitHostPort(server)
+	logFile := filepath.ToSlash(filepath.Join(dir, "home.log"))
+	files := map[string]string{
+		"home.toml": `serverAddr = "` + host + `"
+serverPort = ` + port + `
+auth.token = "server-token"
+log.to = "` + logFile + `"
+includes = ["./proxies.toml"]
+`,
+		"proxies.toml": `[[visitors]]
+name = "secret"
+type = "stcp"
+serverName = "ssh"
+bindPort = 6000
+secretKey = "secret-key"
+`,
+		"broken.toml": `serverAddr = "127.0.0.1"
+serverPort = ` + closed[strings.LastIndexByte(closed, ':')+1:] + `
+auth.token = "other-token"
+`,
+		"home.log":                 "current\n",
+		"home.20250101-000000.log": "oldest\n",
+		"home.20250102-000000.log": "older\n",
+		"home.20250103-000000.log": "newer\n",
+		"winsw.xml":                "<service/>",
+	}
+	// The secrets of a config are sealed, and the bundle is made without the vault
+	key, _ := sec.GenerateKey()
+	v, err := sec.NewVault(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files["home.toml"] = string(config.SealContent([]byte(files["home.toml"]), v))
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var sources []Source
+	for _, name := range []string{"home.toml", "broken.toml"} {
+		path := filepath.Join(dir, name)
+		data, err := config.UnmarshalClientConf(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Both configs have the same name
+		data.ClientCommon.Name = "home"
+		sources = append(sources, Source{Path: path, Data: data})
+	}
+
+	filename := filepath.Join(dir, "diagnostics.zip")
+	m, err := Export(context.Background(), filename, sources, fakeSystem{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle := readZip(t, filename)
+	var names []string
+	for name := range bundle {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	expected := []string{
+		"configs/home-2.toml",
+		"configs/home.toml",
+		"logs/home/home.20250102-000000.log",
+		"logs/home/home.20250103-000000.log",
+		"logs/home/home.log",
+		"manifest.json",
+		"services/home/winsw.xml",
+	}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected: %v, got: %v", expected, names)
+	}
+	for name, content := range bundle {
+		for _, secret := range []string{"server-token", "secret-key", "other-token", "enc:"} {
+			if strings.Contains(content, secret) {
+				t.Errorf("Expected: %v, got: %v", "no "+secret+" in "+name, content)
+			}
+		}
+	}
+	if !strings.Contains(bundle["configs/home.toml"], `name = "secret"`) {
+		t.Errorf("Expected: %v, got: %v", "the included proxies", bundle["configs/home.toml"])
+	}
+
+	var manifest Manifest
+	if err = json.Unmarshal([]byte(bundle[ManifestName]), &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Version != version.Number || manifest.FrpcVersion != "0.61.0" || len(manifest.Configs) != 2 {
+		t.Errorf("Expected: %v, got: %v", "the versions and 2 configs", manifest)
+	}
+	home, broken := manifest.Configs[0], manifest.Configs[1]
+	if home.Service == nil || home.Service.Status != "Running" || len(home.Errors) != 0 {
+		t.Errorf("Expected: %v, got: %v", "a running service without errors", home)
+	}
+	if len(home.Files) != 5 || len(home.Checks) == 0 || home.Checks[0].Status != CheckOK {
+		t.Errorf("Expected: %v, got: %v", "5 files and a reachable server", home)
+	}
+	if broken.Service != nil || !reflect.DeepEqual(broken.Errors, []string{"service: no service"}) {
+		t.Errorf("Expected: %v, got: %v", "a service error", broken)
+	}
+	if m.FailedChecks() != 1 {
+		t.Errorf("Expected: %v, got: %v", 1, m.FailedChecks())
+	}
+}
@@ -0,0 +1,153 @@
+package diag
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hzcrv1911/frpcgui/pkg/config"
+	"github.com/hzcrv1911/frpcgui/pkg/consts"
+)
+
+// checkTimeout limits every check of the pre-flight.
+const checkTimeout = 3 * time.Second
+
+// CheckStatus is the outcome of a check.
+type CheckStatus string
+
+const (
+	CheckOK      CheckStatus = "ok"
+	CheckFailed  CheckStatus = "failed"
+	CheckSkipped CheckStatus = "skipped"
+)
+
+// Kinds of checks
+const (
+	// CheckDNS resolves the server address.
+	CheckDNS = "dns"
+	// CheckServer connects to the server, or to the HTTP proxy in front of it.
+	CheckServer = "server"
+	// CheckLocal connects to the local service of a proxy.
+	CheckLocal = "local"
+)
+
+// Check is the result of a connectivity check.
+type Check struct {
+	Kind string `json:"kind"`
+	// Proxy is the name of the proxy of a local service check.
+	Proxy  string      `json:"proxy,omitempty"`
+	Target string      `json:"target"`
+	Status CheckStatus `json:"status"`
+	// Error is the reason of a failed or skipped check.
+	Error string `json:"error,omitempty"`
+	// Duration is the time taken by the check in milliseconds.
+	Duration int64 `json:"durationMs"`
+}
+
+// Preflight checks that frpc can reach the server of the config and the local services
+// of its proxies. A server connected with a UDP based protocol, and the local services
+// of UDP and plugin proxies, are skipped, as they can't be checked by a connection.
+// The checks run concurrently, and the results are in a stable order.
+func Preflight(ctx context.Context, data *config.ClientConfig) []Check {
+	var checks []Check
+	var runs []func(context.Context) error
+
+	add := func(check Check, run func(context.Context) error) {
+		checks = append(checks, check)
+		runs = append(runs, run)
+	}
+	dial := func(target string) func(context.Context) error {
+		return func(ctx context.Context) error {
+			var d net.Dialer
+			conn, err := d.DialContext(ctx, "tcp", target)
+			if err != nil {
+				return err
+			}
+			return conn.Close()
+		}
+	}
+
+	port := data.ServerPort
+	if port == 0 {
+		port = consts.DefaultServerPort
+	}
+	server := net.JoinHostPort(data.ServerAddress, strconv.Itoa(port))
+	if net.ParseIP(data.ServerAddress) == nil {
+		add(Check{Kind: CheckDNS, Target: data.ServerAddress}, func(ctx context.Context) error {
+			_, err := net.DefaultResolver.LookupHost(ctx, data.ServerAddress)
+			return err
+		})
+	}
+	switch data.Protocol {
+	case consts.ProtoKCP, consts.ProtoQUIC:
+		add(Check{Kind: CheckServer, Target: server, Status: CheckSkipped, Error: "udp based protocol " + data.Protocol}, nil)
+	default:
+		if data.HTTPProxy != "" {
+			// The server is reached through the proxy, so only the proxy is checked
+			if u, err := url.Parse(data.HTTPProxy); err == nil && u.Host != "" {
+				add(Check{Kind: CheckServer, Target: u.Host}, dial(u.Host))
+			} else {
+				add(Check{Kind: CheckServer, Target: data.HTTPProxy, Status: CheckFailed, Error: "invalid proxy url"}, nil)
+			}
+		} else {
+			add(Check{Kind: CheckServer, Target: server}, dial(server))
+		}
+	}
+
+	for _, proxy := range data.Proxies {
+		if proxy.Disabled || proxy.IsVisitor() {
+			continue
+		}
+		check := Check{Kind: CheckLocal, Proxy: proxy.Name}
+		switch {
+		case proxy.Plugin != "":
+			check.Status, check.Error = CheckSkipped, "plugin "+proxy.Plugin
+			add(check, nil)
+			continue
+		case proxy.Type == consts.ProxyTypeUDP || proxy.Type == consts.ProxyTypeSUDP:
+			check.Status, check.Error = CheckSkipped, "udp proxy"
+			add(check, nil)
+			continue
+		}
+		ip := proxy.LocalIP
+		if ip == "" {
+			ip = "127.0.0.1"
+		}
+		ports, err := proxy.LocalPorts()
+		if err != nil || len(ports) == 0 {
+			check.Target = ip + ":" + proxy.LocalPort
+			check.Status, check.Error = CheckFailed, "invalid local port"
+			add(check, nil)
+			continue
+		}
+		// A range proxy is checked on its first port only
+		check.Target = net.JoinHostPort(ip, strconv.Itoa(ports[0].Start))
+		add(check, dial(check.Target))
+	}
+
+	var wg sync.WaitGroup
+	for i, run := range runs {
+		if run == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(check *Check) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+			start := time.Now()
+			err := run(ctx)
+			check.Duration = time.Since(start).Milliseconds()
+			if err != nil {
+				check.Status, check.Error = CheckFailed, err.Error()
+			} else {
+				check.Status = CheckOK
+			}
+		}(&checks[i])
+	}
+	wg.Wait()
+	return checks
+}
@@ -0,0 +1,104 @@
+package diag
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/hzcrv1911/frpcgui/pkg/config"
+)
+
+// listen returns the address of a listening port and of a closed port.
+func listen(t *testing.T) (string, string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed.Close()
+	return l.Addr().String(), closed.Addr().String()
+}
+
+func TestPreflight(t *testing.T) {
+	open, closed := listen(t)
+	_, openPort, _ := net.SplitHostPort(open)
+	_, closedPort, _ := net.SplitHostPort(closed)
+	port := func(addr string) int {
+		_, p, _ := net.SplitHostPort(addr)
+		spec, _ := config.ParsePortSpec(p)
+		return spec[0].Start
+	}
+	proxy := func(name, typ, localPort string) *config.Proxy {
+		return &config.Proxy{BaseProxyConf: config.BaseProxyConf{Name: name, Type: typ, LocalPort: localPort}}
+	}
+	disabled := proxy("disabled", "tcp", closedPort)
+	disabled.Disabled = true
+	visitor := proxy("visitor", "stcp", closedPort)
+	visitor.Role = "visitor"
+	plugin := proxy("plugin", "tcp", "")
+	plugin.Plugin = "http_proxy"
+
+	tests := []struct {
+		common   config.ClientCommon
+		proxies  []*config.Proxy
+		expected []Check
+	}{
+		{
+			common: config.ClientCommon{ServerAddress: "127.0.0.1", ServerPort: port(open)},
+			proxies: []*config.Proxy{
+				proxy("ssh", "tcp", openPort),
+				proxy("web", "http", closedPort),
+				proxy("range", "tcp", openPort+","+closedPort),
+				proxy("dns", "udp", "53"),
+				proxy("bad", "tcp", "x"),
+				disabled, visitor, plugin,
+			},
+			expected: []Check{
+				{Kind: CheckServer, Target: open, Status: CheckOK},
+				{Kind: CheckLocal, Proxy: "ssh", Target: open, Status: CheckOK},
+				{Kind: CheckLocal, Proxy: "web", Target: closed, Status: CheckFailed},
+				{Kind: CheckLocal, Proxy: "range", Target: open, Status: CheckOK},
+				{Kind: CheckLocal, Proxy: "dns", Status: CheckSkipped},
+				{Kind: CheckLocal, Proxy: "bad", Target: "127.0.0.1:x", Status: CheckFailed},
+				{Kind: CheckLocal, Proxy: "plugin", Status: CheckSkipped},
+			},
+		},
+		{
+			common: config.ClientCommon{ServerAddress: "localhost", ServerPort: port(closed)},
+			expected: []Check{
+				{Kind: CheckDNS, Target: "localhost", Status: CheckOK},
+				{Kind: CheckServer, Target: "localhost:" + closedPort, Status: CheckFailed},
+			},
+		},
+		{
+			common: config.ClientCommon{ServerAddress: "127.0.0.1", ServerPort: port(closed), HTTPProxy: "http://user:pwd@" + open},
+			expected: []Check{
+				{Kind: CheckServer, Target: open, Status: CheckOK},
+			},
+		},
+		{
+			common: config.ClientCommon{ServerAddress: "127.0.0.1", Protocol: "quic"},
+			expected: []Check{
+				{Kind: CheckServer, Target: "127.0.0.1:7000", Status: CheckSkipped},
+			},
+		},
+	}
+	for i, test := range tests {
+		data := &config.ClientConfig{ClientCommon: test.common, Proxies: test.proxies}
+		checks := Preflight(context.Background(), data)
+		for j := range checks {
+			if (checks[j].Error == "") != (checks[j].Status == CheckOK) {
+				t.Errorf("Test %d: expected: %v, got: %v", i, "a reason for a check not ok", checks[j])
+			}
+			checks[j].Error, checks[j].Duration = "", 0
+		}
+		if !reflect.DeepEqual(checks, test.expected) {
+			t.Errorf("Test %d: expected: %v, got: %v", i, test.expected, checks)
+		}
+	}
+}
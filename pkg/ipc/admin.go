@@ -0,0 +1,217 @@
+package ipc
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// adminTimeout limits the time of a request to the admin API.
+	adminTimeout = 3 * time.Second
+	// maxAdminBackoff is the longest delay between the retries of a failing admin API.
+	maxAdminBackoff = 30 * time.Second
+)
+
+// adminProxyStatus is the status of a proxy in the response of the admin API.
+type adminProxyStatus struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Status     string `json:"status"`
+	Err        string `json:"err"`
+	RemoteAddr string `json:"remote_addr"`
+}
+
+// AdminClient queries the proxy status from the admin API of frpc, which is served if the
+// admin port of the config is set.
+type AdminClient struct {
+	url      string
+	user     string
+	password string
+	client   *http.Client
+	ch       chan struct{}
+	cb       func([]ProxyMessage)
+}
+
+// NewAdminClient returns a client of the admin API listening on the address and port.
+// An unspecified address is reached through the loopback address.
+func NewAdminClient(addr string, port int, user, password string) *AdminClient {
+	return &AdminClient{
+		url:      "http://" + AdminHost(addr, port),
+		user:     user,
+		password: password,
+		client:   &http.Client{Timeout: adminTimeout},
+		ch:       make(chan struct{}, 1),
+	}
+}
+
+// AdminHost returns the host and port to reach the admin API listening on the address and port.
+func AdminHost(addr string, port int) string {
+	switch addr {
+	case "", "0.0.0.0":
+		addr = "127.0.0.1"
+	case "::":
+		addr = "::1"
+	}
+	return net.JoinHostPort(addr, strconv.Itoa(port))
+}
+
+// SetCallback changes the callback function for response message.
+func (c *AdminClient) SetCallback(cb func([]ProxyMessage)) {
+	c.cb = cb
+}
+
+// Run queries the status until the context is done. The queries are frequent after a
+// probe, and slow down over time. A failing admin API is retried with an increasing delay.
+func (c *AdminClient) Run(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	seq := []time.Duration{100 * time.Millisecond, 500 * time.Millisecond, time.Second, 2 * time.Second, 5 * time.Second}
+	index := -1
+	backoff := time.Duration(0)
+
+	for {
+		select {
+		case <-timer.C:
+			msg, err := c.Status(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				backoff = min(max(2*backoff, time.Second), maxAdminBackoff)
+				timer.Reset(backoff)
+				continue
+			}
+			backoff = 0
+			if c.cb != nil {
+				c.cb(msg)
+			}
+			if index < len(seq)-1 {
+				index++
+			}
+			timer.Reset(seq[index])
+		case <-c.ch:
+			index = 0
+			backoff = 0
+			timer.Reset(seq[index])
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Probe triggers a query request immediately.
+func (c *AdminClient) Probe(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+		return
+	case c.ch <- struct{}{}:
+	default:
+		return
+	}
+}
+
+// Status returns the status of all proxies from the admin API.
+func (c *AdminClient) Status(ctx context.Context) ([]ProxyMessage, error) {
+	resp, err := c.do(ctx, http.MethodGet, "/api/status", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var status map[string][]adminProxyStatus
+	if err = json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, err
+	}
+	var msg []ProxyMessage
+	for _, proxies := range status {
+		for _, p := range proxies {
+			msg = append(msg, ProxyMessage{
+				Name:       p.Name,
+				Type:       p.Type,
+				Status:     proxyStatus(p.Status),
+				Err:        p.Err,
+				RemoteAddr: p.RemoteAddr,
+			})
+		}
+	}
+	// The proxies are grouped by type in the response, which has no order
+	slices.SortFunc(msg, func(a, b ProxyMessage) int { return cmp.Compare(a.Name, b.Name) })
+	return msg, nil
+}
+
+// PutConfig replaces the config file of frpc with the content. The config takes effect
+// after a reload.
+func (c *AdminClient) PutConfig(ctx context.Context, content []byte) error {
+	resp, err := c.do(ctx, http.MethodPut, "/api/config", bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// Reload makes frpc reload its config file, which applies the changes of proxies and
+// visitors without reconnecting to the server.
+func (c *AdminClient) Reload(ctx context.Context) error {
+	resp, err := c.do(ctx, http.MethodGet, "/api/reload", nil)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// do sends a request to the admin API. A response with a status other than 200 is
+// returned as an error with the message from frpc.
+func (c *AdminClient) do(ctx context.Context, method, path string, body io.Reader) (*http.Response, error) {
+	req, err := c.newRequest(ctx, method, path, body)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		if msg := strings.TrimSpace(string(msg)); msg != "" {
+			return nil, fmt.Errorf("admin api: %s: %s", resp.Status, msg)
+		}
+		return nil, fmt.Errorf("admin api: %s", resp.Status)
+	}
+	return resp, nil
+}
+
+func (c *AdminClient) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.url+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if c.user != "" || c.password != "" {
+		req.SetBasicAuth(c.user, c.password)
+	}
+	return req, nil
+}
+
+// proxyStatus converts the phase of a proxy reported by frpc to the status of ProxyMessage.
+func proxyStatus(phase string) string {
+	switch phase {
+	case "running":
+		return "running"
+	case "start error", "check failed":
+		return "error"
+	case "closed":
+		return "stopped"
+	default:
+		return ""
+	}
+}
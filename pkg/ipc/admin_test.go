@@ -0,0 +1,164 @@
+package ipc
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newAdminServer returns a stand-in for the admin API of frpc, which fails the first
+// requests given by failures.
+func newAdminServer(t *testing.T, failures int32) (*httptest.Server, *AdminClient) {
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !ok || user != "admin" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/api/status" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if requests.Add(1) <= failures {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"tcp": [
+				{"name": "ssh", "type": "tcp", "status": "running", "err": "", "local_addr": "127.0.0.1:22", "remote_addr": "example.com:6000"},
+				{"name": "rdp", "type": "tcp", "status": "start error", "err": "port already used", "local_addr": "127.0.0.1:3389"}
+			],
+			"http": [
+				{"name": "web", "type": "http", "status": "wait start", "err": "", "local_addr": "127.0.0.1:80"}
+			]
+		}`))
+	}))
+	host, port, _ := net.SplitHostPort(ts.Listener.Addr().String())
+	p, _ := strconv.Atoi(port)
+	return ts, NewAdminClient(host, p, "admin", "secret")
+}
+
+var expectedMessages = []ProxyMessage{
+	{Name: "rdp", Type: "tcp", Status: "error", Err: "port already used"},
+	{Name: "ssh", Type: "tcp", Status: "running", RemoteAddr: "example.com:6000"},
+	{Name: "web", Type: "http", Status: ""},
+}
+
+func TestAdminClientStatus(t *testing.T) {
+	ts, client := newAdminServer(t, 0)
+	defer ts.Close()
+	msg, err := client.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(msg, expectedMessages) {
+		t.Errorf("Expected: %v, got: %v", expectedMessages, msg)
+	}
+	client.password = "wrong"
+	if _, err = client.Status(context.Background()); err == nil {
+		t.Errorf("Expected: %v, got: %v", "an error", err)
+	}
+}
+
+func TestAdminClientRun(t *testing.T) {
+	// The client keeps retrying a failing admin API
+	ts, client := newAdminServer(t, 1)
+	defer ts.Close()
+	ch := make(chan []ProxyMessage, 1)
+	client.SetCallback(func(msg []ProxyMessage) {
+		select {
+		case ch <- msg:
+		default:
+		}
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		client.Run(ctx)
+		close(done)
+	}()
+	select {
+	case msg := <-ch:
+		if !reflect.DeepEqual(msg, expectedMessages) {
+			t.Errorf("Expected: %v, got: %v", expectedMessages, msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no status received")
+	}
+	client.Probe(ctx)
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Error("no status received after probe")
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("the client didn't stop")
+	}
+}
+
+func TestAdminClientReload(t *testing.T) {
+	var content []byte
+	var reloaded bool
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPut && r.URL.Path == "/api/config":
+			content, _ = io.ReadAll(r.Body)
+		case r.Method == http.MethodGet && r.URL.Path == "/api/reload":
+			if strings.Contains(string(content), "invalid") {
+				http.Error(w, "reload frpc proxy config error: invalid proxy", http.StatusBadRequest)
+				return
+			}
+			reloaded = true
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+	host, port, _ := net.SplitHostPort(ts.Listener.Addr().String())
+	p, _ := strconv.Atoi(port)
+	client := NewAdminClient(host, p, "", "")
+
+	if err := client.PutConfig(context.Background(), []byte("serverPort = 7000")); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Reload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "serverPort = 7000" || !reloaded {
+		t.Errorf("Expected: %v, got: %v %v", "serverPort = 7000 true", string(content), reloaded)
+	}
+	// The message of frpc is kept in the error
+	client.PutConfig(context.Background(), []byte("invalid"))
+	if err := client.Reload(context.Background()); err == nil || !strings.Contains(err.Error(), "invalid proxy") {
+		t.Errorf("Expected: %v, got: %v", "invalid proxy", err)
+	}
+}
+
+func TestAdminHost(t *testing.T) {
+	tests := []struct {
+		addr     string
+		expected string
+	}{
+		{addr: "", expected: "127.0.0.1:7400"},
+		{addr: "0.0.0.0", expected: "127.0.0.1:7400"},
+		{addr: "::", expected: "[::1]:7400"},
+		{addr: "192.168.1.2", expected: "192.168.1.2:7400"},
+	}
+	for _, test := range tests {
+		if output := AdminHost(test.addr, 7400); output != test.expected {
+			t.Errorf("Expected: %v, got: %v", test.expected, output)
+		}
+	}
+}
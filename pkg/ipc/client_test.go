@@ -0,0 +1,31 @@
+package ipc
+
+import (
+	"testing"
+
+	"github.com/hzcrv1911/frpcgui/pkg/logparse"
+)
+
+func TestProxyMessageFromLog(t *testing.T) {
+	tests := []struct {
+		input    string
+		ok       bool
+		expected ProxyMessage
+	}{
+		{input: "2025-01-15 10:23:45.480 [I] [client/control.go:172] [9f2c1d7e5b3a4e60] [ssh] start proxy success",
+			ok: true, expected: ProxyMessage{Name: "ssh", Status: "running"}},
+		{input: "2025-01-15 10:23:45.481 [W] [client/control.go:170] [9f2c1d7e5b3a4e60] [web] start error: port already used",
+			ok: true, expected: ProxyMessage{Name: "web", Status: "error", Err: "port already used"}},
+		{input: "2021/03/02 12:00:00 [I] [proxy_wrapper.go:190] [5e6f7a8b9c0d1e2f] [web] health check failed",
+			ok: true, expected: ProxyMessage{Name: "web", Status: "error", Err: "health check failed"}},
+		{input: "2025-01-15 10:24:00.000 [W] [client/service.go:294] connect to server error: dial tcp 203.0.113.5:7000: connect: connection refused"},
+		{input: "2025-01-15 10:26:00.000 [W] [client/control.go:130] [9f2c1d7e5b3a4e60] work connection closed, failed"},
+	}
+	for i, test := range tests {
+		r, _ := logparse.Parse(test.input)
+		output, ok := ProxyMessageFromLog(r)
+		if ok != test.ok || output != test.expected {
+			t.Errorf("Test %d: expected: %v %v, got: %v %v", i, test.expected, test.ok, output, ok)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+// Package logparse parses the log lines written by frpc.
+//
+// A log line of frpc looks like:
+//
+//	2025-01-15 10:23:45.480 [I] [client/control.go:172] [9f2c1d7e5b3a4e60] [ssh] start proxy success
+//
+// It has a timestamp, a level, the source file, and a message which may be prefixed by
+// the run ID of the session and the name of the proxy or visitor. Versions before v0.50
+// write the date with slashes and the source file without its directory.
+package logparse
+
+import (
+	"strings"
+	"time"
+)
+
+// Level is the severity of a log line.
+type Level int
+
+const (
+	LevelUnknown Level = iota
+	LevelTrace
+	LevelDebug
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levels = map[string]Level{
+	"T": LevelTrace,
+	"D": LevelDebug,
+	"I": LevelInfo,
+	"W": LevelWarn,
+	"E": LevelError,
+}
+
+// ParseLevel returns the level of the name returned by Level.String.
+func ParseLevel(name string) (Level, bool) {
+	for l := LevelTrace; l <= LevelError; l++ {
+		if l.String() == name {
+			return l, true
+		}
+	}
+	return LevelUnknown, false
+}
+
+func (l Level) String() string {
+	switch l {
+	case LevelTrace:
+		return "trace"
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is the kind of event reported by a log line. The events from EventProxyStart
+// concern a proxy or visitor.
+type Event int
+
+const (
+	// EventNone is a line reporting none of the events below.
+	EventNone Event = iota
+	// EventLogin is a successful login to the server, which starts a session.
+	EventLogin
+	// EventLoginFailed is a failed login or connection to the server.
+	EventLoginFailed
+	// EventReconnect is an attempt to connect to the server again.
+	EventReconnect
+	// EventProxyStart is a proxy registered by the server.
+	EventProxyStart
+	// EventProxyError is a proxy rejected by the server.
+	EventProxyError
+	// EventVisitorStart is a visitor listening on its local port.
+	EventVisitorStart
+	// EventVisitorError is a visitor failed to start.
+	EventVisitorError
+	// EventHealthCheckSuccess is a proxy whose local service is healthy again.
+	EventHealthCheckSuccess
+	// EventHealthCheckFailed is a proxy closed because its local service is unhealthy.
+	EventHealthCheckFailed
+)
+
+func (e Event) String() string {
+	switch e {
+	case EventLogin:
+		return "login"
+	case EventLoginFailed:
+		return "login_failed"
+	case EventReconnect:
+		return "reconnect"
+	case EventProxyStart:
+		return "proxy_start"
+	case EventProxyError:
+		return "proxy_error"
+	case EventVisitorStart:
+		return "visitor_start"
+	case EventVisitorError:
+		return "visitor_error"
+	case EventHealthCheckSuccess:
+		return "health_check_success"
+	case EventHealthCheckFailed:
+		return "health_check_failed"
+	default:
+		return "none"
+	}
+}
+
+// Record is a parsed log line.
+type Record struct {
+	Time  time.Time
+	Level Level
+	// Source is the source file and line of the log, like "client/control.go:172".
+	Source string
+	// RunID identifies the session with the server.
+	RunID string
+	// Proxy is the name of the proxy or visitor of the event.
+	Proxy string
+	// ProxyType is the type of the proxy, if the line mentions it.
+	ProxyType string
+	Event     Event
+	// Message is the text after the prefixes.
+	Message string
+	// Err is the reason of a failure event.
+	Err string
+}
+
+// timeLayouts are the formats of the timestamp of different versions. The fractional
+// seconds are accepted without being part of the layouts.
+var timeLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006/01/02 15:04:05",
+}
+
+// Parse parses a log line of frpc. It returns false if the line isn't a log line, like
+// a line of a stack trace. The timestamp is in local time, as written by frpc.
+func Parse(line string) (Record, bool) {
+	line = strings.TrimRight(line, "\r\n")
+	ts, rest, ok := strings.Cut(line, " [")
+	if !ok {
+		return Record{}, false
+	}
+	var r Record
+	if r.Time, ok = parseTime(ts); !ok {
+		return Record{}, false
+	}
+	level, rest, ok := cutPrefix("[" + rest)
+	if !ok {
+		return Record{}, false
+	}
+	if r.Level, ok = levels[level]; !ok {
+		return Record{}, false
+	}
+	if source, next, ok := cutPrefix(rest); ok && strings.Contains(source, ".go:") {
+		r.Source = source
+		rest = next
+	}
+	var prefixes []string
+	for {
+		prefix, next, ok := cutPrefix(rest)
+		if !ok {
+			break
+		}
+		prefixes = append(prefixes, prefix)
+		rest = next
+	}
+	r.Message = rest
+	r.classify()
+
+	// A proxy event is prefixed by the run ID and the proxy name, other events
+	// only by the run ID
+	switch {
+	case r.Event >= EventProxyStart && len(prefixes) > 0:
+		r.Proxy = prefixes[len(prefixes)-1]
+		if len(prefixes) > 1 {
+			r.RunID = prefixes[0]
+		}
+	case len(prefixes) > 0 && r.RunID == "":
+		r.RunID = prefixes[0]
+	}
+	if r.Proxy != "" {
+		r.ProxyType = bracketAfter(r.Message, "type [")
+	}
+	return r, true
+}
+
+// classify sets the event of the record from its message.
+func (r *Record) classify() {
+	msg := r.Message
+	visitor := strings.Contains(r.Source, "visitor")
+	switch {
+	case strings.HasPrefix(msg, "login to server success"):
+		r.Event = EventLogin
+		r.RunID = bracketAfter(msg, "get run id [")
+	case strings.HasPrefix(msg, "login to server failed"), strings.HasPrefix(msg, "connect to server error"):
+		r.Event = EventLoginFailed
+		r.Err = reason(msg)
+	case strings.HasPrefix(msg, "try to reconnect to server"), strings.HasPrefix(msg, "try to connect to server"):
+		r.Event = EventReconnect
+	case strings.HasPrefix(msg, "start visitor success"):
+		r.Event = EventVisitorStart
+	case strings.HasPrefix(msg, "start proxy success"):
+		r.Event = EventProxyStart
+		if visitor {
+			r.Event = EventVisitorStart
+		}
+	case strings.HasPrefix(msg, "start error"), strings.HasPrefix(msg, "start visitor error"):
+		r.Event = EventProxyError
+		if visitor {
+			r.Event = EventVisitorError
+		}
+		r.Err = reason(msg)
+	case strings.HasPrefix(msg, "health check success"):
+		r.Event = EventHealthCheckSuccess
+	case strings.HasPrefix(msg, "health check failed"):
+		r.Event = EventHealthCheckFailed
+		r.Err = reason(msg)
+	}
+}
+
+func parseTime(s string) (time.Time, bool) {
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// cutPrefix cuts a bracketed prefix without spaces from the start of s, and returns
+// the text inside the brackets and the remaining text.
+func cutPrefix(s string) (string, string, bool) {
+	if !strings.HasPrefix(s, "[") {
+		return "", s, false
+	}
+	end := strings.IndexByte(s, ']')
+	if end < 0 || strings.ContainsRune(s[1:end], ' ') {
+		return "", s, false
+	}
+	return s[1:end], strings.TrimLeft(s[end+1:], " "), true
+}
+
+// bracketAfter returns the text between the given opening and the next closing bracket.
+func bracketAfter(s, open string) string {
+	_, after, ok := strings.Cut(s, open)
+	if !ok {
+		return ""
+	}
+	value, _, ok := strings.Cut(after, "]")
+	if !ok {
+		return ""
+	}
+	return value
+}
+
+// reason returns the text after the first colon of a failure message.
+func reason(msg string) string {
+	_, after, ok := strings.Cut(msg, ": ")
+	if !ok {
+		return ""
+	}
+	return strings.TrimSpace(after)
+}
@@ -0,0 +1,195 @@
+package logparse
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input    string
+		ok       bool
+		expected Record
+	}{
+		// frp v0.61
+		{
+			input: "2025-01-15 10:23:45.123 [I] [sub/root.go:142] start frpc service for config file [frpc.toml]",
+			ok:    true,
+			expected: Record{
+				Level: LevelInfo, Source: "sub/root.go:142",
+				Message: "start frpc service for config file [frpc.toml]",
+			},
+		},
+		{
+			input: "2025-01-15 10:23:45.456 [I] [client/service.go:314] [9f2c1d7e5b3a4e60] login to server success, get run id [9f2c1d7e5b3a4e60]",
+			ok:    true,
+			expected: Record{
+				Level: LevelInfo, Source: "client/service.go:314", RunID: "9f2c1d7e5b3a4e60", Event: EventLogin,
+				Message: "login to server success, get run id [9f2c1d7e5b3a4e60]",
+			},
+		},
+		{
+			input: "2025-01-15 10:23:45.460 [I] [proxy/proxy_manager.go:177] [9f2c1d7e5b3a4e60] proxy added: [ssh web]",
+			ok:    true,
+			expected: Record{
+				Level: LevelInfo, Source: "proxy/proxy_manager.go:177", RunID: "9f2c1d7e5b3a4e60",
+				Message: "proxy added: [ssh web]",
+			},
+		},
+		{
+			input: "2025-01-15 10:23:45.480 [I] [client/control.go:172] [9f2c1d7e5b3a4e60] [ssh] start proxy success",
+			ok:    true,
+			expected: Record{
+				Level: LevelInfo, Source: "client/control.go:172", RunID: "9f2c1d7e5b3a4e60", Proxy: "ssh",
+				Event: EventProxyStart, Message: "start proxy success",
+			},
+		},
+		{
+			input: "2025-01-15 10:23:45.481 [W] [client/control.go:170] [9f2c1d7e5b3a4e60] [web] start error: port already used",
+			ok:    true,
+			expected: Record{
+				Level: LevelWarn, Source: "client/control.go:170", RunID: "9f2c1d7e5b3a4e60", Proxy: "web",
+				Event: EventProxyError, Message: "start error: port already used", Err: "port already used",
+			},
+		},
+		{
+			input: "2025-01-15 10:24:00.000 [W] [client/service.go:294] connect to server error: dial tcp 203.0.113.5:7000: connect: connection refused",
+			ok:    true,
+			expected: Record{
+				Level: LevelWarn, Source: "client/service.go:294", Event: EventLoginFailed,
+				Message: "connect to server error: dial tcp 203.0.113.5:7000: connect: connection refused",
+				Err:     "dial tcp 203.0.113.5:7000: connect: connection refused",
+			},
+		},
+		{
+			input: "2025-01-15 10:24:05.000 [I] [client/service.go:286] try to connect to server...",
+			ok:    true,
+			expected: Record{
+				Level: LevelInfo, Source: "client/service.go:286", Event: EventReconnect,
+				Message: "try to connect to server...",
+			},
+		},
+		{
+			input: "2025-01-15 10:25:00.000 [I] [visitor/visitor_manager.go:110] [9f2c1d7e5b3a4e60] [secret_ssh_visitor] start visitor success",
+			ok:    true,
+			expected: Record{
+				Level: LevelInfo, Source: "visitor/visitor_manager.go:110", RunID: "9f2c1d7e5b3a4e60",
+				Proxy: "secret_ssh_visitor", Event: EventVisitorStart, Message: "start visitor success",
+			},
+		},
+		{
+			input: "2025-01-15 10:25:01.000 [W] [visitor/visitor_manager.go:106] [9f2c1d7e5b3a4e60] [secret_ssh_visitor] start error: listen tcp 127.0.0.1:6000: bind: address already in use",
+			ok:    true,
+			expected: Record{
+				Level: LevelWarn, Source: "visitor/visitor_manager.go:106", RunID: "9f2c1d7e5b3a4e60",
+				Proxy: "secret_ssh_visitor", Event: EventVisitorError,
+				Message: "start error: listen tcp 127.0.0.1:6000: bind: address already in use",
+				Err:     "listen tcp 127.0.0.1:6000: bind: address already in use",
+			},
+		},
+		{
+			input: "2025-01-15 10:25:10.000 [I] [proxy/proxy_wrapper.go:200] [9f2c1d7e5b3a4e60] [web] health check failed",
+			ok:    true,
+			expected: Record{
+				Level: LevelInfo, Source: "proxy/proxy_wrapper.go:200", RunID: "9f2c1d7e5b3a4e60", Proxy: "web",
+				Event: EventHealthCheckFailed, Message: "health check failed",
+			},
+		},
+		// frp v0.44
+		{
+			input: "2022/09/01 08:00:00 [I] [service.go:349] [c4b2a1f0e9d8c7b6] login to server success, get run id [c4b2a1f0e9d8c7b6], server udp port [0]",
+			ok:    true,
+			expected: Record{
+				Level: LevelInfo, Source: "service.go:349", RunID: "c4b2a1f0e9d8c7b6", Event: EventLogin,
+				Message: "login to server success, get run id [c4b2a1f0e9d8c7b6], server udp port [0]",
+			},
+		},
+		{
+			input: "2022/09/01 08:00:00 [W] [control.go:179] [c4b2a1f0e9d8c7b6] [rdp] start error: proxy [rdp] already exists",
+			ok:    true,
+			expected: Record{
+				Level: LevelWarn, Source: "control.go:179", RunID: "c4b2a1f0e9d8c7b6", Proxy: "rdp",
+				Event: EventProxyError, Message: "start error: proxy [rdp] already exists", Err: "proxy [rdp] already exists",
+			},
+		},
+		{
+			input: "2022/09/01 08:01:00 [W] [service.go:132] login to server failed: EOF",
+			ok:    true,
+			expected: Record{
+				Level: LevelWarn, Source: "service.go:132", Event: EventLoginFailed,
+				Message: "login to server failed: EOF", Err: "EOF",
+			},
+		},
+		{
+			input: "2022/09/01 08:01:10 [I] [control.go:261] [c4b2a1f0e9d8c7b6] try to reconnect to server...",
+			ok:    true,
+			expected: Record{
+				Level: LevelInfo, Source: "control.go:261", RunID: "c4b2a1f0e9d8c7b6", Event: EventReconnect,
+				Message: "try to reconnect to server...",
+			},
+		},
+		// frp v0.36
+		{
+			input: "2021/03/02 12:00:00 [I] [proxy_wrapper.go:190] [5e6f7a8b9c0d1e2f] [web] health check success\r\n",
+			ok:    true,
+			expected: Record{
+				Level: LevelInfo, Source: "proxy_wrapper.go:190", RunID: "5e6f7a8b9c0d1e2f", Proxy: "web",
+				Event: EventHealthCheckSuccess, Message: "health check success",
+			},
+		},
+		// Lines which used to be taken as proxy errors
+		{
+			input: "2025-01-15 10:26:00.000 [W] [client/control.go:130] [9f2c1d7e5b3a4e60] work connection closed before response StartWorkConn message: EOF, failed",
+			ok:    true,
+			expected: Record{
+				Level: LevelWarn, Source: "client/control.go:130", RunID: "9f2c1d7e5b3a4e60",
+				Message: "work connection closed before response StartWorkConn message: EOF, failed",
+			},
+		},
+		// Not log lines
+		{input: ""},
+		{input: "goroutine 1 [running]:"},
+		{input: "panic: runtime error: invalid memory address [recovered]"},
+		{input: "2025-01-15 10:26:00.000 [X] [client/control.go:130] unknown level"},
+	}
+	for i, test := range tests {
+		output, ok := Parse(test.input)
+		if ok != test.ok {
+			t.Errorf("Test %d: expected: %v, got: %v", i, test.ok, ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		output.Time = time.Time{}
+		if output != test.expected {
+			t.Errorf("Test %d: expected: %+v, got: %+v", i, test.expected, output)
+		}
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected time.Time
+	}{
+		{input: "2025-01-15 10:23:45.123 [I] [sub/root.go:142] start", expected: time.Date(2025, 1, 15, 10, 23, 45, 123e6, time.Local)},
+		{input: "2022/09/01 08:00:00 [I] [service.go:349] start", expected: time.Date(2022, 9, 1, 8, 0, 0, 0, time.Local)},
+	}
+	for i, test := range tests {
+		if output, _ := Parse(test.input); !output.Time.Equal(test.expected) {
+			t.Errorf("Test %d: expected: %v, got: %v", i, test.expected, output.Time)
+		}
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for l := LevelTrace; l <= LevelError; l++ {
+		if output, ok := ParseLevel(l.String()); !ok || output != l {
+			t.Errorf("Expected: %v, got: %v", l, output)
+		}
+	}
+	if _, ok := ParseLevel("fatal"); ok {
+		t.Errorf("Expected: %v, got: %v", false, ok)
+	}
+}
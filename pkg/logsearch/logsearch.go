@@ -0,0 +1,231 @@
+// Package logsearch searches the log files of configs, including the files rotated by
+// frpc, which are found by util.FindLogFiles.
+package logsearch
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/hzcrv1911/frpcgui/pkg/logparse"
+	"github.com/hzcrv1911/frpcgui/pkg/util"
+)
+
+// maxLineSize is the longest line read from a log file.
+const maxLineSize = 1 << 20
+
+// ErrLimit is returned by Search if more lines match the query than its limit.
+var ErrLimit = errors.New("result limit reached")
+
+// Source is the log of a config.
+type Source struct {
+	// Name identifies the source in the results, like the name of the config.
+	Name string
+	// Path is the log file of the config. The rotated files are found next to it.
+	Path string
+}
+
+// Query selects the log lines. The zero value matches every line.
+type Query struct {
+	// Since and Until limit the time of the lines, if they are not zero. Until is excluded.
+	Since time.Time
+	Until time.Time
+	// Level is the minimum level of the lines.
+	Level logparse.Level
+	// Proxy is the name of the proxy or visitor the lines are about.
+	Proxy string
+	// Pattern matches the text of the lines.
+	Pattern *regexp.Regexp
+	// Limit is the maximum number of results, or 0 for no limit.
+	Limit int
+}
+
+// Result is a line matching a query.
+type Result struct {
+	// Source is the name of the source of the line.
+	Source string
+	// File is the log file containing the line.
+	File string
+	// Line is the text of the line.
+	Line string
+	// Record is the parsed line. A line which isn't a log line, like a line of a stack
+	// trace, has the time, level and proxy of the log line before it.
+	Record logparse.Record
+}
+
+// match reports whether the result is selected by the query. The time range is checked
+// by the cursor, which stops at the end of the range.
+func (q *Query) match(r *Result) bool {
+	if r.Record.Level < q.Level {
+		return false
+	}
+	if q.Proxy != "" && r.Record.Proxy != q.Proxy {
+		return false
+	}
+	if q.Pattern != nil && !q.Pattern.MatchString(r.Line) {
+		return false
+	}
+	return !r.Record.Time.Before(q.Since)
+}
+
+// Search calls fn with the lines of the sources matching the query, in chronological
+// order. The lines of different sources are merged by time. It stops at the first error
+// returned by fn, which is returned. ErrLimit is returned if the results are cut by the
+// limit of the query. A source without log files is skipped.
+func Search(ctx context.Context, sources []Source, q Query, fn func(Result) error) error {
+	var cursors []*cursor
+	defer func() {
+		for _, c := range cursors {
+			c.close()
+		}
+	}()
+	for _, src := range sources {
+		files, err := logFiles(src.Path, q.Since)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) || errors.Is(err, os.ErrInvalid) {
+				continue
+			}
+			return err
+		}
+		c := &cursor{source: src.Name, files: files, query: &q}
+		if err = c.next(); err != nil {
+			return err
+		}
+		if c.ok {
+			cursors = append(cursors, c)
+		}
+	}
+	count := 0
+	for len(cursors) > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		// The earliest line comes first, with ties in the order of the sources
+		i := 0
+		for j, c := range cursors[1:] {
+			if c.head.Record.Time.Before(cursors[i].head.Record.Time) {
+				i = j + 1
+			}
+		}
+		c := cursors[i]
+		if q.Limit > 0 && count >= q.Limit {
+			return ErrLimit
+		}
+		if err := fn(c.head); err != nil {
+			return err
+		}
+		count++
+		if err := c.next(); err != nil {
+			return err
+		}
+		if !c.ok {
+			c.close()
+			cursors = slices.Delete(cursors, i, i+1)
+		}
+	}
+	return nil
+}
+
+// logFiles returns the log files of a config from the oldest. A rotated file only has
+// lines before its rotation, so the files rotated before the given time are skipped.
+func logFiles(path string, since time.Time) ([]string, error) {
+	files, dates, err := util.FindLogFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	type rotated struct {
+		path string
+		date time.Time
+	}
+	var logs []rotated
+	for i := 1; i < len(files); i++ {
+		if !dates[i].Before(since) {
+			logs = append(logs, rotated{files[i], dates[i]})
+		}
+	}
+	slices.SortFunc(logs, func(a, b rotated) int { return a.date.Compare(b.date) })
+	result := make([]string, 0, len(logs)+1)
+	for _, log := range logs {
+		result = append(result, log.path)
+	}
+	return append(result, files[0]), nil
+}
+
+// cursor reads the matching lines of the log files of a source in order.
+type cursor struct {
+	source string
+	files  []string
+	query  *Query
+
+	file    *os.File
+	scanner *bufio.Scanner
+	// last is the record of the last log line
+	last logparse.Record
+	// head is the next result if ok is true
+	head Result
+	ok   bool
+}
+
+// next moves to the next matching line. It sets ok to false at the end of the files or
+// the time range.
+func (c *cursor) next() error {
+	for {
+		if c.scanner == nil {
+			if len(c.files) == 0 {
+				c.ok = false
+				return nil
+			}
+			f, err := os.Open(c.files[0])
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					// Rotated in the meantime
+					c.files = c.files[1:]
+					continue
+				}
+				return err
+			}
+			c.file = f
+			c.scanner = bufio.NewScanner(f)
+			c.scanner.Buffer(nil, maxLineSize)
+		}
+		if !c.scanner.Scan() {
+			err := c.scanner.Err()
+			c.close()
+			if err != nil {
+				return err
+			}
+			c.files = c.files[1:]
+			continue
+		}
+		line := c.scanner.Text()
+		r, ok := logparse.Parse(line)
+		if ok {
+			c.last = r
+		} else {
+			r = logparse.Record{Time: c.last.Time, Level: c.last.Level, RunID: c.last.RunID, Proxy: c.last.Proxy, Message: line}
+		}
+		if !c.query.Until.IsZero() && !r.Time.Before(c.query.Until) {
+			c.close()
+			c.files = nil
+			c.ok = false
+			return nil
+		}
+		c.head = Result{Source: c.source, File: c.files[0], Line: line, Record: r}
+		if c.query.match(&c.head) {
+			c.ok = true
+			return nil
+		}
+	}
+}
+
+func (c *cursor) close() {
+	if c.file != nil {
+		c.file.Close()
+		c.file = nil
+		c.scanner = nil
+	}
+}
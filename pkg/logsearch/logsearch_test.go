@@ -0,0 +1,109 @@
+package logsearch
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hzcrv1911/frpcgui/pkg/logparse"
+)
+
+func writeLogs(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(strings.TrimLeft(content, "\n")), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	dir := t.TempDir()
+	writeLogs(t, dir, map[string]string{
+		"home.20250114-000000.log": `
+2025-01-13 23:00:00.000 [I] [client/service.go:314] [aaaa] login to server success, get run id [aaaa]
+2025-01-13 23:00:01.000 [I] [client/control.go:172] [aaaa] [ssh] start proxy success
+`,
+		"home.20250115-000000.log": `
+2025-01-14 08:00:00.000 [W] [client/service.go:294] connect to server error: EOF
+2025-01-14 08:00:10.000 [I] [client/control.go:172] [bbbb] [ssh] start proxy success
+`,
+		"home.log": `
+2025-01-15 09:00:00.000 [W] [client/control.go:170] [cccc] [web] start error: port already used
+2025-01-15 09:00:05.000 [E] [client/control.go:200] [cccc] panic
+goroutine 1 [running]:
+2025-01-15 09:00:10.000 [I] [client/control.go:172] [cccc] [ssh] start proxy success
+`,
+		"office.log": `
+2025-01-14 12:00:00.000 [I] [client/control.go:172] [dddd] [ssh] start proxy success
+2025-01-15 09:00:07.000 [W] [client/service.go:294] connect to server error: i/o timeout
+`,
+	})
+	sources := []Source{
+		{Name: "home", Path: filepath.Join(dir, "home.log")},
+		{Name: "office", Path: filepath.Join(dir, "office.log")},
+		{Name: "missing", Path: filepath.Join(dir, "missing", "frpc.log")},
+		{Name: "console", Path: "console"},
+	}
+	date := func(day, hour int) time.Time { return time.Date(2025, 1, day, hour, 0, 0, 0, time.Local) }
+	tests := []struct {
+		query    Query
+		err      error
+		expected []string
+	}{
+		{
+			query: Query{},
+			expected: []string{
+				"home aaaa login", "home aaaa ssh", "home  ", "home bbbb ssh", "office dddd ssh",
+				"home cccc web", "home cccc ", "home cccc ", "office  ", "home cccc ssh",
+			},
+		},
+		{query: Query{Level: logparse.LevelWarn}, expected: []string{
+			"home  ", "home cccc web", "home cccc ", "home cccc ", "office  ",
+		}},
+		{query: Query{Level: logparse.LevelError}, expected: []string{"home cccc ", "home cccc "}},
+		{query: Query{Proxy: "ssh", Since: date(14, 0), Until: date(15, 9)}, expected: []string{"home bbbb ssh", "office dddd ssh"}},
+		{query: Query{Since: date(15, 0)}, expected: []string{"home cccc web", "home cccc ", "home cccc ", "office  ", "home cccc ssh"}},
+		{query: Query{Pattern: regexp.MustCompile(`connect to server error`)}, expected: []string{"home  ", "office  "}},
+		{query: Query{Limit: 2}, err: ErrLimit, expected: []string{"home aaaa login", "home aaaa ssh"}},
+	}
+	for i, test := range tests {
+		var output []string
+		err := Search(context.Background(), sources, test.query, func(r Result) error {
+			s := r.Source + " " + r.Record.RunID + " " + r.Record.Proxy
+			if r.Record.Event == logparse.EventLogin {
+				s += "login"
+			}
+			output = append(output, s)
+			return nil
+		})
+		if !errors.Is(err, test.err) {
+			t.Errorf("Test %d: expected: %v, got: %v", i, test.err, err)
+		}
+		if !reflect.DeepEqual(output, test.expected) {
+			t.Errorf("Test %d: expected: %q, got: %q", i, test.expected, output)
+		}
+	}
+}
+
+func TestSearchStop(t *testing.T) {
+	dir := t.TempDir()
+	writeLogs(t, dir, map[string]string{"frpc.log": `
+2025-01-15 09:00:00.000 [I] [client/control.go:172] [cccc] [ssh] start proxy success
+2025-01-15 09:00:01.000 [I] [client/control.go:172] [cccc] [web] start proxy success
+`})
+	stop := errors.New("stop")
+	n := 0
+	err := Search(context.Background(), []Source{{Name: "home", Path: filepath.Join(dir, "frpc.log")}}, Query{}, func(r Result) error {
+		n++
+		return stop
+	})
+	if err != stop || n != 1 {
+		t.Errorf("Expected: %v %v, got: %v %v", stop, 1, err, n)
+	}
+}
@@ -0,0 +1,201 @@
+// Package logtail follows the log files written by frpc.
+//
+// The log file is rotated by frpc or WinSW while it's followed. The file is reopened on
+// every read instead of being kept open, because an open file can't be renamed on
+// Windows. A rotation is detected by the identity of the file, and a truncation by its
+// size going below the read offset. The lines written to a rotated file after its last
+// read are missed.
+package logtail
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// pollInterval is the delay between the reads of a followed file.
+	pollInterval = time.Second
+	// maxBacklog is the number of recent lines kept for new subscribers.
+	maxBacklog = 2000
+	// maxInitialRead limits the content read from the end of a file when it's first
+	// opened, so that a large log isn't read entirely.
+	maxInitialRead = 1 << 20
+)
+
+var (
+	tailersMu sync.Mutex
+	tailers   = make(map[string]*Tailer)
+)
+
+// Tailer follows a log file and passes the new lines to its subscribers. Only complete
+// lines are passed, without the line endings.
+type Tailer struct {
+	path   string
+	refs   int
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	subs    map[int]func([]string)
+	nextID  int
+	backlog []string
+	info    os.FileInfo
+	offset  int64
+	partial []byte
+	skip    bool
+}
+
+// Open returns the tailer of the log file, which is shared by all callers opening the
+// same file. The file is followed until every caller calls Close.
+func Open(path string) *Tailer {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	tailersMu.Lock()
+	defer tailersMu.Unlock()
+	t, ok := tailers[path]
+	if !ok {
+		t = newTailer(path)
+		ctx, cancel := context.WithCancel(context.Background())
+		t.cancel = cancel
+		go t.run(ctx)
+		tailers[path] = t
+	}
+	t.refs++
+	return t
+}
+
+func newTailer(path string) *Tailer {
+	return &Tailer{path: path, subs: make(map[int]func([]string))}
+}
+
+// Close releases the tailer returned by Open.
+func (t *Tailer) Close() {
+	tailersMu.Lock()
+	defer tailersMu.Unlock()
+	if t.refs--; t.refs == 0 {
+		delete(tailers, t.path)
+		t.cancel()
+	}
+}
+
+// Path returns the path of the followed file.
+func (t *Tailer) Path() string {
+	return t.path
+}
+
+// Subscribe calls cb with the recent lines of the file, then with the new lines every
+// time they are read. The first call is made before Subscribe returns, the others on
+// the goroutine of the tailer. The callback must not modify the lines or call the
+// tailer. No calls are made after the returned function is called.
+func (t *Tailer) Subscribe(cb func(lines []string)) (cancel func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.poll()
+	if len(t.backlog) > 0 {
+		cb(slices.Clone(t.backlog))
+	}
+	id := t.nextID
+	t.nextID++
+	t.subs[id] = cb
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		delete(t.subs, id)
+	}
+}
+
+// Lines returns the recent lines of the file.
+func (t *Tailer) Lines() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.Clone(t.backlog)
+}
+
+// Poll reads the new lines of the file immediately.
+func (t *Tailer) Poll() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.poll()
+}
+
+func (t *Tailer) run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		t.Poll()
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (t *Tailer) poll() {
+	f, err := os.Open(t.path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return
+	}
+	switch {
+	case t.info == nil:
+		// Start near the end of the file, from the first complete line
+		t.offset = max(0, info.Size()-maxInitialRead)
+		t.skip = t.offset > 0
+	case !os.SameFile(t.info, info) || info.Size() < t.offset:
+		// The file is rotated or truncated
+		t.offset = 0
+		t.partial = nil
+		t.skip = false
+	}
+	t.info = info
+	if info.Size() <= t.offset {
+		return
+	}
+	if _, err = f.Seek(t.offset, io.SeekStart); err != nil {
+		return
+	}
+	b, err := io.ReadAll(io.LimitReader(f, info.Size()-t.offset))
+	if err != nil {
+		return
+	}
+	t.offset += int64(len(b))
+	data := append(t.partial, b...)
+	if t.skip {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			t.partial = nil
+			return
+		}
+		data = data[i+1:]
+		t.skip = false
+	}
+	i := bytes.LastIndexByte(data, '\n')
+	if i < 0 {
+		t.partial = data
+		return
+	}
+	t.partial = bytes.Clone(data[i+1:])
+	lines := strings.Split(string(data[:i]), "\n")
+	for j, line := range lines {
+		lines[j] = strings.TrimSuffix(line, "\r")
+	}
+	t.backlog = append(t.backlog, lines...)
+	if n := len(t.backlog); n > maxBacklog {
+		t.backlog = slices.Clone(t.backlog[n-maxBacklog:])
+	}
+	for _, cb := range t.subs {
+		cb(lines)
+	}
+}
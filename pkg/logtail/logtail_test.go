@@ -0,0 +1,108 @@
+package logtail
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func appendFile(t *testing.T, path, content string) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err = f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTailer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "frpc.log")
+	appendFile(t, path, "a\r\nb\r\nc")
+	tailer := newTailer(path)
+	var received [][]string
+	var other []string
+	cancel := tailer.Subscribe(func(lines []string) { received = append(received, lines) })
+	tailer.Subscribe(func(lines []string) { other = append(other, lines...) })
+
+	tests := []struct {
+		// update changes the file before a poll
+		update   func()
+		expected []string
+	}{
+		// A partial line is completed later
+		{update: func() { appendFile(t, path, "d\n\n") }, expected: []string{"cd", ""}},
+		{update: func() {}, expected: nil},
+		// Truncated
+		{update: func() { os.WriteFile(path, []byte("e\n"), 0666) }, expected: []string{"e"}},
+		// Rotated
+		{update: func() {
+			os.Rename(path, path+".1")
+			appendFile(t, path, "f\ng\n")
+		}, expected: []string{"f", "g"}},
+		// Removed
+		{update: func() { os.Remove(path) }, expected: nil},
+		{update: func() { appendFile(t, path, "h\n") }, expected: []string{"h"}},
+	}
+	if !reflect.DeepEqual(received, [][]string{{"a", "b"}}) {
+		t.Fatalf("Expected: %v, got: %v", [][]string{{"a", "b"}}, received)
+	}
+	for i, test := range tests {
+		received = nil
+		test.update()
+		tailer.Poll()
+		var output []string
+		for _, lines := range received {
+			output = append(output, lines...)
+		}
+		if !reflect.DeepEqual(output, test.expected) {
+			t.Errorf("Test %d: expected: %q, got: %q", i, test.expected, output)
+		}
+	}
+	expected := []string{"a", "b", "cd", "", "e", "f", "g", "h"}
+	if output := tailer.Lines(); !reflect.DeepEqual(output, expected) {
+		t.Errorf("Expected: %q, got: %q", expected, output)
+	}
+	if !reflect.DeepEqual(other, expected) {
+		t.Errorf("Expected: %q, got: %q", expected, other)
+	}
+	// No more lines after cancelling
+	cancel()
+	received = nil
+	appendFile(t, path, "i\n")
+	tailer.Poll()
+	if received != nil {
+		t.Errorf("Expected: %v, got: %v", nil, received)
+	}
+}
+
+func TestTailerLargeFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "frpc.log")
+	line := strings.Repeat("x", 99) + "\n"
+	appendFile(t, path, strings.Repeat(line, 6*maxBacklog)+"last\n")
+	tailer := newTailer(path)
+	tailer.Poll()
+	lines := tailer.Lines()
+	if len(lines) != maxBacklog || lines[len(lines)-1] != "last" || lines[0] != line[:99] {
+		t.Errorf("Expected: %v, got: %v lines ending with %q", maxBacklog, len(lines), lines[len(lines)-1])
+	}
+}
+
+func TestOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "frpc.log")
+	a := Open(path)
+	b := Open(path)
+	if a != b {
+		t.Errorf("Expected: %v, got: %v", "a shared tailer", "different tailers")
+	}
+	a.Close()
+	b.Close()
+	if c := Open(path); c == a {
+		t.Errorf("Expected: %v, got: %v", "a new tailer", "the closed tailer")
+	} else {
+		c.Close()
+	}
+}
@@ -1,12 +1,141 @@
 package sec
 
 import (
+	"crypto/rand"
 	"crypto/sha1"
+	"crypto/subtle"
 	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/argon2"
 )
 
+// ErrInvalidHash is returned for password hashes in an unknown format.
+var ErrInvalidHash = errors.New("invalid password hash")
+
+// Argon2Params are the tunable parameters of the argon2id password hashing.
+type Argon2Params struct {
+	// Memory in KiB
+	Memory  uint32
+	Time    uint32
+	Threads uint8
+	SaltLen uint32
+	KeyLen  uint32
+}
+
+// PasswordParams are the parameters of new password hashes. Hashes made with other
+// parameters still verify, but are reported to need a rehash.
+var PasswordParams = Argon2Params{
+	Memory:  64 * 1024,
+	Time:    3,
+	Threads: 4,
+	SaltLen: 16,
+	KeyLen:  32,
+}
+
 // EncryptPassword returns a Base64-encoded string of the hashed password.
+//
+// Deprecated: use HashPassword instead. It's only kept to verify legacy hashes.
 func EncryptPassword(password string) string {
 	hashed := sha1.Sum([]byte(password))
 	return base64.StdEncoding.EncodeToString(hashed[:])
 }
+
+// HashPassword returns the argon2id hash of the password with a random salt, encoded
+// like "$argon2id$v=19$m=65536,t=3,p=4$<salt>$<hash>".
+func HashPassword(password string) (string, error) {
+	p := PasswordParams
+	salt := make([]byte, p.SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, p.KeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, p.Memory, p.Time, p.Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// VerifyPassword reports whether the password matches the encoded hash. The hash
+// is either made by HashPassword or a legacy one made by EncryptPassword. If the
+// password matches, rehash tells whether the hash should be replaced by a new one,
+// as it's in the legacy format or made with outdated parameters.
+func VerifyPassword(password, encoded string) (ok, rehash bool) {
+	if !strings.HasPrefix(encoded, "$") {
+		ok = subtle.ConstantTimeCompare([]byte(EncryptPassword(password)), []byte(encoded)) == 1
+		return ok, ok
+	}
+	p, salt, key, err := decodeHash(encoded)
+	if err != nil {
+		return false, false
+	}
+	actual := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, p.KeyLen)
+	if subtle.ConstantTimeCompare(actual, key) != 1 {
+		return false, false
+	}
+	return true, p != PasswordParams
+}
+
+func decodeHash(encoded string) (p Argon2Params, salt, key []byte, err error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		err = ErrInvalidHash
+		return
+	}
+	var version int
+	if _, err = fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		err = ErrInvalidHash
+		return
+	}
+	if _, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Time, &p.Threads); err != nil || p.Time < 1 || p.Threads < 1 {
+		err = ErrInvalidHash
+		return
+	}
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		err = ErrInvalidHash
+		return
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(key) == 0 {
+		err = ErrInvalidHash
+		return
+	}
+	p.SaltLen, p.KeyLen = uint32(len(salt)), uint32(len(key))
+	return
+}
+
+const (
+	// FreeAttempts is the number of failed attempts allowed before a lockout.
+	FreeAttempts = 3
+	// MaxLockoutDelay caps the lockout after many failed attempts.
+	MaxLockoutDelay = 15 * time.Minute
+)
+
+// Lockout delays password attempts after repeated failures. The delay starts at one
+// second and doubles with each further failure.
+type Lockout struct {
+	Failures int       `json:"failures,omitempty"`
+	Until    time.Time `json:"until,omitzero"`
+}
+
+// Remaining returns how long to wait before the next attempt.
+func (l *Lockout) Remaining(now time.Time) time.Duration {
+	return max(l.Until.Sub(now), 0)
+}
+
+// Fail records a failed attempt.
+func (l *Lockout) Fail(now time.Time) {
+	l.Failures++
+	if n := l.Failures - FreeAttempts; n >= 0 {
+		delay := MaxLockoutDelay
+		if n < 20 {
+			delay = min(time.Second<<n, MaxLockoutDelay)
+		}
+		l.Until = now.Add(delay)
+	}
+}
+
+// Reset clears the failures after a successful attempt.
+func (l *Lockout) Reset() {
+	*l = Lockout{}
+}
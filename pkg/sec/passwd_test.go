@@ -1,6 +1,10 @@
 package sec
 
-import "testing"
+import (
+	"strings"
+	"testing"
+	"time"
+)
 
 func TestEncryptPassword(t *testing.T) {
 	output := EncryptPassword("123456")
@@ -9,3 +13,67 @@ func TestEncryptPassword(t *testing.T) {
 		t.Errorf("Expected: %v, got: %v", expected, output)
 	}
 }
+
+func TestVerifyPassword(t *testing.T) {
+	hashed, err := HashPassword("123456")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hashed, "$argon2id$v=19$m=65536,t=3,p=4$") {
+		t.Errorf("Expected: %v, got: %v", "an argon2id hash", hashed)
+	}
+	outdated := PasswordParams
+	outdated.Time = 1
+	PasswordParams, outdated = outdated, PasswordParams
+	outdatedHash, err := HashPassword("123456")
+	PasswordParams = outdated
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		password string
+		hash     string
+		ok       bool
+		rehash   bool
+	}{
+		{"123456", hashed, true, false},
+		{"12345", hashed, false, false},
+		{"123456", "fEqNCco3Yq9h5ZUglD3CZJT4lBs=", true, true},
+		{"12345", "fEqNCco3Yq9h5ZUglD3CZJT4lBs=", false, false},
+		{"123456", outdatedHash, true, true},
+		{"123456", "$argon2id$v=19$m=65536,t=3,p=4$invalid", false, false},
+		{"123456", strings.Replace(hashed, ",t=3,", ",t=0,", 1), false, false},
+		{"123456", strings.Replace(hashed, ",p=4$", ",p=0$", 1), false, false},
+	}
+	for i, test := range tests {
+		ok, rehash := VerifyPassword(test.password, test.hash)
+		if ok != test.ok || rehash != test.rehash {
+			t.Errorf("Test %d, expected: %v %v, got: %v %v", i, test.ok, test.rehash, ok, rehash)
+		}
+	}
+}
+
+func TestLockout(t *testing.T) {
+	now := time.Now()
+	var l Lockout
+	expected := []time.Duration{0, 0, time.Second, 2 * time.Second, 4 * time.Second}
+	for i, delay := range expected {
+		l.Fail(now)
+		if actual := l.Remaining(now); actual != delay {
+			t.Errorf("Attempt %d, expected: %v, got: %v", i+1, delay, actual)
+		}
+	}
+	for range 30 {
+		l.Fail(now)
+	}
+	if actual := l.Remaining(now); actual != MaxLockoutDelay {
+		t.Errorf("Expected: %v, got: %v", MaxLockoutDelay, actual)
+	}
+	if actual := l.Remaining(now.Add(time.Hour)); actual != 0 {
+		t.Errorf("Expected: %v, got: %v", 0, actual)
+	}
+	l.Reset()
+	if l.Failures != 0 || l.Remaining(now) != 0 {
+		t.Errorf("Expected: %v, got: %v", Lockout{}, l)
+	}
+}
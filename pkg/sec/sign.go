@@ -0,0 +1,66 @@
+package sec
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"os"
+)
+
+const signingKeyType = "PRIVATE KEY"
+
+// ErrSigningKey is returned when a signing key or public key can't be decoded.
+var ErrSigningKey = errors.New("invalid ed25519 key")
+
+// GenerateSigningKey returns a new ed25519 key for signing.
+func GenerateSigningKey() (ed25519.PrivateKey, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	return key, err
+}
+
+// SaveSigningKey writes the signing key to the file in PEM-encoded PKCS #8,
+// readable by the owner only.
+func SaveSigningKey(path string, key ed25519.PrivateKey) error {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: signingKeyType, Bytes: der}), 0600)
+}
+
+// LoadSigningKey reads a signing key written by SaveSigningKey.
+func LoadSigningKey(path string) (ed25519.PrivateKey, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil || block.Type != signingKeyType {
+		return nil, ErrSigningKey
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	if key, ok := key.(ed25519.PrivateKey); ok {
+		return key, nil
+	}
+	return nil, ErrSigningKey
+}
+
+// EncodePublicKey returns the public key in base64, the form it's shared and trusted in.
+func EncodePublicKey(key ed25519.PublicKey) string {
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+// DecodePublicKey decodes a public key encoded by EncodePublicKey.
+func DecodePublicKey(s string) (ed25519.PublicKey, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(b) != ed25519.PublicKeySize {
+		return nil, ErrSigningKey
+	}
+	return b, nil
+}
@@ -0,0 +1,41 @@
+package sec
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSigningKey(t *testing.T) {
+	key, err := GenerateSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "signing.key")
+	if err = SaveSigningKey(path, key); err != nil {
+		t.Fatal(err)
+	}
+	output, err := LoadSigningKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(output, key) {
+		t.Errorf("Expected: %v, got: %v", key, output)
+	}
+
+	pub := key.Public().(ed25519.PublicKey)
+	decoded, err := DecodePublicKey(EncodePublicKey(pub))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, pub) {
+		t.Errorf("Expected: %v, got: %v", pub, decoded)
+	}
+	for _, input := range []string{"", "not base64", "YWJj"} {
+		if _, err = DecodePublicKey(input); !errors.Is(err, ErrSigningKey) {
+			t.Errorf("Expected: %v, got: %v", ErrSigningKey, err)
+		}
+	}
+}
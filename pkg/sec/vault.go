@@ -0,0 +1,187 @@
+package sec
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/hzcrv1911/frpcgui/pkg/util"
+)
+
+// SealedPrefix marks the values sealed by a vault.
+const SealedPrefix = "enc:"
+
+const (
+	keySize       = 32
+	saltSize      = 16
+	kdfIterations = 600000
+)
+
+// ErrVaultKey is returned when a sealed value or key file can't be opened with the given key.
+var ErrVaultKey = errors.New("the secret is encrypted with a different key")
+
+// Vault seals secrets with AES-256-GCM, so that they can be stored in config files.
+type Vault struct {
+	aead cipher.AEAD
+}
+
+// NewVault returns a vault using the 32-byte key.
+func NewVault(key []byte) (*Vault, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &Vault{aead: aead}, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// GenerateKey returns a new random key for a vault.
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// IsSealed reports whether the value is sealed by a vault.
+func IsSealed(s string) bool {
+	return strings.HasPrefix(s, SealedPrefix)
+}
+
+// Seal encrypts the value. Empty values and values already sealed are returned as is.
+func (v *Vault) Seal(s string) (string, error) {
+	if s == "" || IsSealed(s) {
+		return s, nil
+	}
+	b, err := seal(v.aead, []byte(s))
+	if err != nil {
+		return "", err
+	}
+	return SealedPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Open decrypts a sealed value. Values not sealed are returned as is.
+func (v *Vault) Open(s string) (string, error) {
+	if !IsSealed(s) {
+		return s, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(s, SealedPrefix))
+	if err != nil {
+		return "", ErrVaultKey
+	}
+	if b, err = open(v.aead, b); err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// seal encrypts the plaintext with a random nonce, which is prepended to the result.
+func seal(aead cipher.AEAD, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func open(aead cipher.AEAD, b []byte) ([]byte, error) {
+	if len(b) < aead.NonceSize() {
+		return nil, ErrVaultKey
+	}
+	plaintext, err := aead.Open(nil, b[:aead.NonceSize()], b[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, ErrVaultKey
+	}
+	return plaintext, nil
+}
+
+// KeyFile stores the key of a vault. With a master password, the key is encrypted with
+// a key derived from the password. Otherwise, it's stored as is. The file doesn't depend
+// on the machine, so it can be moved along with the configs.
+type KeyFile struct {
+	Salt       []byte `json:"salt,omitempty"`
+	Iterations int    `json:"iterations,omitempty"`
+	Key        []byte `json:"key"`
+}
+
+// NewKeyFile returns a key file holding the vault key, protected by the password if it's not empty.
+func NewKeyFile(key []byte, password string) (*KeyFile, error) {
+	if password == "" {
+		return &KeyFile{Key: key}, nil
+	}
+	kf := &KeyFile{Salt: make([]byte, saltSize), Iterations: kdfIterations}
+	if _, err := rand.Read(kf.Salt); err != nil {
+		return nil, err
+	}
+	aead, err := kf.passwordAEAD(password)
+	if err != nil {
+		return nil, err
+	}
+	if kf.Key, err = seal(aead, key); err != nil {
+		return nil, err
+	}
+	return kf, nil
+}
+
+// Protected reports whether the key is encrypted with a password.
+func (kf *KeyFile) Protected() bool {
+	return len(kf.Salt) > 0
+}
+
+// Unlock returns the vault key. The password is ignored if the key is not protected.
+func (kf *KeyFile) Unlock(password string) ([]byte, error) {
+	if !kf.Protected() {
+		return kf.Key, nil
+	}
+	aead, err := kf.passwordAEAD(password)
+	if err != nil {
+		return nil, err
+	}
+	return open(aead, kf.Key)
+}
+
+func (kf *KeyFile) passwordAEAD(password string) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, password, kf.Salt, kf.Iterations, keySize)
+	if err != nil {
+		return nil, err
+	}
+	return newAEAD(key)
+}
+
+// LoadKeyFile reads the key file at the path.
+func LoadKeyFile(path string) (*KeyFile, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	kf := new(KeyFile)
+	if err = json.Unmarshal(b, kf); err != nil {
+		return nil, err
+	}
+	return kf, nil
+}
+
+// Save writes the key file to the path.
+func (kf *KeyFile) Save(path string) error {
+	b, err := json.MarshalIndent(kf, "", "    ")
+	if err != nil {
+		return err
+	}
+	// A torn key file would lose every sealed secret
+	return util.WriteFileAtomic(path, b, 0600)
+}
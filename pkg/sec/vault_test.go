@@ -0,0 +1,88 @@
+package sec
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestVault(t *testing.T) {
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := NewVault(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []string{"", "token", "pässwörd with spaces"}
+	for _, test := range tests {
+		sealed, err := v.Seal(test)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test != "" && (!IsSealed(sealed) || strings.Contains(sealed, test)) {
+			t.Errorf("Expected: %v, got: %v", "a sealed value", sealed)
+		}
+		if again, _ := v.Seal(sealed); again != sealed {
+			t.Errorf("Expected: %v, got: %v", sealed, again)
+		}
+		output, err := v.Open(sealed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if output != test {
+			t.Errorf("Expected: %v, got: %v", test, output)
+		}
+	}
+	sealed, _ := v.Seal("token")
+	other, _ := GenerateKey()
+	ov, _ := NewVault(other)
+	if _, err = ov.Open(sealed); !errors.Is(err, ErrVaultKey) {
+		t.Errorf("Expected: %v, got: %v", ErrVaultKey, err)
+	}
+}
+
+func TestKeyFile(t *testing.T) {
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		password  string
+		protected bool
+	}{
+		{"", false},
+		{"123456", true},
+	}
+	for _, test := range tests {
+		path := filepath.Join(t.TempDir(), "vault.key")
+		kf, err := NewKeyFile(key, test.password)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = kf.Save(path); err != nil {
+			t.Fatal(err)
+		}
+		if kf, err = LoadKeyFile(path); err != nil {
+			t.Fatal(err)
+		}
+		if kf.Protected() != test.protected {
+			t.Errorf("Expected: %v, got: %v", test.protected, kf.Protected())
+		}
+		output, err := kf.Unlock(test.password)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(output, key) {
+			t.Errorf("Expected: %v, got: %v", key, output)
+		}
+		if test.protected {
+			if _, err = kf.Unlock("wrong"); !errors.Is(err, ErrVaultKey) {
+				t.Errorf("Expected: %v, got: %v", ErrVaultKey, err)
+			}
+		}
+	}
+}
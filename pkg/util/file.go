@@ -120,13 +120,70 @@ func ZipFiles(filename string, files map[string]string) error {
 
 	// Add files to zip
 	for src, dst := range files {
-		if err = addFileToZip(zipWriter, src, dst); err != nil {
+		if err = addFileToZip(zipWriter, src, filepath.Base(dst)); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// ZipEntry is a file of a zip file written by WriteZip. The content is read from Path if
+// Data is nil.
+type ZipEntry struct {
+	// Name is the slash-separated path of the file in the zip file.
+	Name string
+	Path string
+	Data []byte
+}
+
+// WriteZip writes the entries to a zip file. Unlike ZipFiles, the entries can be put in
+// directories, and be created from memory.
+func WriteZip(filename string, entries []ZipEntry) error {
+	// The zip file is written to a temporary file first, so a failure leaves no partial file
+	f, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp)
+	defer f.Close()
+
+	zipWriter := zip.NewWriter(f)
+	for _, entry := range entries {
+		if entry.Data == nil {
+			err = addFileToZip(zipWriter, entry.Path, entry.Name)
+		} else {
+			err = addDataToZip(zipWriter, entry.Data, entry.Name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err = zipWriter.Close(); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp, 0666); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
+
+func addDataToZip(zipWriter *zip.Writer, data []byte, dst string) error {
+	writer, err := zipWriter.CreateHeader(&zip.FileHeader{
+		Name:     dst,
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(data)
+	return err
+}
+
 func addFileToZip(zipWriter *zip.Writer, src, dst string) error {
 	fileToZip, err := os.Open(src)
 	if err != nil {
@@ -143,7 +200,7 @@ func addFileToZip(zipWriter *zip.Writer, src, dst string) error {
 	if err != nil {
 		return err
 	}
-	header.Name = filepath.Base(dst)
+	header.Name = dst
 
 	// Change to deflate to gain better compression
 	header.Method = zip.Deflate
@@ -172,3 +229,28 @@ func FileNameWithoutExt(path string) string {
 	}
 	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
 }
+
+// WriteFileAtomic writes data to the file like os.WriteFile, but through a temporary file
+// in the same directory, which is renamed to the path once it's complete. Thus, readers
+// never see a partially written file, even if the process crashes.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp)
+	if _, err = f.Write(data); err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
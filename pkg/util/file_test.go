@@ -1,7 +1,10 @@
 package util
 
 import (
+	"archive/zip"
+	"io"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
@@ -66,3 +69,66 @@ func TestFindLogFiles(t *testing.T) {
 		}
 	}
 }
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.conf")
+	for _, content := range []string{"first", "second"} {
+		if err := WriteFileAtomic(path, []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+		if b, err := os.ReadFile(path); err != nil || string(b) != content {
+			t.Errorf("Expected: %v, got: %v", content, string(b))
+		}
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("Expected: %v, got: %v", 1, len(entries))
+	}
+}
+
+func TestWriteZip(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "a.log")
+	if err := os.WriteFile(src, []byte("from file"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(dir, "test.zip")
+	err := WriteZip(filename, []ZipEntry{
+		{Name: "manifest.json", Data: []byte("{}")},
+		{Name: "logs/a/a.log", Path: src},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := zip.OpenReader(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	expected := map[string]string{"manifest.json": "{}", "logs/a/a.log": "from file"}
+	actual := make(map[string]string)
+	for _, f := range r.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual[f.Name] = string(b)
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected: %v, got: %v", expected, actual)
+	}
+
+	// A failed write leaves neither the zip file nor a temporary file
+	failed := filepath.Join(dir, "failed.zip")
+	if err = WriteZip(failed, []ZipEntry{{Name: "missing.log", Path: filepath.Join(dir, "missing.log")}}); err == nil {
+		t.Errorf("Expected: %v, got: %v", "an error", err)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "*failed.zip*")); len(matches) != 0 {
+		t.Errorf("Expected: %v, got: %v", "no file", matches)
+	}
+}
@@ -0,0 +1,68 @@
+package util
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrLocked is returned by LockFile if the lock is held by another process.
+var ErrLocked = errors.New("the file is locked by another process")
+
+// staleLockAge is the age of a lock file considered to be left behind by a crashed
+// process. A held lock is refreshed, so it never expires in use.
+const staleLockAge = 10 * time.Second
+
+// lockRefreshInterval is how often a held lock file is refreshed.
+var lockRefreshInterval = staleLockAge / 4
+
+// LockFile acquires the lock file at the path, waiting up to the timeout for another
+// process to release it. The returned function releases the lock.
+func LockFile(path string, timeout time.Duration) (unlock func(), err error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+		if err == nil {
+			f.WriteString(strconv.Itoa(os.Getpid()))
+			f.Close()
+			done := make(chan struct{})
+			go refreshLock(path, done)
+			var once sync.Once
+			return func() {
+				once.Do(func() {
+					close(done)
+					os.Remove(path)
+				})
+			}, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if fi, err := os.Stat(path); err == nil && time.Since(fi.ModTime()) > staleLockAge {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, ErrLocked
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// refreshLock updates the modification time of the lock file until done is closed, so
+// that a lock held longer than staleLockAge is not taken over by another process.
+func refreshLock(path string, done <-chan struct{}) {
+	ticker := time.NewTicker(lockRefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			now := time.Now()
+			os.Chtimes(path, now, now)
+		}
+	}
+}
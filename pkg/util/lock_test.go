@@ -0,0 +1,53 @@
+package util
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLockFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".lock")
+	unlock, err := LockFile(path, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = LockFile(path, 50*time.Millisecond); !errors.Is(err, ErrLocked) {
+		t.Errorf("Expected: %v, got: %v", ErrLocked, err)
+	}
+	unlock()
+	if unlock, err = LockFile(path, 0); err != nil {
+		t.Fatal(err)
+	}
+	unlock()
+
+	// A lock left behind by a crashed process is taken over
+	if err = os.WriteFile(path, nil, 0666); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Minute)
+	if err = os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if unlock, err = LockFile(path, 0); err != nil {
+		t.Fatal(err)
+	}
+	unlock()
+
+	// A held lock is refreshed, so it's never taken for a stale one
+	lockRefreshInterval = 10 * time.Millisecond
+	defer func() { lockRefreshInterval = staleLockAge / 4 }()
+	if unlock, err = LockFile(path, 0); err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+	if err = os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if _, err = LockFile(path, 0); !errors.Is(err, ErrLocked) {
+		t.Errorf("Expected: %v, got: %v", ErrLocked, err)
+	}
+}
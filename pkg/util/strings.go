@@ -3,6 +3,7 @@ package util
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"strconv"
 	"strings"
 	"unicode/utf8"
 )
@@ -34,3 +35,14 @@ func RandToken(n int) (string, error) {
 	}
 	return hex.EncodeToString(bytes), nil
 }
+
+// UniqueName returns the name, with a number appended if it's already used, and marks
+// the returned name as used.
+func UniqueName(name string, used map[string]bool) string {
+	unique := name
+	for i := 2; used[unique]; i++ {
+		unique = name + "-" + strconv.Itoa(i)
+	}
+	used[unique] = true
+	return unique
+}
@@ -31,3 +31,15 @@ func TestRuneSizeInString(t *testing.T) {
 		t.Errorf("Expected: %v, got: %v", expected, output)
 	}
 }
+
+func TestUniqueName(t *testing.T) {
+	used := make(map[string]bool)
+	expected := []string{"a", "b", "a-2", "a-3"}
+	var output []string
+	for _, name := range []string{"a", "b", "a", "a"} {
+		output = append(output, UniqueName(name, used))
+	}
+	if !reflect.DeepEqual(output, expected) {
+		t.Errorf("Expected: %v, got: %v", expected, output)
+	}
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/hzcrv1911/frpcgui/pkg/diag"
+)
+
+// diagSystem provides the frpc version and WinSW services to the diagnostics.
+type diagSystem struct{}
+
+func (diagSystem) FrpcVersion() (string, error) {
+	return GetFrpcVersion()
+}
+
+// Service returns the status of the WinSW service of the config, with the generated
+// winsw.xml and the logs written by WinSW in the profile directory.
+func (diagSystem) Service(configPath string) (diag.Service, error) {
+	name := ServiceNameOfClient(configPath)
+	status, err := NewWinSWService(name, configPath, "", "", "").Status()
+	if err != nil {
+		return diag.Service{}, err
+	}
+	service := diag.Service{Name: name, Status: status}
+	profileDir, err := GetProfileDirectory(configPath)
+	if err != nil {
+		return service, nil
+	}
+	service.Files = append(service.Files, filepath.Join(profileDir, "winsw.xml"))
+	if logs, err := filepath.Glob(filepath.Join(profileDir, "logs", "*.log")); err == nil {
+		service.Files = append(service.Files, logs...)
+	}
+	return service, nil
+}
+
+// ExportDiagnostics writes the diagnostics bundle of the configs to a zip file.
+func ExportDiagnostics(ctx context.Context, filename string, sources []diag.Source) (*diag.Manifest, error) {
+	return diag.Export(ctx, filename, sources, diagSystem{})
+}
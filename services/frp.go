@@ -1,7 +1,6 @@
 package services
 
 import (
-	"os"
 	"path/filepath"
 	"strings"
 
@@ -9,61 +8,14 @@ import (
 	"github.com/hzcrv1911/frpcgui/pkg/util"
 )
 
-// VerifyClientConfig validates the frp client config file
-// For WinSW integration, we do basic validation without importing frp packages
+// VerifyClientConfig validates the frp client config file.
+// All errors found in the config are reported at once.
 func VerifyClientConfig(path string) error {
-	// Basic file existence and format validation
-	if _, err := os.Stat(path); err != nil {
-		return err
-	}
-
-	// Try to parse the config file to validate its format
-	_, err := config.UnmarshalClientConf(path)
-	if err != nil {
-		return err
-	}
-
-	// Additional validation for essential fields
-	conf, err := config.UnmarshalClientConf(path)
+	ds, err := config.ValidateClientConf(path)
 	if err != nil {
 		return err
 	}
-
-	// Check if server address is specified
-	if conf.ServerAddress == "" {
-		return util.NewError("server address is required")
-	}
-
-	// Check if server port is specified
-	if conf.ServerPort <= 0 || conf.ServerPort > 65535 {
-		return util.NewError("invalid server port")
-	}
-
-	// Check if at least one proxy is defined
-	if len(conf.Proxies) == 0 {
-		return util.NewError("at least one proxy must be defined")
-	}
-
-	// Validate each proxy
-	for _, proxy := range conf.Proxies {
-		if proxy.Name == "" {
-			return util.NewError("proxy name is required")
-		}
-		if proxy.Type == "" {
-			return util.NewError("proxy type is required")
-		}
-		// For non-visitor proxies, check local IP and port
-		if !proxy.IsVisitor() {
-			if proxy.LocalIP == "" {
-				proxy.LocalIP = "127.0.0.1"
-			}
-			if proxy.LocalPort == "" {
-				return util.NewError("local port is required for proxy: " + proxy.Name)
-			}
-		}
-	}
-
-	return nil
+	return ds.Err()
 }
 
 // GetFrpcVersion returns the version of the frpc.exe
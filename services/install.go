@@ -10,11 +10,42 @@ import (
 	"strings"
 
 	"github.com/hzcrv1911/frpcgui/pkg/config"
+	"github.com/hzcrv1911/frpcgui/pkg/sec"
+	"github.com/hzcrv1911/frpcgui/pkg/util"
 )
 
+// profileConfigName is the name of the config file run by frpc in a profile directory.
+const profileConfigName = "frpc.conf"
+
+// Vault opens the secrets sealed in config files, when the configs are rendered for
+// frpc. It's nil if the secrets are stored in plain text.
+var Vault *sec.Vault
+
 // GetProfileDirectory returns the profile directory path for a config
-// Format: profiles/R_<server_ip>_<port> (dots and colons replaced with underscores)
+// Format: profiles/R_<id>, where id is the config file name without extension
 func GetProfileDirectory(configPath string) (string, error) {
+	profileDir := profileDirectory(configPath)
+	if _, err := os.Stat(profileDir); err == nil {
+		return profileDir, nil
+	}
+	// Services installed by older versions run from a directory named after the server
+	if legacyDir, err := legacyProfileDirectory(configPath); err == nil {
+		if _, err := os.Stat(filepath.Join(legacyDir, "winsw.xml")); err == nil {
+			return legacyDir, nil
+		}
+	}
+	return profileDir, nil
+}
+
+// profileDirectory returns the profile directory of a config, keyed by its id so that
+// configs connecting to the same server don't share a directory
+func profileDirectory(configPath string) string {
+	return filepath.Join("profiles", "R_"+util.FileNameWithoutExt(configPath))
+}
+
+// legacyProfileDirectory returns the profile directory used by older versions
+// Format: profiles/R_<server_ip>_<port> (dots and colons replaced with underscores)
+func legacyProfileDirectory(configPath string) (string, error) {
 	// Load config to get server address and port
 	cfg, err := config.UnmarshalClientConf(configPath)
 	if err != nil {
@@ -56,10 +87,7 @@ func copyFile(src, dst string) error {
 // prepareProfileDirectory creates profile directory and copies assets
 func prepareProfileDirectory(configPath string) (string, error) {
 	// Get profile directory path
-	profileDir, err := GetProfileDirectory(configPath)
-	if err != nil {
-		return "", err
-	}
+	profileDir := profileDirectory(configPath)
 
 	// Create profile directory
 	if err := os.MkdirAll(profileDir, os.ModePerm); err != nil {
@@ -110,21 +138,33 @@ func prepareProfileDirectory(configPath string) (string, error) {
 	}
 
 	// Copy config file to profile directory
-	// Determine config file name based on extension
-	configExt := filepath.Ext(configPath)
-	var configFileName string
-	if configExt == ".toml" {
-		configFileName = "frpc.toml"
-	} else {
-		configFileName = "frpc.ini"
+	if err := writeProfileConfig(configPath, profileConfigFile(profileDir)); err != nil {
+		return "", err
 	}
 
-	configDst := filepath.Join(profileDir, configFileName)
-	if err := copyFile(configPath, configDst); err != nil {
-		return "", fmt.Errorf("failed to copy config file: %v", err)
+	return profileDir, nil
+}
+
+// profileConfigFile returns the config file run by frpc in the profile directory. frpc tells
+// the format of the config by its content, so the file has the same name in all formats.
+// Services installed by older versions keep the name given to the file then.
+func profileConfigFile(profileDir string) string {
+	for _, name := range []string{"frpc.toml", "frpc.ini"} {
+		if path := filepath.Join(profileDir, name); util.FileExists(path) {
+			return path
+		}
 	}
+	return filepath.Join(profileDir, profileConfigName)
+}
 
-	return profileDir, nil
+// writeProfileConfig writes the config used by frpc to dst. The config is rendered
+// with the proxies of its included files inlined, as their relative paths don't
+// resolve from the profile directory, and with the secrets sealed by the vault opened.
+func writeProfileConfig(configPath, dst string) error {
+	if err := config.RenderClientConf(configPath, dst, Vault); err != nil {
+		return fmt.Errorf("failed to render config file: %w", err)
+	}
+	return nil
 }
 
 // InstallWinSWService installs the WinSW service without starting it
@@ -164,15 +204,8 @@ func InstallWinSWService(name string, configPath string, manual bool) error {
 	winSWPath := filepath.Join(profileDir, "winsw.exe")
 	frpcPath := filepath.Join(profileDir, "frpc.exe")
 
-	// Use config file in profile directory
-	configExt := filepath.Ext(configPath)
-	var configFileName string
-	if configExt == ".toml" {
-		configFileName = "frpc.toml"
-	} else {
-		configFileName = "frpc.ini"
-	}
-	profileConfigPath := filepath.Join(profileDir, configFileName)
+	// Get config file in profile directory
+	profileConfigPath := profileConfigFile(profileDir)
 
 	// Create log directory in profile directory
 	logPath := filepath.Join(profileDir, "logs")
@@ -243,14 +276,7 @@ func StartWinSWService(configPath string) error {
 	logPath := filepath.Join(profileDir, "logs")
 
 	// Get config file in profile directory
-	configExt := filepath.Ext(configPath)
-	var configFileName string
-	if configExt == ".toml" {
-		configFileName = "frpc.toml"
-	} else {
-		configFileName = "frpc.ini"
-	}
-	profileConfigPath := filepath.Join(profileDir, configFileName)
+	profileConfigPath := profileConfigFile(profileDir)
 
 	wsService := NewWinSWService(serviceName, profileConfigPath, winSWPath, "", logPath)
 
@@ -286,14 +312,7 @@ func StopWinSWService(configPath string) error {
 	logPath := filepath.Join(profileDir, "logs")
 
 	// Get config file in profile directory
-	configExt := filepath.Ext(configPath)
-	var configFileName string
-	if configExt == ".toml" {
-		configFileName = "frpc.toml"
-	} else {
-		configFileName = "frpc.ini"
-	}
-	profileConfigPath := filepath.Join(profileDir, configFileName)
+	profileConfigPath := profileConfigFile(profileDir)
 
 	wsService := NewWinSWService(serviceName, profileConfigPath, winSWPath, "", logPath)
 
@@ -341,14 +360,7 @@ func UninstallService(configPath string, wait bool) error {
 	logPath := filepath.Join(profileDir, "logs")
 
 	// Get config file in profile directory
-	configExt := filepath.Ext(configPath)
-	var configFileName string
-	if configExt == ".toml" {
-		configFileName = "frpc.toml"
-	} else {
-		configFileName = "frpc.ini"
-	}
-	profileConfigPath := filepath.Join(profileDir, configFileName)
+	profileConfigPath := profileConfigFile(profileDir)
 
 	// Create WinSW service
 	wsService := NewWinSWService(serviceName, profileConfigPath, winSWPath, "", logPath)
@@ -386,7 +398,8 @@ func cleanupProfileArtifacts(profileDir string) {
 			continue
 		}
 
-		if strings.EqualFold(filepath.Ext(name), ".conf") {
+		// The config run by frpc holds the secrets in plain text, unlike the configs of users
+		if strings.EqualFold(filepath.Ext(name), ".conf") && !strings.EqualFold(name, profileConfigName) {
 			continue
 		}
 
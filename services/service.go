@@ -1,12 +1,21 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"path/filepath"
+	"time"
 
+	"github.com/hzcrv1911/frpcgui/pkg/config"
+	"github.com/hzcrv1911/frpcgui/pkg/ipc"
+	"github.com/hzcrv1911/frpcgui/pkg/sec"
 	"github.com/hzcrv1911/frpcgui/pkg/util"
 )
 
+// reloadTimeout limits the time of reloading a config through the admin API.
+const reloadTimeout = 10 * time.Second
+
 func ServiceNameOfClient(configPath string) string {
 	// Use the config filename without extension as service name
 	// This makes the service name readable and consistent with the config file
@@ -18,9 +27,61 @@ func DisplayNameOfClient(name string) string {
 	return "FRPCGUI: " + name
 }
 
-// ReloadService restarts the WinSW-managed frp service
-// which triggers hot-reloading of frp configuration.
+// ReloadService applies the changes of the config to the running frpc. If the admin API
+// of frpc is available, the proxies and visitors are reloaded through it without
+// reconnecting to the server. Otherwise, the WinSW-managed service is restarted.
 func ReloadService(configPath string) error {
+	err := reloadByAdmin(configPath)
+	if err == nil {
+		return nil
+	}
+	// A restart can't open the secrets either, and must not hand them sealed to frpc
+	if errors.Is(err, sec.ErrVaultKey) {
+		return err
+	}
+	return restartService(configPath)
+}
+
+// reloadByAdmin pushes the config to frpc through the admin API and reloads it. It fails
+// if the admin API is disabled or unreachable, or the changes need a reconnection.
+func reloadByAdmin(configPath string) error {
+	profileDir, err := GetProfileDirectory(configPath)
+	if err != nil {
+		return err
+	}
+	// The running frpc uses the copy in the profile directory
+	running, err := config.UnmarshalClientConf(profileConfigFile(profileDir))
+	if err != nil {
+		return err
+	}
+	if running.AdminPort == 0 {
+		return fmt.Errorf("admin api is disabled")
+	}
+	// The secrets are opened, or the error is returned if they are sealed without a vault
+	content, err := config.RenderClientConfContent(configPath, Vault)
+	if err != nil {
+		return fmt.Errorf("failed to render config file: %w", err)
+	}
+	conf, err := config.UnmarshalClientConf(content)
+	if err != nil {
+		return err
+	}
+	if !config.Diff(running, conf).Reloadable() {
+		return fmt.Errorf("the changes need a reconnection")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), reloadTimeout)
+	defer cancel()
+	// frpc writes the new content to its config file before reloading it
+	client := ipc.NewAdminClient(running.AdminAddr, running.AdminPort, running.AdminUser, running.AdminPwd)
+	if err = client.PutConfig(ctx, content); err != nil {
+		return err
+	}
+	return client.Reload(ctx)
+}
+
+// restartService refreshes the config in the profile directory and restarts the
+// WinSW-managed frp service.
+func restartService(configPath string) error {
 	// Check if WinSW is available
 	if !IsWinSWAvailable() {
 		return fmt.Errorf("WinSW executable not found")
@@ -36,6 +97,15 @@ func ReloadService(configPath string) error {
 		return err
 	}
 
+	// Refresh the config used by frpc
+	profileDir, err := GetProfileDirectory(configPath)
+	if err != nil {
+		return err
+	}
+	if err = writeProfileConfig(configPath, profileConfigFile(profileDir)); err != nil {
+		return err
+	}
+
 	// Create log directory
 	logPath := filepath.Join(filepath.Dir(configPath), "logs")
 
@@ -317,3 +317,34 @@ func notifyStateToConfigState(s uint32) consts.ConfigState {
 		return consts.ConfigStateUnknown
 	}
 }
+
+// QueryState returns the current state of the service of the given config.
+func QueryState(configPath string) consts.ConfigState {
+	if IsWinSWAvailable() {
+		winSWPath, err := GetWinSWPath()
+		if err != nil {
+			return consts.ConfigStateNotInstalled
+		}
+		logPath := filepath.Join(filepath.Dir(configPath), "logs")
+		wsService := NewWinSWService(ServiceNameOfClient(configPath), configPath, winSWPath, "", logPath)
+		status, err := wsService.Status()
+		if err != nil {
+			return consts.ConfigStateNotInstalled
+		}
+		return winSWStatusToConfigState(status)
+	}
+	m, err := serviceManager()
+	if err != nil {
+		return consts.ConfigStateUnknown
+	}
+	service, err := m.OpenService(ServiceNameOfClient(configPath))
+	if err != nil {
+		return consts.ConfigStateNotInstalled
+	}
+	defer service.Close()
+	status, err := service.Query()
+	if err != nil {
+		return consts.ConfigStateUnknown
+	}
+	return svcStateToConfigState(uint32(status.State))
+}
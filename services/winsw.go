@@ -21,6 +21,7 @@ type WinSWConfig struct {
 	Desc        string   `xml:"description"`
 	Executable  string   `xml:"executable"`
 	Arguments   string   `xml:"arguments"`
+	WorkingDir  string   `xml:"workingdirectory,omitempty"`
 	LogPath     string   `xml:"log>directory"`
 	LogMode     string   `xml:"log>mode"`
 	StopTimeout string   `xml:"stoptimeout,omitempty"`
@@ -68,13 +69,15 @@ func (ws *WinSWService) GenerateConfigFile() (string, error) {
 		logPath = ws.LogPath
 	}
 
-	// Create WinSW configuration
+	// Create WinSW configuration. The working directory is the directory of the config
+	// run by frpc, which is the profile directory.
 	config := WinSWConfig{
 		ID:          ws.ServiceName,
 		Name:        ws.ServiceName,
 		Desc:        "FRPC Runtime Service(" + ws.ServiceName + ")",
 		Executable:  frpcPath,
 		Arguments:   fmt.Sprintf("-c %s", configPath),
+		WorkingDir:  filepath.Dir(configPath),
 		LogPath:     logPath,
 		LogMode:     "roll",
 		StopTimeout: "15s",
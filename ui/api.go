@@ -0,0 +1,426 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/samber/lo"
+
+	"github.com/hzcrv1911/frpcgui/pkg/api"
+	"github.com/hzcrv1911/frpcgui/pkg/config"
+	"github.com/hzcrv1911/frpcgui/pkg/consts"
+	"github.com/hzcrv1911/frpcgui/pkg/res"
+	"github.com/hzcrv1911/frpcgui/pkg/util"
+	"github.com/hzcrv1911/frpcgui/services"
+)
+
+// localAPI serves the local API on the configs of the config page.
+var localAPI *apiService
+
+// apiService is the backend of the local API. The requests are served in their own
+// goroutines, so the configs are only touched on the UI thread, while the services are
+// controlled in the goroutines of the requests.
+type apiService struct {
+	cp     *ConfPage
+	server *api.Server
+}
+
+func newAPIService(cp *ConfPage) *apiService {
+	return &apiService{cp: cp}
+}
+
+// restart serves the API with the current settings, replacing the running server.
+func (s *apiService) restart() error {
+	s.close()
+	if !appConf.API.Enabled {
+		return nil
+	}
+	l, err := api.Listen(appConf.API.Address)
+	if err != nil {
+		return err
+	}
+	s.server = api.NewServer(appConf.API.Token, s)
+	go s.server.Serve(l)
+	return nil
+}
+
+func (s *apiService) close() {
+	if s.server != nil {
+		s.server.Close()
+		s.server = nil
+	}
+}
+
+// publishState sends the new state of the config at the path to the event streams.
+func (s *apiService) publishState(path string) {
+	if s.server == nil {
+		return
+	}
+	if conf, found := lo.Find(getConfList(), func(item *Conf) bool { return item.Path == path }); found {
+		s.server.Publish(api.Event{Type: "state", ID: conf.ID, State: conf.State.String()})
+	}
+}
+
+// do runs the function on the UI thread, and waits for its result.
+func (s *apiService) do(ctx context.Context, f func() error) error {
+	done := make(chan error, 1)
+	s.cp.Synchronize(func() {
+		done <- f()
+	})
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *apiService) find(id string) (*Conf, error) {
+	conf, found := lo.Find(getConfList(), func(item *Conf) bool { return item.ID == id })
+	if !found {
+		return nil, fmt.Errorf("config %s: %w", id, api.ErrNotFound)
+	}
+	return conf, nil
+}
+
+// setState changes the state of the config, showing it if it's the current config.
+func (s *apiService) setState(conf *Conf, state consts.ConfigState) {
+	setConfState(conf, state)
+	if getCurrentConf() == conf {
+		s.cp.detailView.panelView.setState(state)
+	}
+}
+
+func (s *apiService) Configs(ctx context.Context) (confs []api.Config, err error) {
+	err = s.do(ctx, func() error {
+		confs = lo.Map(getConfList(), func(conf *Conf, i int) api.Config {
+			return api.Config{ID: conf.ID, Name: conf.Name(), State: conf.State.String()}
+		})
+		return nil
+	})
+	return
+}
+
+// Proxies returns the proxies of the config. The status of the proxies is only tracked
+// for the config shown in the proxy view.
+func (s *apiService) Proxies(ctx context.Context, id string) (proxies []api.Proxy, err error) {
+	err = s.do(ctx, func() error {
+		conf, err := s.find(id)
+		if err != nil {
+			return err
+		}
+		rows := lo.Map(conf.Data.Proxies, func(p *config.Proxy, i int) *ProxyRow { return NewProxyRow(p) })
+		if pv := s.cp.detailView.proxyView; pv.model != nil && pv.model.conf == conf && pv.model.data == conf.Data {
+			if pv.tracker != nil {
+				pv.tracker.Lock()
+				defer pv.tracker.Unlock()
+			}
+			rows = pv.model.items
+		}
+		proxies = lo.Map(rows, func(row *ProxyRow, i int) api.Proxy {
+			return api.Proxy{
+				Name:       row.Name,
+				Type:       row.Type,
+				Disabled:   row.Disabled,
+				State:      row.State.String(),
+				Error:      row.Error,
+				RemoteAddr: row.RemoteAddr,
+			}
+		})
+		return nil
+	})
+	return
+}
+
+// Start starts the service of the config, installing it first if needed.
+func (s *apiService) Start(ctx context.Context, id string) error {
+	var conf *Conf
+	var oldState consts.ConfigState
+	var name, logFile string
+	var manual bool
+	err := s.do(ctx, func() (err error) {
+		if conf, err = s.find(id); err != nil {
+			return err
+		}
+		switch conf.State {
+		case consts.ConfigStateStarted:
+			conf = nil
+			return nil
+		case consts.ConfigStateStarting, consts.ConfigStateStopping:
+			return fmt.Errorf("%w: the config is currently locked", api.ErrInvalid)
+		}
+		if !util.FileExists(conf.Path) {
+			return fmt.Errorf("config %s: %w", id, api.ErrNotFound)
+		}
+		name, logFile, manual = conf.Name(), conf.Data.LogFile, !conf.Data.AutoStart()
+		oldState = conf.State
+		s.setState(conf, consts.ConfigStateStarting)
+		return nil
+	})
+	if err != nil || conf == nil {
+		return err
+	}
+	err = func() error {
+		if err := services.VerifyClientConfig(conf.Path); err != nil {
+			return fmt.Errorf("%w: %v", api.ErrInvalid, err)
+		}
+		// Ensure log directory is valid
+		if logFile != "" && logFile != "console" {
+			if err := os.MkdirAll(filepath.Dir(logFile), os.ModePerm); err != nil {
+				return err
+			}
+		}
+		if oldState == consts.ConfigStateNotInstalled || oldState == consts.ConfigStateUnknown {
+			return services.InstallService(name, conf.Path, manual)
+		}
+		return services.StartWinSWService(conf.Path)
+	}()
+	if err != nil {
+		s.cp.Synchronize(func() {
+			if conf.State == consts.ConfigStateStarting {
+				s.setState(conf, oldState)
+			}
+		})
+	}
+	return err
+}
+
+// Stop stops the service of the config, keeping it installed.
+func (s *apiService) Stop(ctx context.Context, id string) error {
+	var conf *Conf
+	err := s.do(ctx, func() (err error) {
+		if conf, err = s.find(id); err != nil {
+			return err
+		}
+		if conf.State != consts.ConfigStateStarted {
+			return fmt.Errorf("%w: the service is not running", api.ErrInvalid)
+		}
+		s.setState(conf, consts.ConfigStateStopping)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err = services.StopWinSWService(conf.Path); err != nil {
+		s.cp.Synchronize(func() {
+			if conf.State == consts.ConfigStateStopping {
+				s.setState(conf, consts.ConfigStateStarted)
+			}
+		})
+	}
+	return err
+}
+
+func (s *apiService) Reload(ctx context.Context, id string) error {
+	var path string
+	err := s.do(ctx, func() error {
+		conf, err := s.find(id)
+		if err != nil {
+			return err
+		}
+		if conf.State != consts.ConfigStateStarted {
+			return fmt.Errorf("%w: the service is not running", api.ErrInvalid)
+		}
+		path = conf.Path
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return services.ReloadService(path)
+}
+
+// SetProxyDisabled enables or disables the proxy, and reloads the running service.
+func (s *apiService) SetProxyDisabled(ctx context.Context, id, name string, disabled bool) error {
+	var reload string
+	err := s.do(ctx, func() error {
+		conf, err := s.find(id)
+		if err != nil {
+			return err
+		}
+		if conf.editing {
+			return fmt.Errorf("%w: the config is being edited", api.ErrInvalid)
+		}
+		proxy, found := lo.Find(conf.Data.Proxies, func(p *config.Proxy) bool { return p.Name == name })
+		if !found {
+			return fmt.Errorf("proxy %s: %w", name, api.ErrNotFound)
+		}
+		if proxy.Disabled == disabled {
+			return nil
+		}
+		proxy.Disabled = disabled
+		// We can't disable all proxies
+		if conf.Data.CountStart() == 0 {
+			proxy.Disabled = !disabled
+			return fmt.Errorf("%w: at least one proxy must be enabled", api.ErrInvalid)
+		}
+		if err = conf.Save(); err != nil {
+			proxy.Disabled = !disabled
+			return err
+		}
+		if pv := s.cp.detailView.proxyView; pv.model != nil && pv.model.conf == conf {
+			if i := slices.IndexFunc(pv.model.items, func(row *ProxyRow) bool { return row.Proxy == proxy }); i >= 0 {
+				if disabled {
+					if pv.tracker != nil {
+						pv.tracker.Lock()
+					}
+					pv.resetProxyState(i)
+					if pv.tracker != nil {
+						pv.tracker.Unlock()
+					}
+				} else {
+					defer pv.model.PublishRowEdited(i)
+				}
+				pv.model.PublishRowChanged(i)
+				pv.switchToggleAction()
+			}
+		}
+		if conf.State == consts.ConfigStateStarted {
+			reload = conf.Path
+		}
+		return nil
+	})
+	if err != nil || reload == "" {
+		return err
+	}
+	return services.ReloadService(reload)
+}
+
+// Import imports the configs in the file. The content is subject to the bundle policy, and
+// a config of the same name as an existing one replaces it. All the configs are checked
+// before any is saved, so an invalid file imports nothing.
+func (s *apiService) Import(ctx context.Context, filename string, data []byte) (results []api.ImportResult, err error) {
+	var reload []string
+	err = s.do(ctx, func() error {
+		files := []config.BundleFile{{Name: filename, Data: data}}
+		if config.IsZip(data) {
+			bundle, err := appConf.Bundles.OpenBundle(data)
+			if err != nil {
+				return fmt.Errorf("%w: %v", api.ErrInvalid, err)
+			}
+			files = lo.Filter(bundle.Files, func(file config.BundleFile, i int) bool {
+				return slices.Contains(res.SupportedConfigFormats, strings.ToLower(filepath.Ext(file.Name)))
+			})
+		} else if err := appConf.Bundles.CheckUnsigned(); err != nil {
+			return fmt.Errorf("%w: %v", api.ErrInvalid, err)
+		}
+		items := make([]importItem, len(files))
+		for i, file := range files {
+			item, err := s.checkImport(file.Name, file.Data)
+			if err != nil {
+				return fmt.Errorf("%s: %w", file.Name, err)
+			}
+			if slices.ContainsFunc(items[:i], func(other importItem) bool { return other.data.Name() == item.data.Name() }) {
+				return fmt.Errorf("%s: %w: the config \"%s\" is imported more than once", file.Name, api.ErrInvalid, item.data.Name())
+			}
+			items[i] = item
+		}
+		for i, item := range items {
+			result, conf, err := s.importData(item)
+			// The configs saved before a failure are reloaded all the same
+			if conf != nil && conf.State == consts.ConfigStateStarted {
+				reload = append(reload, conf.Path)
+			}
+			if err != nil {
+				return fmt.Errorf("%s: %w", files[i].Name, err)
+			}
+			results = append(results, result)
+		}
+		return nil
+	})
+	for _, path := range reload {
+		if reloadErr := services.ReloadService(path); reloadErr != nil && err == nil {
+			err = reloadErr
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+// importItem is an imported config, checked before it's saved.
+type importItem struct {
+	src  []byte
+	data *config.ClientConfig
+	// conf is the existing config replaced by the import, if any.
+	conf *Conf
+}
+
+// checkImport parses the imported config content and finds the config it replaces,
+// without saving anything.
+func (s *apiService) checkImport(filename string, src []byte) (importItem, error) {
+	data, err := config.UnmarshalClientConf(src)
+	if err != nil {
+		return importItem{}, fmt.Errorf("%w: %v", api.ErrInvalid, err)
+	}
+	if data.Name() == "" {
+		data.ClientCommon.Name = util.FileNameWithoutExt(filename)
+	}
+	if data.Name() == "" {
+		return importItem{}, fmt.Errorf("%w: the config has no name", api.ErrInvalid)
+	}
+	if err = openSecrets(data.Name(), data); err != nil {
+		return importItem{}, err
+	}
+	item := importItem{src: src, data: data}
+	if conf, found := lo.Find(s.cp.confView.model.List(), func(item *Conf) bool { return item.Name() == data.Name() }); found {
+		if conf.editing {
+			return importItem{}, fmt.Errorf("%w: the config is being edited", api.ErrInvalid)
+		}
+		// The log file is always decided by the path of the config
+		data.LogFile = conf.Data.LogFile
+		item.conf = conf
+	}
+	return item, nil
+}
+
+// importData saves a checked config, replacing the config of the same name. A config
+// saved without its merge base is returned along with the error.
+func (s *apiService) importData(item importItem) (api.ImportResult, *Conf, error) {
+	conf, data := item.conf, item.data
+	if conf != nil {
+		oldData := conf.Data
+		conf.Data = data
+		if err := conf.Save(); err != nil {
+			conf.Data = oldData
+			return api.ImportResult{}, nil, err
+		}
+		if conf == getCurrentConf() {
+			setCurrentConf(conf)
+		}
+	} else {
+		conf = NewConf("", data)
+		if err := conf.Save(); err != nil {
+			return api.ImportResult{}, nil, err
+		}
+		s.cp.confView.model.Add(conf)
+	}
+	if err := conf.saveBase(item.src); err != nil {
+		return api.ImportResult{}, conf, err
+	}
+	result := api.ImportResult{ID: conf.ID, Name: conf.Name(), Created: item.conf == nil}
+	for _, d := range data.Validate() {
+		result.Problems = append(result.Problems, d.String())
+	}
+	return result, conf, nil
+}
+
+// Export returns the file of the config as stored, so the secrets stay sealed if the
+// vault is enabled.
+func (s *apiService) Export(ctx context.Context, id string) (filename string, data []byte, err error) {
+	err = s.do(ctx, func() error {
+		conf, err := s.find(id)
+		if err != nil {
+			return err
+		}
+		filename = conf.Name() + conf.Data.Ext()
+		data, err = confStore.Load(conf.ID)
+		return err
+	})
+	return
+}
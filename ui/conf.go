@@ -1,6 +1,8 @@
 package ui
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"os"
@@ -29,50 +31,33 @@ const (
 
 // Conf contains all data of a config
 type Conf struct {
+	// ID of the config in the store, which never changes
+	ID string
 	// Path of the config file
 	Path string
 	// State of service
 	State consts.ConfigState
 	Data  *config.ClientConfig
-}
 
-// PathOfConfInProfile returns the config path in profile directory (R_<IP>_<port>/)
-func PathOfConfInProfile(data *config.ClientConfig, filename string) string {
-	if data == nil || data.ServerAddress == "" {
-		// If no server info, generate a temporary path (should be updated later)
-		return filepath.Join("profiles", "temp", filename)
-	}
-	// Generate directory name: R_<ip>_<port>
-	// Replace dots and colons with underscores
-	serverAddr := strings.ReplaceAll(data.ServerAddress, ".", "_")
-	serverAddr = strings.ReplaceAll(serverAddr, ":", "_")
-	dirName := fmt.Sprintf("R_%s_%d", serverAddr, data.ServerPort)
-	return filepath.Join("profiles", dirName, filename)
+	// sum is the checksum of the files when the config was last loaded or saved, which
+	// tells the changes made outside the program apart. A nil sum is never checked.
+	sum []byte
+	// editing is set while the config is edited, so that it isn't reloaded under the editor
+	editing bool
 }
 
-func NewConf(path string, data *config.ClientConfig) *Conf {
-	if path == "" {
-		// Use config name as filename, fallback to random token if no name
-		filename := data.Name()
-		if filename == "" {
-			token, err := util.RandToken(16)
-			if err != nil {
-				panic(err)
-			}
-			filename = token
-		}
-		// Sanitize filename (remove invalid characters)
-		filename = strings.Map(func(r rune) rune {
-			if strings.ContainsRune(`<>:"/\|?*`, r) {
-				return '_'
-			}
-			return r
-		}, filename)
-		// Use profile directory structure for new configs
-		path = PathOfConfInProfile(data, filename+".conf")
+// errEditConflict is returned by Save if the config file was changed outside the program
+// since the config was loaded.
+var errEditConflict = errors.New("the config file was changed outside the program")
+
+// NewConf returns a config of the id in the store. An empty id creates a new config.
+func NewConf(id string, data *config.ClientConfig) *Conf {
+	if id == "" {
+		id = config.NewConfigID()
 	}
 	return &Conf{
-		Path:  path,
+		ID:    id,
+		Path:  confStore.Path(id),
 		State: consts.ConfigStateNotInstalled, // Default to not installed, tracker will update actual state
 		Data:  data,
 	}
@@ -82,6 +67,29 @@ func (conf *Conf) Name() string {
 	return conf.Data.Name()
 }
 
+// baseFile returns the path of the content last imported into the config, which is
+// the common base of a three-way merge with a newer import.
+func (conf *Conf) baseFile() string {
+	return conf.Path + ".base"
+}
+
+// saveBase keeps the content imported into the config as the base of the next merge.
+func (conf *Conf) saveBase(src []byte) error {
+	return config.SaveBase(confStore, conf.ID, sealContent(src))
+}
+
+// merge merges a newer import of the config with the config, keeping the local changes.
+// The content imported last time tells the local changes apart from the remote ones.
+func (conf *Conf) merge(update *config.ClientConfig) (*config.ClientConfig, []config.MergeConflict) {
+	var base *config.ClientConfig
+	if b, err := config.LoadBase(confStore, conf.ID); err == nil {
+		if base, err = config.UnmarshalClientConf(b); err == nil {
+			base.OpenSecrets(vault)
+		}
+	}
+	return config.Merge(base, conf.Data, update)
+}
+
 // Delete config will remove service, logs, config file in disk
 func (conf *Conf) Delete() error {
 	// Delete service
@@ -94,68 +102,92 @@ func (conf *Conf) Delete() error {
 		util.DeleteFiles(logs)
 	}
 	// Delete config file
-	if err := os.Remove(conf.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+	if err := confStore.Delete(conf.ID); err != nil {
 		return err
 	}
-
-	// Try to remove the profile directory if it's empty
-	// This only applies if the config is in a R_* subdirectory
-	configDir := filepath.Dir(conf.Path)
-	if filepath.Base(filepath.Dir(configDir)) == "profiles" && len(filepath.Base(configDir)) > 2 && filepath.Base(configDir)[:2] == "R_" {
-		// This is a profile subdirectory, try to remove it (will fail if not empty)
-		os.Remove(configDir)
+	os.Remove(conf.baseFile())
+	if _, ok := appConf.Subscriptions[conf.ID]; ok {
+		delete(appConf.Subscriptions, conf.ID)
+		saveAppConfig()
 	}
-
 	return nil
 }
 
-// Save config to the disk. The config will be completed before saving
-func (conf *Conf) Save() error {
-	// Use config name as filename
-	filename := conf.Data.Name()
-	if filename == "" {
-		// Keep existing filename if no name is set
-		filename = util.FileNameWithoutExt(conf.Path)
-	}
-	// Sanitize filename
-	filename = strings.Map(func(r rune) rune {
-		if strings.ContainsRune(`<>:"/\|?*`, r) {
-			return '_'
-		}
-		return r
-	}, filename)
-
-	expectedPath := PathOfConfInProfile(conf.Data, filename+".conf")
-
-	// If path needs to be updated (e.g., from temp or server info changed)
-	if conf.Path != expectedPath {
-		oldPath := conf.Path
-		conf.Path = expectedPath
-
-		// Remove old file if it exists in a different location
-		if oldPath != "" && oldPath != expectedPath {
-			if _, err := os.Stat(oldPath); err == nil {
-				os.Remove(oldPath)
-				// Try to remove old directory if empty
-				oldDir := filepath.Dir(oldPath)
-				os.Remove(oldDir)
+// diskSum returns the checksum of the config file of the id, along with the files it
+// includes, so that the changes made to included proxies are noticed too.
+func diskSum(id string) ([]byte, error) {
+	b, err := confStore.Load(id)
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	h.Write(b)
+	if data, err := config.UnmarshalClientConf(b); err == nil {
+		if files, err := data.IncludeFiles(confStore.Path(id)); err == nil {
+			for _, file := range files {
+				h.Write([]byte(file))
+				if b, err := os.ReadFile(file); err == nil {
+					h.Write(b)
+				}
 			}
 		}
 	}
+	return h.Sum(nil), nil
+}
 
-	// Ensure the directory exists before saving
-	configDir := filepath.Dir(conf.Path)
-	if err := os.MkdirAll(configDir, os.ModePerm); err != nil {
-		return err
+// updateSum records the checksum of the config files as they're stored now.
+func (conf *Conf) updateSum() {
+	if sum, err := diskSum(conf.ID); err == nil {
+		conf.sum = sum
 	}
+}
 
-	logPath, err := filepath.Abs(filepath.Join("logs", util.FileNameWithoutExt(conf.Path)+".log"))
+// changedOnDisk reports whether the config file, or a file it includes, was changed since
+// the config was last loaded or saved. A removed file isn't a change, as saving brings it back.
+func (conf *Conf) changedOnDisk() bool {
+	if conf.sum == nil {
+		return false
+	}
+	sum, err := diskSum(conf.ID)
+	return err == nil && !bytes.Equal(sum, conf.sum)
+}
+
+// Save config to the disk. The config will be completed before saving.
+// If the file was changed outside the program, errEditConflict is returned
+// instead, unless the sum of the config is cleared.
+func (conf *Conf) Save() error {
+	if conf.changedOnDisk() {
+		return errEditConflict
+	}
+	logPath, err := filepath.Abs(filepath.Join("logs", conf.ID+".log"))
 	if err != nil {
 		return err
 	}
 	conf.Data.Complete(false)
 	conf.Data.LogFile = filepath.ToSlash(logPath)
-	return conf.Data.Save(conf.Path)
+	save := func() error { return conf.Data.SaveTo(confStore, conf.ID) }
+	if vault != nil {
+		err = conf.Data.WithSealedSecrets(vault, save)
+	} else {
+		err = save()
+	}
+	if err != nil {
+		return err
+	}
+	conf.updateSum()
+	return nil
+}
+
+// loadConfData loads the config of the id from the store, with its secrets opened by
+// the vault. The secrets failed to open are kept sealed, and reported by sealed.
+func loadConfData(id string) (data *config.ClientConfig, sealed bool, err error) {
+	if data, err = config.LoadClientConf(confStore, id); err != nil {
+		return nil, false, err
+	}
+	if data.Name() == "" {
+		data.ClientCommon.Name = id
+	}
+	return data, data.OpenSecrets(vault) != nil, nil
 }
 
 var (
@@ -167,12 +199,17 @@ var (
 			TCPMux:     true,
 			TLSEnable:  true,
 		},
+		History: config.DefaultRetention,
 	}
 	confDB *walk.DataBinder
+	// confStore persists the configs in the profiles directory, keeping their versions
+	confStore = config.NewHistory(config.NewFileStore(profilesDir), filepath.Join(profilesDir, ".history"), appConf.History)
 )
 
-func loadAllConfs() ([]*Conf, error) {
-	// Load and migrate application configuration.
+const profilesDir = "profiles"
+
+// loadAppConf loads and migrates the application configuration.
+func loadAppConf() {
 	if lang, _ := config.UnmarshalAppConf(config.DefaultAppFile, &appConf); lang != nil {
 		if _, ok := i18n.IDToName[*lang]; ok {
 			appConf.Lang = *lang
@@ -183,33 +220,34 @@ func loadAllConfs() ([]*Conf, error) {
 			os.Remove(config.LangFile)
 		}
 	}
-	// Find all config files in `profiles` directory subdirectories only.
-	var files []string
-	// Scan R_* subdirectories
-	profilesDir := "profiles"
-	if entries, err := os.ReadDir(profilesDir); err == nil {
-		for _, entry := range entries {
-			if entry.IsDir() && len(entry.Name()) > 2 && entry.Name()[:2] == "R_" {
-				subdirFiles, err := filepath.Glob(filepath.Join(profilesDir, entry.Name(), "*.conf"))
-				if err == nil {
-					files = append(files, subdirFiles...)
-				}
-			}
-		}
+	confStore.Retention = appConf.History
+}
+
+// loadAllConfs loads the configs, with their secrets opened by the vault. The names of the
+// configs whose secrets can't be opened are returned along with the configs.
+func loadAllConfs() ([]*Conf, []string, error) {
+	if err := migrateProfiles(); err != nil {
+		return nil, nil, err
+	}
+	ids, err := confStore.List()
+	if err != nil {
+		return nil, nil, err
 	}
 	cfgList := make([]*Conf, 0)
-	for _, f := range files {
-		if conf, err := config.UnmarshalClientConf(f); err == nil {
-			c := NewConf(f, conf)
-			if c.Name() == "" {
-				conf.ClientCommon.Name = util.FileNameWithoutExt(f)
+	var sealed []string
+	for _, id := range ids {
+		if data, secretsSealed, err := loadConfData(id); err == nil {
+			c := NewConf(id, data)
+			c.updateSum()
+			if secretsSealed {
+				sealed = append(sealed, c.Name())
 			}
 			cfgList = append(cfgList, c)
 		}
 	}
 	slices.SortStableFunc(cfgList, func(a, b *Conf) int {
-		i := slices.Index(appConf.Sort, util.FileNameWithoutExt(a.Path))
-		j := slices.Index(appConf.Sort, util.FileNameWithoutExt(b.Path))
+		i := slices.Index(appConf.Sort, a.ID)
+		j := slices.Index(appConf.Sort, b.ID)
 		if i < 0 && j >= 0 {
 			return 1
 		} else if j < 0 && i >= 0 {
@@ -217,7 +255,40 @@ func loadAllConfs() ([]*Conf, error) {
 		}
 		return i - j
 	})
-	return cfgList, nil
+	return cfgList, sealed, nil
+}
+
+// migrateProfiles moves the configs of older versions, kept in a directory per server like
+// "profiles/R_<addr>_<port>/<name>.conf", into the store. The file name becomes the ID of
+// a config, which keeps the name of its service.
+func migrateProfiles() error {
+	legacy, err := filepath.Glob(filepath.Join(profilesDir, "R_*", "*.conf"))
+	if err != nil || len(legacy) == 0 {
+		return err
+	}
+	ids, err := confStore.List()
+	if err != nil {
+		return err
+	}
+	for _, path := range legacy {
+		id := util.FileNameWithoutExt(path)
+		for i := 2; slices.Contains(ids, id); i++ {
+			id = fmt.Sprintf("%s_%d", util.FileNameWithoutExt(path), i)
+		}
+		// A config failing to move is left in place, rather than blocking the others
+		b, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		// The relative includes must still match the same files from the store
+		if b, err = config.AbsIncludes(path, b); err != nil || confStore.Save(id, b) != nil {
+			continue
+		}
+		os.Rename(path+".base", confStore.Path(id)+".base")
+		os.Remove(path)
+		ids = append(ids, id)
+	}
+	return nil
 }
 
 // ConfBinder is the view model of configs
@@ -230,6 +301,8 @@ type ConfBinder struct {
 	SetState func(conf *Conf, state consts.ConfigState) bool
 	// Commit will save the given config and try to reload service
 	Commit func(conf *Conf, flag runFlag)
+	// Sync applies the changes made outside the program to the config file of the id
+	Sync func(id string)
 }
 
 // getCurrentConf returns the current selected config
@@ -261,6 +334,16 @@ func commitConf(conf *Conf, flag runFlag) {
 	}
 }
 
+// syncConf applies the changes made to the config file outside the program, which were
+// held back while the config was edited.
+func syncConf(conf *Conf) {
+	if confDB != nil {
+		if ds, ok := confDB.DataSource().(*ConfBinder); ok {
+			ds.Sync(conf.ID)
+		}
+	}
+}
+
 // getConfList returns a list of all configs.
 func getConfList() []*Conf {
 	if confDB != nil {
@@ -271,6 +354,32 @@ func getConfList() []*Conf {
 	return nil
 }
 
+// reportedConflicts holds the conflicts already shown to the user.
+var reportedConflicts = make(map[string]bool)
+
+// newConflicts returns the conflicts between the configs claimed by any of the named
+// configs, which were not reported before. The returned conflicts are marked as reported.
+func newConflicts(confs []*config.ClientConfig, names ...string) []string {
+	var conflicts []string
+	for _, c := range config.FindConflicts(confs) {
+		if s := c.String(); !reportedConflicts[s] && slices.ContainsFunc(names, c.Involves) {
+			reportedConflicts[s] = true
+			conflicts = append(conflicts, s)
+		}
+	}
+	return conflicts
+}
+
+// checkConflicts warns about the new conflicts between the config and the other configs.
+func checkConflicts(owner walk.Form, conf *Conf) {
+	confs := lo.Map(getConfList(), func(item *Conf, i int) *config.ClientConfig { return item.Data })
+	if conflicts := newConflicts(confs, conf.Name()); len(conflicts) > 0 {
+		showWarningMessage(owner, i18n.Sprintf("Conflicts"),
+			i18n.Sprintf("The following resources of config \"%s\" are claimed more than once, so some proxies will fail to start:", conf.Name())+
+				"\n\n"+strings.Join(conflicts, "\n"))
+	}
+}
+
 func setConfState(conf *Conf, state consts.ConfigState) bool {
 	if confDB != nil {
 		if ds, ok := confDB.DataSource().(*ConfBinder); ok {
@@ -292,7 +401,7 @@ func saveAppConfig() error {
 
 func setConfOrder(cfgList []*Conf) {
 	appConf.Sort = lo.Map(cfgList, func(item *Conf, index int) string {
-		return util.FileNameWithoutExt(item.Path)
+		return item.ID
 	})
 	saveAppConfig()
 }
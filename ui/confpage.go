@@ -1,6 +1,7 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -23,7 +24,9 @@ type ConfPage struct {
 	detailView  *DetailView
 	welcomeView *walk.Composite
 
-	svcCleanup func() error
+	svcCleanup   func() error
+	watchCleanup func() error
+	subCleanup   func()
 }
 
 func NewConfPage(cfgList []*Conf) *ConfPage {
@@ -43,12 +46,18 @@ func (cp *ConfPage) Page() TabPage {
 			DataSource: &ConfBinder{
 				List:     cp.confView.model.List,
 				SetState: cp.confView.model.SetStateByConf,
+				Sync:     cp.confView.syncConf,
 				Commit: func(conf *Conf, flag runFlag) {
 					if conf != nil {
-						if err := conf.Save(); err != nil {
+						if err := conf.Save(); errors.Is(err, errEditConflict) {
+							if saved, err := cp.confView.resolveEditConflict(conf); !saved || showError(err, cp.Form()) {
+								return
+							}
+						} else if err != nil {
 							showError(err, cp.Form())
 							return
 						}
+						checkConflicts(cp.Form(), conf)
 						if flag == runFlagForceStart {
 							// The service of config is stopped by other code, but it should be restarted
 						} else if conf.State == consts.ConfigStateStarted {
@@ -154,6 +163,14 @@ func (cp *ConfPage) OnCreate() {
 		cp.detailView.panelView.Invalidate(false)
 	})
 	cp.addVisibleChangedListener()
+	if cleanup, err := cp.confView.watchConfs(); err == nil {
+		cp.watchCleanup = cleanup
+	}
+	cp.subCleanup = cp.confView.watchSubscriptions()
+	localAPI = newAPIService(cp)
+	if err := localAPI.restart(); err != nil {
+		showErrorMessage(cp.Form(), "", i18n.Sprintf("Failed to start the local API: %v", err))
+	}
 	cleanup, err := services.WatchConfigServices(func() []string {
 		return lo.Map(getConfList(), func(item *Conf, index int) string {
 			return item.Path
@@ -166,6 +183,7 @@ func (cp *ConfPage) OnCreate() {
 		}
 		cp.Synchronize(func() {
 			if cp.confView.model.SetStateByPath(path, state) {
+				localAPI.publishState(path)
 				if conf := getCurrentConf(); conf != nil && conf.Path == path {
 					cp.detailView.panelView.setState(state)
 					if !cp.Visible() {
@@ -213,6 +231,15 @@ func (cp *ConfPage) addVisibleChangedListener() {
 }
 
 func (cp *ConfPage) Close() error {
+	if localAPI != nil {
+		localAPI.close()
+	}
+	if cp.watchCleanup != nil {
+		cp.watchCleanup()
+	}
+	if cp.subCleanup != nil {
+		cp.subCleanup()
+	}
 	if cp.svcCleanup != nil {
 		return cp.svcCleanup()
 	}
@@ -3,12 +3,14 @@ package ui
 import (
 	"archive/zip"
 	"bytes"
+	"context"
 	"encoding/base64"
-	"io"
+	"fmt"
 	"os"
 	"path/filepath"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/lxn/walk"
 	. "github.com/lxn/walk/declarative"
@@ -17,9 +19,11 @@ import (
 	"github.com/hzcrv1911/frpcgui/i18n"
 	"github.com/hzcrv1911/frpcgui/pkg/config"
 	"github.com/hzcrv1911/frpcgui/pkg/consts"
+	"github.com/hzcrv1911/frpcgui/pkg/diag"
 	"github.com/hzcrv1911/frpcgui/pkg/layout"
 	"github.com/hzcrv1911/frpcgui/pkg/res"
 	"github.com/hzcrv1911/frpcgui/pkg/util"
+	"github.com/hzcrv1911/frpcgui/services"
 )
 
 type ConfView struct {
@@ -35,6 +39,9 @@ type ConfView struct {
 	tbAddAction    *walk.Action
 	tbDeleteAction *walk.Action
 	tbExportAction *walk.Action
+
+	// refreshSubscription refreshes the subscribed config of the id in the background
+	refreshSubscription func(id string, manual bool)
 }
 
 var cachedListViewIconsForWidthAndState = make(map[widthAndConfigState]*walk.Bitmap)
@@ -144,6 +151,36 @@ func (cv *ConfView) View() Widget {
 						Enabled:     Bind("confView.ItemCount > 0"),
 						OnTriggered: cv.onExport,
 					},
+					Action{
+						Text:        i18n.SprintfEllipsis("Export Diagnostics"),
+						Enabled:     Bind("confView.ItemCount > 0"),
+						OnTriggered: cv.onExportDiagnostics,
+					},
+					Action{
+						Text:        i18n.SprintfEllipsis("Upgrade Legacy Configs"),
+						Enabled:     Bind("confView.ItemCount > 0"),
+						OnTriggered: cv.onMigrate,
+					},
+					Action{
+						Text:    i18n.SprintfEllipsis("Version History"),
+						Enabled: Bind("confView.SelectedCount == 1"),
+						OnTriggered: func() {
+							if conf := getCurrentConf(); conf != nil {
+								if _, err := NewHistoryDialog(conf).Run(cv.Form()); err != nil {
+									showError(err, cv.Form())
+								}
+							}
+						},
+					},
+					Action{
+						Text:    i18n.SprintfEllipsis("Subscription"),
+						Enabled: Bind("confView.SelectedCount == 1"),
+						OnTriggered: func() {
+							if conf := getCurrentConf(); conf != nil {
+								cv.onSubscribe(conf)
+							}
+						},
+					},
 					Action{
 						Text:    i18n.Sprintf("Properties"),
 						Enabled: Bind("confView.SelectedCount == 1"),
@@ -307,6 +344,13 @@ func (cv *ConfView) fixWidthToToolbarWidth() {
 }
 
 func (cv *ConfView) onEditConf(conf *Conf, create bool) {
+	if !create {
+		conf.editing = true
+		defer func() {
+			conf.editing = false
+			cv.syncConf(conf.ID)
+		}()
+	}
 	dlg := NewEditClientDialog(conf.Data, create)
 	if result, _ := dlg.Run(cv.Form()); result == walk.DlgCmdOK {
 		if create {
@@ -323,36 +367,46 @@ func (cv *ConfView) onEditConf(conf *Conf, create bool) {
 	}
 }
 
+func (cv *ConfView) onSubscribe(conf *Conf) {
+	old := appConf.Subscriptions[conf.ID]
+	dlg := NewSubscriptionDialog(conf)
+	if result, err := dlg.Run(cv.Form()); err != nil || result != walk.DlgCmdOK {
+		return
+	}
+	if sub := appConf.Subscriptions[conf.ID]; sub != nil && (old == nil || old.URL != sub.URL) {
+		cv.refreshSubscription(conf.ID, true)
+	}
+}
+
 func (cv *ConfView) onURLImport() {
 	dlg := NewURLImportDialog()
 	if result, err := dlg.Run(cv.Form()); err != nil || result != walk.DlgCmdOK {
 		return
 	}
 	var cfgList []*Conf
-	cv.importConfig(func() (total, imported int) {
+	cv.importConfig(func(check func(string, *config.ClientConfig)) (total, imported int) {
 		for _, item := range dlg.Items {
 			if item.Zip {
-				subList, subTotal, subImported := cv.importZip(item.Filename, item.Data)
+				subList, subTotal, subImported := cv.importZip(item.Filename, item.Data, check)
 				total += subTotal
 				imported += subImported
 				cfgList = append(cfgList, subList...)
 			} else {
 				total++
-				conf, err := config.UnmarshalClientConf(item.Data)
-				if err != nil {
-					showError(err, cv.Form())
+				if err := appConf.Bundles.CheckUnsigned(); err != nil {
+					showErrorMessage(cv.Form(), "", i18n.Sprintf("The file \"%s\" is rejected: %s", item.Filename, err))
 					continue
 				}
-				if conf.Name() == "" {
-					conf.ClientCommon.Name = util.FileNameWithoutExt(item.Filename)
+				cfg, ok, err := cv.importData(item.Filename, item.Data, check)
+				if cfg != nil {
+					cfgList = append(cfgList, cfg)
 				}
-				cfg := NewConf("", conf)
-				if err = cfg.Save(); err != nil {
+				if ok {
+					imported++
+				}
+				if err != nil {
 					showError(err, cv.Form())
-					continue
 				}
-				cfgList = append(cfgList, cfg)
-				imported++
 			}
 		}
 		return
@@ -372,44 +426,67 @@ func (cv *ConfView) onFileImport() {
 	cv.ImportFiles(dlg.FilePaths)
 }
 
-func (cv *ConfView) importConfig(f func() (int, int)) {
-	if total, imported := f(); imported > 0 {
-		showInfoMessage(cv.Form(),
-			i18n.Sprintf("Import Config"),
-			i18n.Sprintf("Imported %d of %d configs.", imported, total))
+// importConfig runs the import and shows a summary, listing the problems found in the
+// imported configs by the check function.
+func (cv *ConfView) importConfig(f func(check func(string, *config.ClientConfig)) (int, int)) {
+	var problems []string
+	var importedConfs []*config.ClientConfig
+	check := func(name string, conf *config.ClientConfig) {
+		for _, d := range conf.Validate() {
+			problems = append(problems, fmt.Sprintf("%s: %s", name, d))
+		}
+		importedConfs = append(importedConfs, conf)
+	}
+	if total, imported := f(check); imported > 0 {
+		// Look for conflicts between the imported configs and the others
+		names := lo.Map(importedConfs, func(conf *config.ClientConfig, i int) string { return conf.Name() })
+		confs := importedConfs
+		for _, conf := range cv.model.List() {
+			if !slices.Contains(names, conf.Name()) {
+				confs = append(confs, conf.Data)
+			}
+		}
+		problems = append(problems, newConflicts(confs, names...)...)
+		message := i18n.Sprintf("Imported %d of %d configs.", imported, total)
+		if len(problems) > 0 {
+			showWarningMessage(cv.Form(), i18n.Sprintf("Import Config"),
+				message+"\n\n"+i18n.Sprintf("The following problems were found:")+"\n"+strings.Join(problems, "\n"))
+			return
+		}
+		showInfoMessage(cv.Form(), i18n.Sprintf("Import Config"), message)
 	}
 }
 
 func (cv *ConfView) ImportFiles(files []string) {
 	var cfgList []*Conf
-	cv.importConfig(func() (total, imported int) {
+	cv.importConfig(func(check func(string, *config.ClientConfig)) (total, imported int) {
 		for _, path := range files {
 			if dir, err := util.IsDirectory(path); err != nil || dir {
 				continue
 			}
 			ext := strings.ToLower(filepath.Ext(path))
 			if ext == ".zip" {
-				subList, subTotal, subImported := cv.importZip(path, nil)
+				subList, subTotal, subImported := cv.importZip(path, nil, check)
 				total += subTotal
 				imported += subImported
 				cfgList = append(cfgList, subList...)
 			} else if slices.Contains(res.SupportedConfigFormats, ext) {
 				total++
-				conf, err := config.UnmarshalClientConf(path)
+				src, err := os.ReadFile(path)
 				if err != nil {
 					showError(err, cv.Form())
 					continue
 				}
-				if conf.Name() == "" {
-					conf.ClientCommon.Name = util.FileNameWithoutExt(path)
+				cfg, ok, err := cv.importData(filepath.Base(path), src, check)
+				if cfg != nil {
+					cfgList = append(cfgList, cfg)
+				}
+				if ok {
+					imported++
 				}
-				cfg := NewConf("", conf)
-				if err = cfg.Save(); err != nil {
+				if err != nil {
 					showError(err, cv.Form())
-					continue
 				}
-				cfgList = append(cfgList, cfg)
-				imported++
 			}
 		}
 		return
@@ -417,30 +494,7 @@ func (cv *ConfView) ImportFiles(files []string) {
 	cv.model.Add(cfgList...)
 }
 
-func (cv *ConfView) importZip(path string, data []byte) (cfgList []*Conf, total, imported int) {
-	importFile := func(file *zip.File) (*Conf, error) {
-		fr, err := file.Open()
-		if err != nil {
-			return nil, err
-		}
-		defer fr.Close()
-		src, err := io.ReadAll(fr)
-		if err != nil {
-			return nil, err
-		}
-		conf, err := config.UnmarshalClientConf(src)
-		if err != nil {
-			return nil, err
-		}
-		if conf.Name() == "" {
-			conf.ClientCommon.Name = util.FileNameWithoutExt(file.Name)
-		}
-		cfg := NewConf("", conf)
-		if err = cfg.Save(); err != nil {
-			return nil, err
-		}
-		return cfg, nil
-	}
+func (cv *ConfView) importZip(path string, data []byte, check func(string, *config.ClientConfig)) (cfgList []*Conf, total, imported int) {
 	var zr *zip.Reader
 	var err error
 	if data == nil {
@@ -458,22 +512,138 @@ func (cv *ConfView) importZip(path string, data []byte) (cfgList []*Conf, total,
 		showErrorMessage(cv.Form(), "", i18n.Sprintf("The file \"%s\" is not a valid ZIP file.", path))
 		return
 	}
-	for _, file := range zr.File {
-		if file.FileInfo().IsDir() {
-			continue
-		}
+	// The files of a bundle are only trusted if it's signed by a trusted key
+	bundle, err := appConf.Bundles.ReadBundle(zr)
+	if err != nil {
+		showErrorMessage(cv.Form(), "", i18n.Sprintf("The file \"%s\" is rejected: %s", path, err))
+		return
+	}
+	for _, file := range bundle.Files {
 		if !slices.Contains(res.SupportedConfigFormats, strings.ToLower(filepath.Ext(file.Name))) {
 			continue
 		}
 		total++
-		if cfg, err := importFile(file); err == nil {
-			imported++
+		cfg, ok, _ := cv.importData(file.Name, file.Data, check)
+		if cfg != nil {
 			cfgList = append(cfgList, cfg)
 		}
+		if ok {
+			imported++
+		}
 	}
 	return
 }
 
+// onMigrate converts all configs in the legacy INI format to TOML, keeping the original
+// content in the history, and offers to show the report of the changes.
+func (cv *ConfView) onMigrate() {
+	title := i18n.Sprintf("Upgrade Legacy Configs")
+	legacy := lo.Filter(cv.model.List(), func(conf *Conf, i int) bool {
+		return conf.Data.Format == consts.FormatINI
+	})
+	if len(legacy) == 0 {
+		showInfoMessage(cv.Form(), title, i18n.Sprintf("There are no configs in the legacy format."))
+		return
+	}
+	if walk.MsgBox(cv.Form(), title,
+		i18n.Sprintf("Are you sure you would like to upgrade %d configs to the TOML format? The original configs are kept as backups and in their history.", len(legacy)),
+		walk.MsgBoxYesNo|walk.MsgBoxIconQuestion) == walk.DlgCmdNo {
+		return
+	}
+	var reports []string
+	upgraded := 0
+	for _, conf := range legacy {
+		if conf.State == consts.ConfigStateStarting {
+			reports = append(reports, fmt.Sprintf("%s: %s", conf.Path, i18n.Sprintf("The config is currently locked.")))
+			continue
+		}
+		data, report, err := config.MigrateConf(confStore, conf.ID, consts.FormatTOML)
+		if err != nil {
+			reports = append(reports, fmt.Sprintf("%s: %v", conf.Path, err))
+			continue
+		}
+		if data.Name() == "" {
+			data.ClientCommon.Name = conf.Name()
+		}
+		data.OpenSecrets(vault)
+		conf.Data = data
+		commitConf(conf, runFlagReload)
+		reports = append(reports, report.String())
+		upgraded++
+	}
+	setCurrentConf(getCurrentConf())
+	if walk.MsgBox(cv.Form(), title,
+		i18n.Sprintf("Upgraded %d of %d configs. Would you like to view the report?", upgraded, len(legacy)),
+		walk.MsgBoxYesNo|walk.MsgBoxIconInformation) == walk.DlgCmdYes {
+		path := filepath.Join(os.TempDir(), fmt.Sprintf("frpcgui-migration-%d.txt", time.Now().Unix()))
+		if err := os.WriteFile(path, []byte(strings.Join(reports, "\n")), 0666); err != nil {
+			showError(err, cv.Form())
+			return
+		}
+		openPath(path)
+	}
+}
+
+// importData saves the imported config content. If a config of the same name exists, the
+// changes are shown and the user chooses to merge them into it, replace it or skip the import.
+// It reports whether the config is imported, and returns the config if it's a new one,
+// even along with an error if only its merge base failed to be saved.
+func (cv *ConfView) importData(filename string, src []byte, check func(string, *config.ClientConfig)) (*Conf, bool, error) {
+	conf, err := config.UnmarshalClientConf(src)
+	if err != nil {
+		return nil, false, err
+	}
+	if conf.Name() == "" {
+		conf.ClientCommon.Name = util.FileNameWithoutExt(filename)
+	}
+	if err = openSecrets(conf.Name(), conf); err != nil {
+		return nil, false, err
+	}
+	existing, found := lo.Find(cv.model.List(), func(item *Conf) bool { return item.Name() == conf.Name() })
+	if !found {
+		cfg := NewConf("", conf)
+		if err = cfg.Save(); err != nil {
+			return nil, false, err
+		}
+		if err = cfg.saveBase(src); err != nil {
+			return cfg, true, err
+		}
+		check(filename, conf)
+		return cfg, true, nil
+	}
+	// The log file is always decided by the path of the config
+	conf.LogFile = existing.Data.LogFile
+	if diff := config.Diff(existing.Data, conf); !diff.Empty() {
+		switch walk.MsgBox(cv.Form(), i18n.Sprintf("Import Config"),
+			i18n.Sprintf("The config \"%s\" already exists. The import makes the following changes:", existing.Name())+
+				"\n\n"+diff.String()+"\n\n"+
+				i18n.Sprintf("Would you like to merge the changes, keeping your own changes? Choose \"No\" to replace the config."),
+			walk.MsgBoxYesNoCancel|walk.MsgBoxIconQuestion) {
+		case walk.DlgCmdYes:
+			merged, conflicts := existing.merge(conf)
+			if len(conflicts) > 0 {
+				showWarningMessage(cv.Form(), i18n.Sprintf("Import Config"),
+					i18n.Sprintf("The following options were changed on both sides, your values are kept:")+"\n"+
+						strings.Join(lo.Map(conflicts, func(c config.MergeConflict, i int) string { return c.String() }), "\n"))
+			}
+			existing.Data = merged
+		case walk.DlgCmdNo:
+			existing.Data = conf
+		default:
+			return nil, false, nil
+		}
+		commitConf(existing, runFlagReload)
+		if existing == getCurrentConf() {
+			setCurrentConf(existing)
+		}
+	}
+	if err = existing.saveBase(src); err != nil {
+		return nil, true, err
+	}
+	check(filename, existing.Data)
+	return nil, true, nil
+}
+
 func (cv *ConfView) onClipboardImport() {
 	text, err := walk.Clipboard().Text()
 	if err != nil {
@@ -497,16 +667,26 @@ func (cv *ConfView) onClipboardImport() {
 		showError(err, cv.Form())
 		return
 	}
+	if showError(openSecrets(conf.Name(), conf), cv.Form()) {
+		return
+	}
 	cv.onEditConf(NewConf("", conf), true)
 }
 
 func (cv *ConfView) onCopyShareLink() {
 	if conf := getCurrentConf(); conf != nil {
-		content, err := os.ReadFile(conf.Path)
+		content, err := confStore.Load(conf.ID)
 		if err != nil {
 			showError(err, cv.Form())
 			return
 		}
+		// The sealed secrets are of no use without the vault key
+		if vault != nil {
+			if content, err = config.RedactContent(content); err != nil {
+				showError(err, cv.Form())
+				return
+			}
+		}
 		walk.Clipboard().SetText(res.ShareLinkScheme + base64.StdEncoding.EncodeToString(content))
 	}
 }
@@ -593,14 +773,57 @@ func (cv *ConfView) onExport() {
 	if !strings.HasSuffix(dlg.FilePath, ".zip") {
 		dlg.FilePath += ".zip"
 	}
-	files := lo.SliceToMap(cv.model.List(), func(conf *Conf) (string, string) {
-		return conf.Path, conf.Name() + conf.Data.Ext()
+	// The secrets are archived as stored, so they stay sealed if the vault is enabled
+	paths := lo.Map(cv.model.List(), func(conf *Conf, _ int) string {
+		return conf.Path
 	})
-	if err := util.ZipFiles(dlg.FilePath, files); err != nil {
+	if err := config.ExportClientConfs(dlg.FilePath, paths); err != nil {
 		showError(err, cv.Form())
 	}
 }
 
+// onExportDiagnostics exports the diagnostics of the selected configs, or of all configs
+// if none is selected, to a ZIP file for support.
+func (cv *ConfView) onExportDiagnostics() {
+	confs := lo.Map(cv.listView.SelectedIndexes(), func(i int, _ int) *Conf {
+		return cv.model.items[i]
+	})
+	if len(confs) == 0 {
+		confs = cv.model.List()
+	}
+	dlg := walk.FileDialog{
+		Filter:   res.FilterZip,
+		Title:    i18n.Sprintf("Export Diagnostics"),
+		FilePath: "diagnostics.zip",
+	}
+	if ok, _ := dlg.ShowSave(cv.Form()); !ok {
+		return
+	}
+	if !strings.HasSuffix(dlg.FilePath, ".zip") {
+		dlg.FilePath += ".zip"
+	}
+	// The configs are copied, as the export runs in the background
+	sources := lo.Map(confs, func(conf *Conf, i int) diag.Source {
+		data := conf.Data.Copy(true)
+		data.LogFile = conf.Data.LogFile
+		return diag.Source{Path: conf.Path, Data: data}
+	})
+	filename := dlg.FilePath
+	go func() {
+		m, err := services.ExportDiagnostics(context.Background(), filename, sources)
+		cv.Synchronize(func() {
+			if showError(err, cv.Form()) {
+				return
+			}
+			msg := i18n.Sprintf("The diagnostics of %d configs are exported to %s.", len(m.Configs), filename)
+			if n := m.FailedChecks(); n > 0 {
+				msg += "\n\n" + i18n.Sprintf("%d connectivity checks failed, see %s in the ZIP file for details.", n, diag.ManifestName)
+			}
+			showInfoMessage(cv.Form(), i18n.Sprintf("Export Diagnostics"), msg)
+		})
+	}()
+}
+
 func (cv *ConfView) onNATDiscovery() {
 	var stunServer string
 	// Try to use the address in config first
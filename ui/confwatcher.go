@@ -0,0 +1,201 @@
+package ui
+
+import (
+	"bytes"
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/lxn/walk"
+
+	"github.com/hzcrv1911/frpcgui/i18n"
+	"github.com/hzcrv1911/frpcgui/pkg/consts"
+	"github.com/hzcrv1911/frpcgui/services"
+)
+
+// confWatchDelay is how long the events of a config file are collected before it's
+// reloaded, as editors and scripts tend to write a file in several steps.
+const confWatchDelay = 300 * time.Millisecond
+
+// watchConfs reloads the configs changed, added or removed outside the program, and
+// those whose included files are changed. The returned function stops watching.
+func (cv *ConfView) watchConfs() (func() error, error) {
+	if err := os.MkdirAll(profilesDir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err = watcher.Add(profilesDir); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	cv.watchIncludes(watcher)
+	go func() {
+		pending := make(map[string]bool)
+		// includes is set by the changes of files other than configs, which may be included
+		includes := false
+		timer := time.NewTimer(confWatchDelay)
+		timer.Stop()
+		defer timer.Stop()
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if id, ok := confIDOfFile(event.Name); ok {
+					pending[id] = true
+				} else {
+					includes = true
+				}
+				timer.Reset(confWatchDelay)
+			case _, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+			case <-timer.C:
+				ids := slices.Sorted(maps.Keys(pending))
+				syncIncludes := includes
+				clear(pending)
+				includes = false
+				cv.Synchronize(func() {
+					if syncIncludes {
+						for _, conf := range cv.model.items {
+							if len(conf.Data.Includes) > 0 && !slices.Contains(ids, conf.ID) {
+								ids = append(ids, conf.ID)
+							}
+						}
+					}
+					for _, id := range ids {
+						cv.syncConf(id)
+					}
+					cv.watchIncludes(watcher)
+				})
+			}
+		}
+	}()
+	return watcher.Close, nil
+}
+
+// watchIncludes adds the directories of the files included by the configs to the watcher.
+// Directories already watched are left as is.
+func (cv *ConfView) watchIncludes(watcher *fsnotify.Watcher) {
+	watched := watcher.WatchList()
+	for _, conf := range cv.model.items {
+		files, err := conf.Data.IncludeFiles(conf.Path)
+		if err != nil {
+			continue
+		}
+		for _, file := range files {
+			if dir := filepath.Dir(file); !slices.Contains(watched, dir) && watcher.Add(dir) == nil {
+				watched = append(watched, dir)
+			}
+		}
+	}
+}
+
+// confIDOfFile returns the ID of the config stored in the file. Temporary files,
+// lock files and other files of the profiles directory aren't configs.
+func confIDOfFile(path string) (string, bool) {
+	id, ok := strings.CutSuffix(filepath.Base(path), ".conf")
+	if !ok || id == "" || strings.HasPrefix(id, ".") {
+		return "", false
+	}
+	return id, true
+}
+
+// syncConf applies the changes made outside the program to the config file of the id.
+// The configs being edited are left alone, as their files are checked for changes when
+// the edits are saved.
+func (cv *ConfView) syncConf(id string) {
+	i := slices.IndexFunc(cv.model.items, func(conf *Conf) bool { return conf.ID == id })
+	var conf *Conf
+	if i >= 0 {
+		conf = cv.model.items[i]
+		if conf.editing {
+			return
+		}
+	}
+	sum, err := diskSum(id)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && conf != nil {
+			// Files are often replaced by a removal and a rename, so the config is only
+			// dropped if the file is still missing a while later
+			time.AfterFunc(confWatchDelay, func() {
+				cv.Synchronize(func() { cv.dropConf(id) })
+			})
+		}
+		return
+	}
+	if conf != nil && bytes.Equal(sum, conf.sum) {
+		// The files are saved by the program itself
+		return
+	}
+	data, _, err := loadConfData(id)
+	if err != nil {
+		// The file may still be written, or be fixed later
+		return
+	}
+	if conf == nil {
+		conf = NewConf(id, data)
+		conf.updateSum()
+		cv.model.Add(conf)
+		checkConflicts(cv.Form(), conf)
+		return
+	}
+	conf.Data = data
+	conf.updateSum()
+	cv.model.PublishRowChanged(i)
+	cv.model.PublishRowEdited(i)
+	if conf == getCurrentConf() {
+		setCurrentConf(conf)
+	}
+	checkConflicts(cv.Form(), conf)
+}
+
+// dropConf removes the config of the id from the list if its file is still missing, or
+// reloads it otherwise. The service of the config is only removed if the user agrees.
+func (cv *ConfView) dropConf(id string) {
+	i := slices.IndexFunc(cv.model.items, func(conf *Conf) bool { return conf.ID == id })
+	if i < 0 {
+		return
+	}
+	conf := cv.model.items[i]
+	if _, err := confStore.Load(id); !errors.Is(err, os.ErrNotExist) {
+		cv.syncConf(id)
+		return
+	}
+	cv.model.Remove(i)
+	if conf.State == consts.ConfigStateNotInstalled {
+		return
+	}
+	if walk.MsgBox(cv.Form(), i18n.Sprintf("Delete config \"%s\"", conf.Name()),
+		i18n.Sprintf("The config file of \"%s\" was removed outside the program. Would you like to remove its service too?", conf.Name()),
+		walk.MsgBoxYesNo|walk.MsgBoxIconQuestion) == walk.DlgCmdYes {
+		showError(services.UninstallService(conf.Path, true), cv.Form())
+	}
+}
+
+// resolveEditConflict asks whether the edits of the config should overwrite the changes
+// made to its file outside the program. The config is saved if so, or reloaded from its
+// file otherwise. It reports whether the config is saved.
+func (cv *ConfView) resolveEditConflict(conf *Conf) (bool, error) {
+	if walk.MsgBox(cv.Form(), i18n.Sprintf("Edit Conflict"),
+		i18n.Sprintf("The config \"%s\" was changed outside the program while being edited. "+
+			"Would you like to overwrite those changes with your edits? Choose No to discard your edits and reload the file.", conf.Name()),
+		walk.MsgBoxYesNo|walk.MsgBoxIconWarning) == walk.DlgCmdYes {
+		conf.sum = nil
+		return true, conf.Save()
+	}
+	conf.sum = nil
+	conf.editing = false
+	cv.syncConf(conf.ID)
+	return false, nil
+}
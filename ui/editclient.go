@@ -106,8 +106,8 @@ func (cd *EditClientDialog) basicConfPage() TabPage {
 }
 
 func (cd *EditClientDialog) authConfPage() TabPage {
-	tokenSource := Bind("tokenCheck.Checked && !legacyFormat.Checked")
-	tokenInput := Bind("tokenCheck.Checked && (legacyFormat.Checked || tokenSource.Value == '')")
+	tokenSource := Bind("tokenCheck.Checked && format.Value != 'ini'")
+	tokenInput := Bind("tokenCheck.Checked && (format.Value == 'ini' || tokenSource.Value == '')")
 	tokenFile := Bind("tokenSource.Visible && tokenSource.Value == 'file'")
 	oidc := Bind("oidcCheck.Checked")
 	auth := Bind("!noAuthCheck.Checked")
@@ -361,7 +361,7 @@ func (cd *EditClientDialog) tlsConfPage() TabPage {
 
 func (cd *EditClientDialog) advancedConfPage() TabPage {
 	muxChecked := Bind("muxCheck.Checked")
-	var legacy *walk.CheckBox
+	var format *walk.ComboBox
 	return TabPage{
 		Title:  i18n.Sprintf("Advanced"),
 		Layout: Grid{Columns: 2},
@@ -370,6 +370,21 @@ func (cd *EditClientDialog) advancedConfPage() TabPage {
 			LineEdit{Text: Bind("DNSServer")},
 			Label{Text: i18n.SprintfColon("Source Address")},
 			LineEdit{Text: Bind("ConnectServerLocalIP")},
+			Label{Text: i18n.SprintfColon("File Format")},
+			ComboBox{
+				AssignTo:      &format,
+				Name:          "format",
+				Value:         Bind("Format"),
+				Model:         NewFormatListModel(),
+				BindingMember: "Value",
+				DisplayMember: "Title",
+				OnCurrentIndexChanged: func() {
+					legacy := slices.Index(consts.Formats, consts.FormatINI)
+					if format.CurrentIndex() != legacy && !cd.canUpgradeFormat() {
+						format.SetCurrentIndex(legacy)
+					}
+				},
+			},
 			Composite{
 				Layout: VBox{MarginsZero: true, SpacingZero: true},
 				Children: []Widget{
@@ -400,17 +415,6 @@ func (cd *EditClientDialog) advancedConfPage() TabPage {
 					},
 					CheckBox{Text: i18n.Sprintf("Exit after login failure"), Checked: Bind("LoginFailExit")},
 					CheckBox{Text: i18n.Sprintf("Disable auto-start at boot"), Checked: Bind("ManualStart")},
-					CheckBox{
-						AssignTo: &legacy,
-						Name:     "legacyFormat",
-						Text:     i18n.Sprintf("Use legacy file format"),
-						Checked:  Bind("LegacyFormat"),
-						OnCheckedChanged: func() {
-							if !legacy.Checked() && !cd.canUpgradeFormat() {
-								legacy.SetChecked(true)
-							}
-						},
-					},
 					VSpacer{Size: 4},
 					LinkLabel{
 						Text: fmt.Sprintf("<a>%s</a>", i18n.SprintfEllipsis("Metadata")),
@@ -449,10 +453,13 @@ func (cd *EditClientDialog) onSave() {
 			return
 		}
 	}
-	if !newConf.LegacyFormat && newConf.TokenSource == "file" && newConf.TokenSourceFile == "" {
+	if newConf.Format != consts.FormatINI && newConf.TokenSource == "file" && newConf.TokenSourceFile == "" {
 		showErrorMessage(cd.Form(), "", i18n.Sprintf("Token file is required."))
 		return
 	}
+	if showError(newConf.ClientCommon.Validate().Err(), cd.Form()) {
+		return
+	}
 	cd.data.ClientCommon = newConf.ClientCommon
 	cd.data.ClientCommon.Name = newConf.Name
 	cd.Accept()
@@ -471,7 +478,11 @@ func (cd *EditClientDialog) Run(owner walk.Form) (int, error) {
 }
 
 func (cd *EditClientDialog) canUpgradeFormat() bool {
-	// Format upgrade validation
-	// For now, we allow all format upgrades
+	// The TOML format has no equivalent of range proxies
+	if slices.ContainsFunc(cd.data.Proxies, func(proxy *config.Proxy) bool { return proxy.IsRange() }) {
+		showWarningMessage(cd.Form(), i18n.Sprintf("Unable to upgrade format"),
+			i18n.Sprintf("Range ports are only supported by the legacy file format. Please remove range proxies first."))
+		return false
+	}
 	return true
 }
@@ -514,8 +514,13 @@ func (pd *EditProxyDialog) onSave() {
 		pd.binder.BandwidthLimit = ""
 		pd.binder.BandwidthLimitMode = ""
 	}
-	pd.binder.LocalPort = strings.TrimSpace(pd.binder.LocalPort)
-	pd.binder.RemotePort = strings.TrimSpace(pd.binder.RemotePort)
+	for _, port := range []*string{&pd.binder.LocalPort, &pd.binder.RemotePort} {
+		*port = strings.TrimSpace(*port)
+		// Write valid port lists in their shortest form
+		if ports, err := config.ParsePortSpec(*port); err == nil {
+			*port = ports.Normalize().String()
+		}
+	}
 	if ok := pd.validateProxy(pd.binder.Proxy); !ok {
 		return
 	}
@@ -624,7 +629,7 @@ func (pd *EditProxyDialog) validateProxy(p config.Proxy) bool {
 			showErrorMessage(pd.Form(), "", i18n.Sprintf("Bind port is required."))
 			return false
 		}
-		return true
+		return !showError(p.Validate().Err(), pd.Form())
 	}
 	if !pd.legacyFormat {
 		// For now, we skip annotation validation when using WinSW
@@ -655,7 +660,7 @@ func (pd *EditProxyDialog) validateProxy(p config.Proxy) bool {
 			}
 		}
 	} else if p.Type != consts.ProxyTypeTCP && p.Type != consts.ProxyTypeUDP {
-		if port, err := strconv.ParseInt(p.LocalPort, 10, 64); err != nil || port <= 0 || port > 65535 {
+		if ports, err := p.LocalPorts(); err != nil || ports.IsRange() || ports.Validate(1) != nil {
 			showErrorMessage(pd.Form(), "", i18n.Sprintf("Invalid local port."))
 			return false
 		}
@@ -673,7 +678,7 @@ func (pd *EditProxyDialog) validateProxy(p config.Proxy) bool {
 		if p.Plugin != "" {
 			if p.IsRange() {
 				showErrorMessage(pd.Form(), "", i18n.Sprintf("The plugin does not support range ports."))
-			} else if port, err := strconv.ParseInt(p.RemotePort, 10, 64); err != nil || port < 0 {
+			} else if ports, err := p.RemotePorts(); err != nil || ports.Validate(0) != nil {
 				showErrorMessage(pd.Form(), "", i18n.Sprintf("Invalid remote port."))
 			} else {
 				break
@@ -682,11 +687,6 @@ func (pd *EditProxyDialog) validateProxy(p config.Proxy) bool {
 		} else {
 			// For now, we don't support range ports when using WinSW
 			// This could be re-implemented later if needed
-			if strings.Contains(p.LocalPort, ",") || strings.Contains(p.RemotePort, ",") {
-				showErrorMessage(pd.Form(), "", i18n.Sprintf("Range ports are not supported when using WinSW integration."))
-				return false
-			}
-			// Basic validation for port ranges if IsRange returns true
 			if p.IsRange() {
 				showErrorMessage(pd.Form(), "", i18n.Sprintf("Range ports are not supported when using WinSW integration."))
 				return false
@@ -698,5 +698,6 @@ func (pd *EditProxyDialog) validateProxy(p config.Proxy) bool {
 			return false
 		}
 	}
-	return true
+	// Report the remaining problems all at once
+	return !showError(p.Validate().Err(), pd.Form())
 }
@@ -0,0 +1,150 @@
+package ui
+
+import (
+	"time"
+
+	"github.com/lxn/walk"
+	. "github.com/lxn/walk/declarative"
+
+	"github.com/hzcrv1911/frpcgui/i18n"
+	"github.com/hzcrv1911/frpcgui/pkg/res"
+	"github.com/hzcrv1911/frpcgui/pkg/util"
+)
+
+// HistoryDialog lists the versions of a config, which can be compared and restored.
+type HistoryDialog struct {
+	*walk.Dialog
+
+	table *walk.TableView
+	model *NonSortedModel[historyItem]
+	conf  *Conf
+}
+
+type historyItem struct {
+	Time    string
+	Size    string
+	version string
+}
+
+func NewHistoryDialog(conf *Conf) *HistoryDialog {
+	return &HistoryDialog{conf: conf, model: NewNonSortedModel([]*historyItem{})}
+}
+
+func (hd *HistoryDialog) Run(owner walk.Form) (int, error) {
+	if err := hd.load(); err != nil {
+		return 0, err
+	}
+	dlg := Dialog{
+		AssignTo: &hd.Dialog,
+		Icon:     loadIcon(res.IconFile, 32),
+		Title:    i18n.Sprintf("%s Version History", hd.conf.Name()),
+		Layout:   VBox{},
+		Font:     res.TextRegular,
+		MinSize:  Size{Width: 420, Height: 350},
+		Children: []Widget{
+			Label{Text: i18n.Sprintf("A version is kept each time the config is saved. Select two versions to compare them.")},
+			TableView{
+				AssignTo: &hd.table,
+				Name:     "history",
+				Columns: []TableViewColumn{
+					{Title: i18n.Sprintf("Saved"), DataMember: "Time", Width: 180},
+					{Title: i18n.Sprintf("Size"), DataMember: "Size", Width: 100},
+				},
+				ColumnsOrderable: false,
+				MultiSelection:   true,
+				Model:            hd.model,
+			},
+			Composite{
+				Layout: HBox{MarginsZero: true},
+				Children: []Widget{
+					PushButton{
+						Text:      i18n.Sprintf("Compare"),
+						Enabled:   Bind("history.SelectedCount == 1 || history.SelectedCount == 2"),
+						OnClicked: hd.onCompare,
+					},
+					PushButton{
+						Text:      i18n.SprintfEllipsis("Restore"),
+						Enabled:   Bind("history.SelectedCount == 1"),
+						OnClicked: hd.onRestore,
+					},
+					HSpacer{},
+					PushButton{Text: i18n.Sprintf("Close"), OnClicked: func() { hd.Cancel() }},
+				},
+			},
+		},
+	}
+	if err := dlg.Create(owner); err != nil {
+		return 0, err
+	}
+	return hd.Dialog.Run(), nil
+}
+
+// load fills the table with the versions of the config, newest first.
+func (hd *HistoryDialog) load() error {
+	snapshots, err := confStore.Snapshots(hd.conf.ID)
+	if err != nil {
+		return err
+	}
+	hd.model.items = make([]*historyItem, len(snapshots))
+	for i, snapshot := range snapshots {
+		hd.model.items[i] = &historyItem{
+			Time:    snapshot.Time.Local().Format(time.DateTime),
+			Size:    util.ByteCountIEC(snapshot.Size),
+			version: snapshot.Version,
+		}
+	}
+	hd.model.PublishRowsReset()
+	return nil
+}
+
+// onCompare shows the changes between two selected versions, or from the selected
+// version to the current config.
+func (hd *HistoryDialog) onCompare() {
+	indexes := hd.table.SelectedIndexes()
+	from, to := hd.model.items[indexes[0]].version, ""
+	if len(indexes) == 2 {
+		// The table is sorted newest first
+		from, to = hd.model.items[indexes[1]].version, from
+	}
+	diff, err := confStore.Diff(hd.conf.ID, from, to)
+	if showError(err, hd.Form()) {
+		return
+	}
+	if diff.Empty() {
+		showInfoMessage(hd.Form(), i18n.Sprintf("Compare"), i18n.Sprintf("The versions are identical."))
+		return
+	}
+	showInfoMessage(hd.Form(), i18n.Sprintf("Compare"), diff.String())
+}
+
+func (hd *HistoryDialog) onRestore() {
+	item := hd.model.items[hd.table.CurrentIndex()]
+	if walk.MsgBox(hd.Form(), i18n.Sprintf("Restore"),
+		i18n.Sprintf("Are you sure you would like to restore the version saved at %s? A running service is restarted with it.", item.Time),
+		walk.MsgBoxYesNo|walk.MsgBoxIconQuestion) == walk.DlgCmdNo {
+		return
+	}
+	if showError(restoreConf(hd.conf, item.version), hd.Form()) {
+		return
+	}
+	showError(hd.load(), hd.Form())
+}
+
+// restoreConf replaces the config with a version from its history, and restarts its
+// service, so that the service runs the restored config.
+func restoreConf(conf *Conf, version string) error {
+	if err := confStore.Restore(conf.ID, version); err != nil {
+		return err
+	}
+	data, _, err := loadConfData(conf.ID)
+	if err != nil {
+		return err
+	}
+	conf.Data = data
+	conf.updateSum()
+	commitConf(conf, runFlagAuto)
+	if conf == getCurrentConf() {
+		setCurrentConf(conf)
+	}
+	return nil
+}
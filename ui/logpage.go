@@ -1,18 +1,17 @@
 package ui
 
 import (
-	"path/filepath"
 	"slices"
 	"sort"
 	"strings"
 	"time"
 
-	"github.com/fsnotify/fsnotify"
 	"github.com/lxn/walk"
 	. "github.com/lxn/walk/declarative"
 	"github.com/samber/lo"
 
 	"github.com/hzcrv1911/frpcgui/i18n"
+	"github.com/hzcrv1911/frpcgui/pkg/logtail"
 	"github.com/hzcrv1911/frpcgui/pkg/util"
 )
 
@@ -23,7 +22,6 @@ type LogPage struct {
 	dateModel ListModel
 	logModel  *LogModel
 	ch        chan logSelect
-	watcher   *fsnotify.Watcher
 
 	// Views
 	logView  *walk.TableView
@@ -37,16 +35,10 @@ type logSelect struct {
 	maxLines int
 }
 
-func NewLogPage() (*LogPage, error) {
-	lp := &LogPage{
+func NewLogPage() *LogPage {
+	return &LogPage{
 		ch: make(chan logSelect),
 	}
-	watcher, err := fsnotify.NewWatcher()
-	if err != nil {
-		return nil, err
-	}
-	lp.watcher = watcher
-	return lp, nil
 }
 
 func (lp *LogPage) Page() TabPage {
@@ -136,84 +128,73 @@ func (lp *LogPage) Page() TabPage {
 func (lp *LogPage) OnCreate() {
 	lp.VisibleChanged().Attach(lp.onVisibleChanged)
 	go func() {
-		// Due to the file caching mechanism, new logs may not be written to
-		// the disk immediately, and therefore no write events will be received.
-		// It is still necessary to read files regularly.
-		ticker := time.NewTicker(time.Second * 5)
-		defer ticker.Stop()
 		var path string
-		var watch bool
-		for {
-			select {
-			case event, ok := <-lp.watcher.Events:
-				if !ok {
-					return
+		var tailer *logtail.Tailer
+		var unsubscribe func()
+		stop := func() {
+			if tailer != nil {
+				unsubscribe()
+				tailer.Close()
+				tailer = nil
+			}
+			path = ""
+		}
+		defer stop()
+		for logs := range lp.ch {
+			// Try to avoid duplicate operations
+			if path != "" && len(logs.paths) > 0 && logs.paths[0] == path {
+				continue
+			}
+			stop()
+			var model *LogModel
+			var ok bool
+			if len(logs.paths) > 0 {
+				path = logs.paths[0]
+				if logs.maxLines > 0 {
+					// The latest log is followed by a tailer shared with the proxy trackers,
+					// which provides its recent lines
+					model, ok = NewLogModel(logs.paths[1:], logs.maxLines)
+					ok = ok || util.FileExists(path)
+				} else {
+					model, ok = NewLogModel(logs.paths, logs.maxLines)
 				}
-				if path != event.Name {
-					continue
+			}
+			lp.Synchronize(func() {
+				lp.openView.SetEnabled(ok)
+				lp.logModel = model
+				if model != nil {
+					lp.logView.SetModel(model)
+					lp.scrollToBottom()
+				} else {
+					lp.logView.SetModel(nil)
 				}
-				if event.Has(fsnotify.Write) {
-					lp.refreshLog()
-				} else if event.Has(fsnotify.Create) {
-					lp.logView.Synchronize(func() {
-						if lp.logModel != nil {
-							lp.logModel.Reset()
-						}
-						if !lp.openView.Enabled() {
-							lp.openView.SetEnabled(true)
-						}
+			})
+			if model != nil && logs.maxLines > 0 {
+				tailer = logtail.Open(path)
+				unsubscribe = tailer.Subscribe(func(lines []string) {
+					lp.Synchronize(func() {
+						lp.appendLog(model, lines)
 					})
-				}
-			case logs := <-lp.ch:
-				// Try to avoid duplicate operations
-				if path != "" && len(logs.paths) > 0 && logs.paths[0] == path {
-					continue
-				}
-				if path != "" {
-					if watch {
-						lp.watcher.Remove(filepath.Dir(path))
-					}
-					path = ""
-					watch = false
-				}
-				var model *LogModel
-				var ok bool
-				if len(logs.paths) > 0 {
-					path = logs.paths[0]
-					watch = logs.maxLines > 0
-					if watch {
-						lp.watcher.Add(filepath.Dir(path))
-					}
-					model, ok = NewLogModel(logs.paths, logs.maxLines)
-				}
-				lp.Synchronize(func() {
-					lp.openView.SetEnabled(ok)
-					lp.logModel = model
-					if model != nil {
-						lp.logView.SetModel(model)
-						lp.scrollToBottom()
-					} else {
-						lp.logView.SetModel(nil)
-					}
 				})
-			case <-ticker.C:
-				if path != "" && watch {
-					lp.refreshLog()
-				}
 			}
 		}
 	}()
 }
 
-func (lp *LogPage) refreshLog() {
-	lp.logView.Synchronize(func() {
-		if lp.logModel != nil {
-			scroll := lp.logModel.RowCount() == 0 || (lp.logView.ItemVisible(lp.logModel.RowCount()-1) && len(lp.logView.SelectedIndexes()) <= 1)
-			if n, err := lp.logModel.ReadMore(); err == nil && n > 0 && scroll {
-				lp.scrollToBottom()
-			}
-		}
-	})
+// appendLog adds the new lines of the followed log to the view. The view keeps
+// following the end of the log unless it's scrolled up or lines are selected.
+func (lp *LogPage) appendLog(model *LogModel, lines []string) {
+	if lp.logModel != model {
+		return
+	}
+	if !lp.openView.Enabled() {
+		lp.openView.SetEnabled(true)
+	}
+	scroll := model.RowCount() == 0 || (lp.logView.ItemVisible(model.RowCount()-1) && len(lp.logView.SelectedIndexes()) <= 1)
+	model.Append(lines)
+	if scroll {
+		lp.scrollToBottom()
+	}
 }
 
 func (lp *LogPage) onVisibleChanged() {
@@ -297,5 +278,6 @@ func (lp *LogPage) switchLogDate() {
 }
 
 func (lp *LogPage) Close() error {
-	return lp.watcher.Close()
+	close(lp.ch)
+	return nil
 }
@@ -2,6 +2,7 @@ package ui
 
 import (
 	"net"
+	"path/filepath"
 	"slices"
 	"strconv"
 	"strings"
@@ -10,6 +11,7 @@ import (
 	"github.com/lxn/walk"
 	"github.com/samber/lo"
 
+	"github.com/hzcrv1911/frpcgui/i18n"
 	"github.com/hzcrv1911/frpcgui/pkg/config"
 	"github.com/hzcrv1911/frpcgui/pkg/consts"
 	"github.com/hzcrv1911/frpcgui/pkg/util"
@@ -147,6 +149,8 @@ type ProxyRow struct {
 	DisplayLocalPort string
 	// DisplayRemotePort changes the remote port shown in table.
 	DisplayRemotePort string
+	// DisplaySource is the name of the included file holding the proxy
+	DisplaySource string
 }
 
 func NewProxyRow(p *config.Proxy) *ProxyRow {
@@ -192,6 +196,10 @@ func fillProxyRow(p *config.Proxy, pr *ProxyRow) *ProxyRow {
 			pr.DisplayLocalIP = p.PluginLocalAddr
 		}
 	}
+	pr.DisplaySource = ""
+	if p.Source != "" {
+		pr.DisplaySource = filepath.Base(p.Source)
+	}
 	pr.UpdateRemotePort()
 	return pr
 }
@@ -297,31 +305,32 @@ func NewListModel(values []string, titles ...any) ListModel {
 	return items
 }
 
+// NewFormatListModel returns a list model of the config file formats.
+func NewFormatListModel() ListModel {
+	return NewListModel(consts.Formats, "TOML", "YAML", "JSON", i18n.Sprintf("INI (Legacy)"))
+}
+
 type LogModel struct {
 	walk.TableModelBase
 
-	path     string
-	offset   int64
 	maxLines int
 	lines    []string
 }
 
+// NewLogModel reads the last lines of the log files, which are ordered from the newest.
+// The new lines of a followed log are added by Append.
 func NewLogModel(paths []string, maxLines int) (*LogModel, bool) {
 	m := &LogModel{
-		path:     paths[0],
 		maxLines: maxLines,
 		lines:    make([]string, 0),
 	}
 	ok := false
-	for i, path := range paths {
-		lines, k, offset, err := util.ReadFileLines(path, 0, maxLines)
+	for _, path := range paths {
+		lines, k, _, err := util.ReadFileLines(path, 0, maxLines)
 		if err != nil {
 			continue
 		}
 		ok = true
-		if i == 0 {
-			m.offset = offset
-		}
 		if k >= 0 {
 			for n, j := len(lines), k-1; (j+n)%n != k; j-- {
 				m.lines = append(m.lines, lines[(j+n)%n])
@@ -340,28 +349,27 @@ func NewLogModel(paths []string, maxLines int) (*LogModel, bool) {
 	if len(m.lines) > 0 {
 		slices.Reverse(m.lines)
 	}
+	for i, line := range m.lines {
+		m.lines[i] = strings.TrimRight(line, "\r\n")
+	}
 	return m, ok
 }
 
-func (m *LogModel) write(lines []string, i int) {
+// Append adds the lines to the end of the log, and drops the oldest lines beyond the limit.
+func (m *LogModel) Append(lines []string) {
 	if len(lines) == 0 {
 		return
 	}
-	if m.maxLines > 0 && len(m.lines) >= m.maxLines {
-		copy(m.lines[:], m.lines[len(lines):])
-		m.lines = m.lines[:len(m.lines)-len(lines)]
-		m.PublishRowsRemoved(0, len(lines)-1)
-		m.PublishRowsChanged(0, len(m.lines)-1)
+	if m.maxLines > 0 {
+		lines = lines[max(0, len(lines)-m.maxLines):]
+		if n := len(m.lines) + len(lines) - m.maxLines; n > 0 {
+			m.lines = slices.Delete(m.lines, 0, n)
+			m.PublishRowsRemoved(0, n-1)
+		}
 	}
 	from := len(m.lines)
-	if i >= 0 {
-		m.lines = append(m.lines, lines[i:]...)
-		m.lines = append(m.lines, lines[:i]...)
-	} else {
-		m.lines = append(m.lines, lines...)
-	}
-	to := from + len(lines) - 1
-	m.PublishRowsInserted(from, to)
+	m.lines = append(m.lines, lines...)
+	m.PublishRowsInserted(from, len(m.lines)-1)
 }
 
 func (m *LogModel) Value(row, col int) any {
@@ -372,20 +380,6 @@ func (m *LogModel) RowCount() int {
 	return len(m.lines)
 }
 
-func (m *LogModel) Reset() {
-	m.offset = 0
-}
-
-func (m *LogModel) ReadMore() (int, error) {
-	lines, k, offset, err := util.ReadFileLines(m.path, m.offset, m.maxLines)
-	if err != nil {
-		return 0, err
-	}
-	m.write(lines, k)
-	m.offset = offset
-	return len(lines), nil
-}
-
 // NonSortedModel preserves the original order of items
 // in the slice.
 type NonSortedModel[T any] struct {
@@ -10,6 +10,8 @@ import (
 	"github.com/samber/lo"
 
 	"github.com/hzcrv1911/frpcgui/i18n"
+	"github.com/hzcrv1911/frpcgui/pkg/api"
+	"github.com/hzcrv1911/frpcgui/pkg/config"
 	"github.com/hzcrv1911/frpcgui/pkg/consts"
 	"github.com/hzcrv1911/frpcgui/pkg/res"
 	"github.com/hzcrv1911/frpcgui/pkg/sec"
@@ -20,6 +22,7 @@ type PrefPage struct {
 	*walk.TabPage
 
 	usePassword *walk.CheckBox
+	useVault    *walk.CheckBox
 }
 
 func NewPrefPage() *PrefPage {
@@ -28,6 +31,7 @@ func NewPrefPage() *PrefPage {
 
 func (pp *PrefPage) OnCreate() {
 	pp.usePassword.CheckedChanged().Attach(pp.switchPassword)
+	pp.useVault.CheckedChanged().Attach(pp.switchVault)
 }
 
 func (pp *PrefPage) Page() TabPage {
@@ -73,6 +77,12 @@ func (pp *PrefPage) passwordSection() GroupBox {
 					HSpacer{},
 				},
 			},
+			CheckBox{
+				Row: 3, Column: 1,
+				AssignTo: &pp.useVault,
+				Text:     i18n.Sprintf("Encrypt tokens and passwords in config files"),
+				Checked:  appConf.SecretVault,
+			},
 		},
 	}
 }
@@ -147,10 +157,17 @@ func (pp *PrefPage) advancedSection() GroupBox {
 						Text:    i18n.Sprintf("Settings"),
 						MinSize: Size{Width: 100},
 						OnClicked: func() {
+							oldAPI := appConf.API
 							if r, err := pp.setAdvancedSettings(); err == nil && r == win.IDOK {
+								confStore.Retention = appConf.History
 								if err = saveAppConfig(); err != nil {
 									showError(err, pp.Form())
 								}
+								if appConf.API != oldAPI && localAPI != nil {
+									if err = localAPI.restart(); err != nil {
+										showErrorMessage(pp.Form(), "", i18n.Sprintf("Failed to start the local API: %v", err))
+									}
+								}
 							}
 						},
 					},
@@ -168,8 +185,7 @@ func (pp *PrefPage) switchPassword() {
 		}
 	} else {
 		if appConf.Password != "" {
-			appConf.Password = ""
-			if err := saveAppConfig(); err != nil {
+			if err := setMasterPassword("", ""); err != nil {
 				showError(err, pp.Form())
 				return
 			}
@@ -200,10 +216,12 @@ func (pp *PrefPage) changePassword() string {
 			},
 		}, VSpacer{}).Run(pp.Form())
 	if vm.Password != "" {
-		oldPassword := appConf.Password
-		appConf.Password = sec.EncryptPassword(vm.Password)
-		if err := saveAppConfig(); err != nil {
-			appConf.Password = oldPassword
+		hashed, err := sec.HashPassword(vm.Password)
+		if err != nil {
+			showError(err, pp.Form())
+			return ""
+		}
+		if err = setMasterPassword(vm.Password, hashed); err != nil {
 			showError(err, pp.Form())
 		} else {
 			showInfoMessage(pp.Form(), "", i18n.Sprintf("Password is set."))
@@ -212,6 +230,21 @@ func (pp *PrefPage) changePassword() string {
 	return vm.Password
 }
 
+func (pp *PrefPage) switchVault() {
+	if pp.useVault.Checked() == appConf.SecretVault {
+		return
+	}
+	var err error
+	if pp.useVault.Checked() {
+		err = enableVault()
+	} else {
+		err = disableVault()
+	}
+	if showError(err, pp.Form()) {
+		pp.useVault.SetChecked(appConf.SecretVault)
+	}
+}
+
 func (pp *PrefPage) switchLanguage(lc string) {
 	appConf.Lang = lc
 	if err := saveAppConfig(); err != nil {
@@ -219,9 +252,24 @@ func (pp *PrefPage) switchLanguage(lc string) {
 	}
 }
 
+// bundleSettings is the view model of the bundle policy.
+type bundleSettings struct {
+	RequireSigned bool
+	TrustedKeys   string
+}
+
 func (pp *PrefPage) setAdvancedSettings() (int, error) {
+	// Replace the deprecated format option
+	appConf.Defaults.Format = appConf.Defaults.FileFormat()
+	appConf.Defaults.LegacyFormat = false
+	bundles := bundleSettings{
+		RequireSigned: appConf.Bundles.RequireSigned,
+		TrustedKeys:   config.FormatTrustedKeys(appConf.Bundles.TrustedKeys),
+	}
+	apiConf := appConf.API
 	var w *walk.Dialog
-	var dbs [2]*walk.DataBinder
+	var tokenEdit *walk.LineEdit
+	var dbs [5]*walk.DataBinder
 	dlg := NewBasicDialog(&w, i18n.Sprintf("Advanced"),
 		loadIcon(res.IconSettings, 32),
 		DataBinder{}, func() {
@@ -230,6 +278,18 @@ func (pp *PrefPage) setAdvancedSettings() (int, error) {
 					return
 				}
 			}
+			keys, err := config.ParseTrustedKeys(bundles.TrustedKeys)
+			if showError(err, w) {
+				return
+			}
+			appConf.Bundles = config.BundlePolicy{TrustedKeys: keys, RequireSigned: bundles.RequireSigned}
+			// The API can't be used without a token
+			if apiConf.Enabled && apiConf.Token == "" {
+				if apiConf.Token, err = api.NewToken(); showError(err, w) {
+					return
+				}
+			}
+			appConf.API = apiConf
 			w.Accept()
 		}, Composite{
 			Layout: VBox{Margins: Margins{Left: 4, Top: 4, Right: 4, Bottom: 4}},
@@ -271,6 +331,13 @@ func (pp *PrefPage) setAdvancedSettings() (int, error) {
 						LineEdit{Text: Bind("NatHoleSTUNServer")},
 						Label{Text: i18n.SprintfColon("Source Address")},
 						LineEdit{Text: Bind("ConnectServerLocalIP")},
+						Label{Text: i18n.SprintfColon("File Format")},
+						ComboBox{
+							Value:         Bind("Format"),
+							Model:         NewFormatListModel(),
+							BindingMember: "Value",
+							DisplayMember: "Title",
+						},
 						Composite{
 							Layout: VBox{MarginsZero: true, SpacingZero: true},
 							Children: []Widget{
@@ -289,7 +356,59 @@ func (pp *PrefPage) setAdvancedSettings() (int, error) {
 									},
 								},
 								CheckBox{Text: i18n.Sprintf("Disable auto-start at boot"), Checked: Bind("ManualStart")},
-								CheckBox{Text: i18n.Sprintf("Use legacy file format"), Checked: Bind("LegacyFormat")},
+							},
+						},
+					},
+				},
+				GroupBox{
+					Title:      i18n.Sprintf("Version History"),
+					Layout:     Grid{Columns: 2},
+					DataBinder: DataBinder{AssignTo: &dbs[2], DataSource: &appConf.History},
+					Children: []Widget{
+						Label{Text: i18n.SprintfColon("Versions per config")},
+						NewNumberInput(NIOption{Value: Bind("Count"), Max: math.MaxFloat64}),
+						Label{Text: i18n.SprintfColon("Keep versions for")},
+						NewNumberInput(NIOption{Value: Bind("Days"), Suffix: i18n.Sprintf("Days"), Max: math.MaxFloat64}),
+					},
+				},
+				GroupBox{
+					Title:      i18n.Sprintf("Config Bundles"),
+					Layout:     VBox{},
+					DataBinder: DataBinder{AssignTo: &dbs[3], DataSource: &bundles},
+					Children: []Widget{
+						CheckBox{
+							Text:    i18n.Sprintf("Only accept bundles signed by a trusted key"),
+							Checked: Bind("RequireSigned"),
+						},
+						Label{Text: i18n.Sprintf("Trusted keys, one per line with an optional name:")},
+						TextEdit{Text: Bind("TrustedKeys"), VScroll: true, MinSize: Size{Height: 60}},
+					},
+				},
+				GroupBox{
+					Title:      i18n.Sprintf("Local API"),
+					Layout:     Grid{Columns: 2},
+					DataBinder: DataBinder{AssignTo: &dbs[4], DataSource: &apiConf},
+					Children: []Widget{
+						CheckBox{
+							Text:       i18n.Sprintf("Allow other programs to control the configs"),
+							Checked:    Bind("Enabled"),
+							ColumnSpan: 2,
+						},
+						Label{Text: i18n.SprintfColon("Address")},
+						LineEdit{Text: Bind("Address"), CueBanner: api.DefaultAddress},
+						Label{Text: i18n.SprintfColon("Token")},
+						Composite{
+							Layout: HBox{MarginsZero: true},
+							Children: []Widget{
+								LineEdit{AssignTo: &tokenEdit, Text: Bind("Token"), ReadOnly: true},
+								PushButton{
+									Text: i18n.Sprintf("New Token"),
+									OnClicked: func() {
+										if token, err := api.NewToken(); !showError(err, w) {
+											tokenEdit.SetText(token)
+										}
+									},
+								},
 							},
 						},
 					},
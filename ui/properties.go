@@ -57,7 +57,7 @@ func (pd *PropertiesDialog) Run(owner walk.Form) (int, error) {
 	}
 	items := []*ListItem{
 		{Title: i18n.Sprintf("Name"), Value: pd.conf.Name()},
-		{Title: i18n.Sprintf("Identifier"), Value: util.FileNameWithoutExt(pd.conf.Path)},
+		{Title: i18n.Sprintf("Identifier"), Value: pd.conf.ID},
 		{Title: i18n.Sprintf("Service Name"), Value: services.ServiceNameOfClient(pd.conf.Path)},
 		{Title: i18n.Sprintf("File Format"), Value: strings.ToUpper(pd.conf.Data.Ext()[1:])},
 		{Title: i18n.Sprintf("Server Address"), Value: pd.conf.Data.ServerAddress},
@@ -1,11 +1,8 @@
 package ui
 
 import (
-	"bufio"
 	"context"
-	"os"
 	"path/filepath"
-	"strings"
 	"sync"
 	"time"
 
@@ -14,6 +11,8 @@ import (
 	"github.com/hzcrv1911/frpcgui/pkg/config"
 	"github.com/hzcrv1911/frpcgui/pkg/consts"
 	"github.com/hzcrv1911/frpcgui/pkg/ipc"
+	"github.com/hzcrv1911/frpcgui/pkg/logparse"
+	"github.com/hzcrv1911/frpcgui/pkg/logtail"
 	"github.com/hzcrv1911/frpcgui/services"
 )
 
@@ -25,13 +24,15 @@ type ProxyTracker struct {
 	ctx                context.Context
 	cancel             context.CancelFunc
 	refreshTimer       *time.Timer
-	ticker             *time.Ticker
-	lastLogPos         int64
-	logFilePath        string
 	rowsInsertedHandle int
 	beforeRemoveHandle int
 	rowEditedHandle    int
 	rowRenamedHandle   int
+	// client queries the status from the admin API of frpc, if it's enabled
+	client ipc.Client
+	// log follows the log file of frpc, if the admin API isn't enabled
+	log         *logtail.Tailer
+	unsubscribe func()
 }
 
 func NewProxyTracker(owner walk.Form, model *ProxyModel, refresh bool) (tracker *ProxyTracker) {
@@ -61,20 +62,19 @@ func NewProxyTrackerWithConfig(owner walk.Form, model *ProxyModel, configPath st
 	}
 
 	tracker = &ProxyTracker{
-		owner:       owner,
-		model:       model,
-		cache:       cache,
-		ctx:         ctx,
-		cancel:      cancel,
-		logFilePath: logFilePath,
+		owner:  owner,
+		model:  model,
+		cache:  cache,
+		ctx:    ctx,
+		cancel: cancel,
 		rowsInsertedHandle: model.RowsInserted().Attach(func(from, to int) {
 			tracker.Lock()
-			defer tracker.Unlock()
 			for i := from; i <= to; i++ {
 				for _, key := range model.items[i].GetAlias() {
 					cache[key] = model.items[i].Proxy
 				}
 			}
+			tracker.Unlock()
 			// In WinSW mode, we trigger a status check
 			tracker.checkProxyStatus()
 		}),
@@ -95,8 +95,27 @@ func NewProxyTrackerWithConfig(owner walk.Form, model *ProxyModel, configPath st
 	}
 	tracker.buildCache()
 
-	// Start log file monitoring
-	go tracker.monitorLogFile()
+	if data := model.conf.Data; data.AdminPort > 0 {
+		tracker.client = ipc.NewAdminClient(data.AdminAddr, data.AdminPort, data.AdminUser, data.AdminPwd)
+		tracker.client.SetCallback(func(msg []ipc.ProxyMessage) {
+			owner.Synchronize(func() {
+				if ctx.Err() == nil {
+					tracker.onMessage(msg)
+				}
+			})
+		})
+		go tracker.client.Run(ctx)
+	} else {
+		// Without the admin API, the status is read from the log file
+		tracker.log = logtail.Open(logFilePath)
+		tracker.unsubscribe = tracker.log.Subscribe(func(lines []string) {
+			owner.Synchronize(func() {
+				if ctx.Err() == nil {
+					tracker.processLogLines(lines)
+				}
+			})
+		})
+	}
 
 	// If no status information is received within a certain period of time,
 	// we need to refresh the view to make the icon visible.
@@ -161,157 +180,26 @@ func (pt *ProxyTracker) Close() {
 		pt.refreshTimer.Stop()
 		pt.refreshTimer = nil
 	}
-	if pt.ticker != nil {
-		pt.ticker.Stop()
-	}
-}
-
-// monitorLogFile monitors the frpc log file for proxy status changes
-func (pt *ProxyTracker) monitorLogFile() {
-	// Create ticker for periodic checks
-	pt.ticker = time.NewTicker(2 * time.Second)
-	defer pt.ticker.Stop()
-
-	for {
-		select {
-		case <-pt.ctx.Done():
-			return
-		case <-pt.ticker.C:
-			pt.processLogFile()
-		}
-	}
-}
-
-// processLogFile processes the log file to extract proxy status information
-func (pt *ProxyTracker) processLogFile() {
-	// Check if file exists
-	if _, err := os.Stat(pt.logFilePath); os.IsNotExist(err) {
-		return
-	}
-
-	// Open file
-	file, err := os.Open(pt.logFilePath)
-	if err != nil {
-		return
-	}
-	defer file.Close()
-
-	// Seek to last position
-	if _, err := file.Seek(pt.lastLogPos, 0); err != nil {
-		return
-	}
-
-	// Read new lines
-	scanner := bufio.NewScanner(file)
-	var newLines []string
-	for scanner.Scan() {
-		line := scanner.Text()
-		pt.lastLogPos += int64(len(line) + 1) // +1 for newline
-		newLines = append(newLines, line)
-	}
-
-	// Process new lines
-	if len(newLines) > 0 {
-		pt.processLogLines(newLines)
+	if pt.log != nil {
+		pt.unsubscribe()
+		pt.log.Close()
 	}
 }
 
 // processLogLines processes multiple log lines to extract proxy status
 func (pt *ProxyTracker) processLogLines(lines []string) {
-	var messages []ipc.ProxyMessage
-
-	for _, line := range lines {
-		// Look for proxy status messages in log
-		// Examples:
-		// "proxy [tcp:ssh] starts successfully"
-		// "proxy [http:web] starts successfully"
-		// "proxy [tcp:ssh] error: connection refused"
-
-		// Check for proxy start message
-		if strings.Contains(line, "starts successfully") {
-			msg := pt.extractProxyStatusFromLog(line, "running", "")
-			if msg != nil {
-				messages = append(messages, *msg)
-			}
-		}
-
-		// Check for proxy error messages
-		if strings.Contains(line, "error:") || strings.Contains(line, "failed") {
-			// Extract error message
-			var errorMsg string
-			parts := strings.SplitN(line, "error:", 2)
-			if len(parts) > 1 {
-				errorMsg = strings.TrimSpace(parts[1])
-			} else {
-				parts = strings.SplitN(line, "failed", 2)
-				if len(parts) > 1 {
-					errorMsg = strings.TrimSpace(parts[1])
-				}
-			}
-			msg := pt.extractProxyStatusFromLog(line, "error", errorMsg)
-			if msg != nil {
-				messages = append(messages, *msg)
-			}
-		}
-
-		// Check for proxy stop messages
-		if strings.Contains(line, "proxy stopped") {
-			msg := pt.extractProxyStatusFromLog(line, "stopped", "")
-			if msg != nil {
-				messages = append(messages, *msg)
-			}
-		}
-	}
-
 	// Send messages to UI
-	if len(messages) > 0 {
+	if messages := proxyMessagesFromLog(lines); len(messages) > 0 {
 		pt.onMessage(messages)
 	}
 }
 
-// extractProxyStatusFromLog extracts proxy status from a log line
-func (pt *ProxyTracker) extractProxyStatusFromLog(line, status, errorMsg string) *ipc.ProxyMessage {
-	// Extract proxy name and type from log line
-	// Format: "proxy [type:name] message"
-	start := strings.Index(line, "[")
-	end := strings.Index(line, "]")
-	if start == -1 || end == -1 {
-		return nil
-	}
-
-	proxyInfo := line[start+1 : end]
-	parts := strings.SplitN(proxyInfo, ":", 2)
-	if len(parts) != 2 {
-		return nil
-	}
-
-	proxyType := parts[0]
-	proxyName := parts[1]
-
-	// Create proxy message
-	msg := ipc.ProxyMessage{
-		Name:   proxyName,
-		Type:   proxyType,
-		Status: status,
-		Err:    errorMsg,
-	}
-
-	// Try to extract remote address if available
-	if strings.Contains(line, "remote address:") {
-		addrStart := strings.Index(line, "remote address:") + len("remote address:")
-		addrEnd := strings.Index(line[addrStart:], ",")
-		if addrEnd == -1 {
-			addrEnd = len(line[addrStart:])
-		}
-		remoteAddr := strings.TrimSpace(line[addrStart : addrStart+addrEnd])
-		msg.RemoteAddr = remoteAddr
-	}
-
-	return &msg
-}
-
 // checkProxyStatus checks the current status of all proxies
 func (pt *ProxyTracker) checkProxyStatus() {
+	if pt.client != nil {
+		pt.client.Probe(pt.ctx)
+		return
+	}
 	// Check if service is running
 	running, err := services.IsFrpcRunning(getCurrentConf().Path)
 	if err != nil {
@@ -352,34 +240,24 @@ func (pt *ProxyTracker) checkProxyStatus() {
 	}
 }
 
-// getProxyStatusFromLogs retrieves proxy status by parsing the entire log file
+// getProxyStatusFromLogs retrieves proxy status from the recent lines of the log file
 func (pt *ProxyTracker) getProxyStatusFromLogs() []ipc.ProxyMessage {
-	var messages []ipc.ProxyMessage
-
-	// Check if file exists
-	if _, err := os.Stat(pt.logFilePath); os.IsNotExist(err) {
-		return messages
-	}
-
-	// Read log file
-	file, err := os.Open(pt.logFilePath)
-	if err != nil {
-		return messages
+	if pt.log == nil {
+		return nil
 	}
-	defer file.Close()
-
-	// Scan file for proxy status
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
+	return proxyMessagesFromLog(pt.log.Lines())
+}
 
-		// Extract proxy status from log line
-		msg := pt.extractProxyStatusFromLog(line, "", "")
-		if msg != nil && msg.Status != "" {
-			messages = append(messages, *msg)
+// proxyMessagesFromLog extracts the proxy status from log lines
+func proxyMessagesFromLog(lines []string) []ipc.ProxyMessage {
+	var messages []ipc.ProxyMessage
+	for _, line := range lines {
+		if r, ok := logparse.Parse(line); ok {
+			if msg, ok := ipc.ProxyMessageFromLog(r); ok {
+				messages = append(messages, msg)
+			}
 		}
 	}
-
 	return messages
 }
 
@@ -3,12 +3,14 @@ package ui
 import (
 	"fmt"
 	"net"
+	"path/filepath"
 	"strconv"
 	"strings"
 
 	"github.com/lxn/walk"
 	. "github.com/lxn/walk/declarative"
 	"github.com/lxn/win"
+	"github.com/samber/lo"
 
 	"github.com/hzcrv1911/frpcgui/i18n"
 	"github.com/hzcrv1911/frpcgui/pkg/config"
@@ -87,6 +89,7 @@ func (pv *ProxyView) Invalidate() {
 	if conf := getCurrentConf(); conf != nil {
 		pv.model = NewProxyModel(conf)
 		pv.table.SetModel(pv.model)
+		pv.showSourceColumn()
 		if conf.State == consts.ConfigStateStarted {
 			pv.startTracker(false)
 		}
@@ -283,6 +286,7 @@ func (pv *ProxyView) createProxyTable() TableView {
 			{Title: i18n.Sprintf("Domains"), DataMember: "Domains", Width: 80},
 			{Title: i18n.Sprintf("Plugin"), DataMember: "Plugin", Width: 80},
 			{Title: i18n.Sprintf("Remote Address"), DataMember: "RemoteAddr", Width: 110, Name: "remoteAddr", Hidden: true},
+			{Title: i18n.Sprintf("File"), DataMember: "DisplaySource", Width: 80, Name: "source", Hidden: true},
 		},
 		MultiSelection: true,
 		ContextMenuItems: []MenuItem{
@@ -326,6 +330,11 @@ func (pv *ProxyView) createProxyTable() TableView {
 					},
 				},
 			},
+			Action{
+				Enabled:     Bind("proxy.SelectedCount > 0"),
+				Text:        i18n.Sprintf("Move to File"),
+				OnTriggered: pv.onMoveToFile,
+			},
 			Separator{},
 			ActionRef{Action: &pv.newAction},
 			Menu{
@@ -557,7 +566,13 @@ func (pv *ProxyView) onEdit(proxy *config.Proxy, create bool) {
 		oldName = proxy.Name
 		oldAliasLen = len(proxy.GetAlias())
 	}
-	dlg := NewEditProxyDialog(proxy, pv.visitors(except), create, pv.model.data.LegacyFormat, pv.model.HasName)
+	conf := pv.model.conf
+	conf.editing = true
+	defer func() {
+		conf.editing = false
+		syncConf(conf)
+	}()
+	dlg := NewEditProxyDialog(proxy, pv.visitors(except), create, pv.model.data.Format == consts.FormatINI, pv.model.HasName)
 	if result, _ := dlg.Run(pv.Form()); result == walk.DlgCmdOK {
 		if create {
 			pv.model.Add(dlg.Proxy)
@@ -662,6 +677,56 @@ func (pv *ProxyView) onMove(delta int) {
 	pv.table.SetCurrentIndex(targetIdx)
 }
 
+// onMoveToFile moves the selected proxies to a file included by the config, which is
+// created if it doesn't exist. Choosing the config file itself moves them back to it.
+func (pv *ProxyView) onMoveToFile() {
+	indexes := pv.table.SelectedIndexes()
+	if len(indexes) == 0 || pv.model == nil {
+		return
+	}
+	conf := pv.model.conf
+	path, err := filepath.Abs(conf.Path)
+	if err != nil {
+		showError(err, pv.Form())
+		return
+	}
+	dlg := walk.FileDialog{
+		Filter:         res.FilterConfig + res.FilterAllFiles,
+		Title:          i18n.Sprintf("Move to File"),
+		InitialDirPath: filepath.Dir(path),
+	}
+	if ok, _ := dlg.ShowSave(pv.Form()); !ok {
+		return
+	}
+	source := dlg.FilePath
+	if filepath.Ext(source) == "" {
+		source += conf.Data.Ext()
+	}
+	if strings.EqualFold(source, path) {
+		source = ""
+	} else if err = conf.Data.Include(conf.Path, source); err != nil {
+		showError(err, pv.Form())
+		return
+	}
+	for _, idx := range indexes {
+		row := pv.model.items[idx]
+		row.Source = source
+		fillProxyRow(row.Proxy, row)
+		pv.model.PublishRowChanged(idx)
+	}
+	pv.showSourceColumn()
+	pv.commit()
+}
+
+// showSourceColumn shows the file column if any proxy comes from an included file.
+func (pv *ProxyView) showSourceColumn() {
+	if col := pv.table.Columns().ByName("source"); col != nil {
+		col.SetVisible(pv.model != nil && lo.SomeBy(pv.model.data.Proxies, func(p *config.Proxy) bool {
+			return p.Source != ""
+		}))
+	}
+}
+
 // switchToggleAction updates the toggle action based on the current selected proxies
 func (pv *ProxyView) switchToggleAction() {
 	indexes := pv.table.SelectedIndexes()
@@ -2,7 +2,6 @@ package ui
 
 import (
 	"fmt"
-	"strconv"
 
 	"github.com/lxn/walk"
 	. "github.com/lxn/walk/declarative"
@@ -100,19 +99,21 @@ func (sp *SimpleProxyDialog) onSave() {
 		return
 	}
 	for _, proto := range sp.types {
+		localPorts := config.PortSpec{{Start: sp.binder.LocalPort, End: sp.binder.LocalPort}}
+		remotePorts := config.PortSpec{{Start: sp.binder.RemotePort, End: sp.binder.RemotePort}}
+		if sp.binder.LocalPortMin > 0 && sp.binder.LocalPortMax > 0 {
+			portRange := config.PortRange{Start: sp.binder.LocalPortMin, End: sp.binder.LocalPortMax}
+			localPorts = append(localPorts, portRange)
+			remotePorts = append(remotePorts, portRange)
+		}
 		proxy := config.Proxy{
 			BaseProxyConf: config.BaseProxyConf{
 				Name:      fmt.Sprintf("%s_%s_%d", sp.service, proto, sp.binder.RemotePort),
 				Type:      proto,
 				LocalIP:   sp.binder.LocalAddr,
-				LocalPort: strconv.Itoa(sp.binder.LocalPort),
+				LocalPort: localPorts.String(),
 			},
-			RemotePort: strconv.Itoa(sp.binder.RemotePort),
-		}
-		if sp.binder.LocalPortMin > 0 && sp.binder.LocalPortMax > 0 {
-			portRange := fmt.Sprintf("%d-%d", sp.binder.LocalPortMin, sp.binder.LocalPortMax)
-			proxy.LocalPort += "," + portRange
-			proxy.RemotePort += "," + portRange
+			RemotePort: remotePorts.String(),
 		}
 		sp.Proxies = append(sp.Proxies, &proxy)
 	}
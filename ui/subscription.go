@@ -0,0 +1,270 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lxn/walk"
+	. "github.com/lxn/walk/declarative"
+	"github.com/samber/lo"
+
+	"github.com/hzcrv1911/frpcgui/i18n"
+	"github.com/hzcrv1911/frpcgui/pkg/config"
+	"github.com/hzcrv1911/frpcgui/pkg/consts"
+	"github.com/hzcrv1911/frpcgui/pkg/res"
+	"github.com/hzcrv1911/frpcgui/services"
+)
+
+// subscriptionCheckInterval is how often the subscriptions are checked for a due refresh.
+const subscriptionCheckInterval = time.Minute
+
+// SubscriptionDialog sets the URL a config is refreshed from.
+type SubscriptionDialog struct {
+	*walk.Dialog
+
+	db   *walk.DataBinder
+	conf *Conf
+	sub  config.Subscription
+}
+
+func NewSubscriptionDialog(conf *Conf) *SubscriptionDialog {
+	sd := &SubscriptionDialog{conf: conf, sub: config.Subscription{Interval: config.DefaultSubscriptionInterval}}
+	if sub := appConf.Subscriptions[conf.ID]; sub != nil {
+		sd.sub = *sub
+	}
+	return sd
+}
+
+func (sd *SubscriptionDialog) Run(owner walk.Form) (int, error) {
+	status := i18n.Sprintf("Not refreshed yet")
+	if sd.sub.LastError != "" {
+		status = i18n.Sprintf("The last refresh failed: %s", sd.sub.LastError)
+	} else if !sd.sub.LastCheck.IsZero() {
+		status = i18n.Sprintf("Last refreshed at %s", sd.sub.LastCheck.Local().Format(time.DateTime))
+	}
+	return NewBasicDialog(&sd.Dialog, i18n.Sprintf("%s Subscription", sd.conf.Name()), loadIcon(res.IconURLImport, 32),
+		DataBinder{AssignTo: &sd.db, DataSource: &sd.sub}, sd.onSave,
+		Label{Text: i18n.Sprintf("* The config is refreshed from the URL. Leave it empty to unsubscribe.")},
+		Composite{
+			Layout: Grid{Columns: 2, MarginsZero: true},
+			Children: []Widget{
+				Label{Text: i18n.SprintfColon("URL")},
+				LineEdit{Text: Bind("URL"), MinSize: Size{Width: 350}},
+				Label{Text: i18n.SprintfColon("Refresh interval")},
+				NewNumberInput(NIOption{
+					Value:  Bind("Interval"),
+					Suffix: i18n.Sprintf("Minutes"),
+					Min:    config.MinSubscriptionInterval,
+					Max:    7 * 24 * 60,
+				}),
+				HSpacer{},
+				CheckBox{Text: i18n.Sprintf("Apply changes without asking"), Checked: Bind("AutoApply")},
+			},
+		},
+		Label{Text: status, EllipsisMode: EllipsisEnd},
+		VSpacer{Size: 4},
+	).Run(owner)
+}
+
+func (sd *SubscriptionDialog) onSave() {
+	if err := sd.db.Submit(); err != nil {
+		return
+	}
+	sd.sub.URL = strings.TrimSpace(sd.sub.URL)
+	old := appConf.Subscriptions[sd.conf.ID]
+	if sd.sub.URL == "" {
+		if old != nil {
+			delete(appConf.Subscriptions, sd.conf.ID)
+			if showError(saveAppConfig(), sd.Form()) {
+				return
+			}
+		}
+		sd.Accept()
+		return
+	}
+	if u, err := url.Parse(sd.sub.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		showWarningMessage(sd.Form(), i18n.Sprintf("Subscription"), i18n.Sprintf("Please enter the correct URL."))
+		return
+	}
+	if old == nil || old.URL != sd.sub.URL {
+		// The content of another URL is refreshed right away
+		sd.sub.ETag, sd.sub.LastModified, sd.sub.LastError = "", "", ""
+		sd.sub.LastCheck = time.Time{}
+	}
+	if appConf.Subscriptions == nil {
+		appConf.Subscriptions = make(map[string]*config.Subscription)
+	}
+	appConf.Subscriptions[sd.conf.ID] = &sd.sub
+	if showError(saveAppConfig(), sd.Form()) {
+		return
+	}
+	sd.Accept()
+}
+
+// watchSubscriptions refreshes the subscribed configs when they're due.
+// The returned function stops refreshing.
+func (cv *ConfView) watchSubscriptions() func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	refreshing := make(map[string]bool)
+	cv.refreshSubscription = func(id string, manual bool) {
+		sub := appConf.Subscriptions[id]
+		if sub == nil || refreshing[id] {
+			return
+		}
+		refreshing[id] = true
+		s := *sub
+		go func() {
+			update, err := s.Fetch(ctx)
+			if errors.Is(err, context.Canceled) {
+				return
+			}
+			cv.Synchronize(func() {
+				cv.applySubscription(id, update, err, manual)
+				delete(refreshing, id)
+			})
+		}()
+	}
+	go func() {
+		ticker := time.NewTicker(subscriptionCheckInterval)
+		defer ticker.Stop()
+		for {
+			cv.Synchronize(func() {
+				now := time.Now()
+				for id, sub := range appConf.Subscriptions {
+					if sub.Due(now) {
+						cv.refreshSubscription(id, false)
+					}
+				}
+			})
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return cancel
+}
+
+// applySubscription applies the result of a refresh to the subscribed config. The failures
+// are reported once unless the refresh is requested, leaving the config unchanged.
+func (cv *ConfView) applySubscription(id string, update *config.SubscriptionUpdate, err error, manual bool) {
+	sub := appConf.Subscriptions[id]
+	if sub == nil {
+		return
+	}
+	conf, found := lo.Find(cv.model.List(), func(item *Conf) bool { return item.ID == id })
+	if !found {
+		delete(appConf.Subscriptions, id)
+		saveAppConfig()
+		return
+	}
+	if conf.editing {
+		// Refresh again once the config is no longer edited
+		return
+	}
+	if err == nil && update != nil {
+		err = cv.applyUpdate(conf, sub, update)
+	}
+	lastError := sub.LastError
+	sub.Checked(time.Now(), update, err)
+	saveAppConfig()
+	title := i18n.Sprintf("Subscription")
+	if errors.Is(err, config.ErrUpdateDeclined) {
+		return
+	}
+	if err != nil {
+		if manual || sub.LastError != lastError {
+			showWarningMessage(cv.Form(), title,
+				i18n.Sprintf("Failed to refresh the config \"%s\" from %s, the config is kept unchanged.", conf.Name(), sub.URL)+
+					"\n\n"+err.Error())
+		}
+	} else if manual && update == nil {
+		showInfoMessage(cv.Form(), title, i18n.Sprintf("The config \"%s\" is up to date.", conf.Name()))
+	}
+}
+
+// applyUpdate validates the subscribed content and merges it with the config, keeping the
+// local changes. The changes are applied with the consent of the user, unless they're
+// applied automatically, and config.ErrUpdateDeclined is returned if the user declines.
+// The content becomes the base of the next merge only once the changes are saved.
+func (cv *ConfView) applyUpdate(conf *Conf, sub *config.Subscription, update *config.SubscriptionUpdate) error {
+	content, err := subscribedContent(update.Data)
+	if err != nil {
+		return err
+	}
+	data, err := config.UnmarshalClientConf(content)
+	if err != nil {
+		return err
+	}
+	if data.Name() == "" {
+		data.ClientCommon.Name = conf.Name()
+	}
+	if err = openSecrets(data.Name(), data); err != nil {
+		return err
+	}
+	// The log file is always decided by the path of the config
+	data.LogFile = conf.Data.LogFile
+	merged, conflicts := conf.merge(data)
+	if err = merged.Validate().Err(); err != nil {
+		return err
+	}
+	diff := config.Diff(conf.Data, merged)
+	if !diff.Empty() {
+		if !sub.AutoApply {
+			message := i18n.Sprintf("The config \"%s\" was updated at %s. The update makes the following changes:", conf.Name(), sub.URL) +
+				"\n\n" + diff.String()
+			if len(conflicts) > 0 {
+				message += "\n\n" + i18n.Sprintf("The following options were changed on both sides, your values are kept:") + "\n" +
+					strings.Join(lo.Map(conflicts, func(c config.MergeConflict, i int) string { return c.String() }), "\n")
+			}
+			if walk.MsgBox(cv.Form(), i18n.Sprintf("Subscription"),
+				message+"\n\n"+i18n.Sprintf("Would you like to apply the changes?"),
+				walk.MsgBoxYesNo|walk.MsgBoxIconQuestion) == walk.DlgCmdNo {
+				return config.ErrUpdateDeclined
+			}
+		}
+		oldData := conf.Data
+		conf.Data = merged
+		if err = conf.Save(); err != nil {
+			conf.Data = oldData
+			return err
+		}
+		checkConflicts(cv.Form(), conf)
+		if conf.State == consts.ConfigStateStarted {
+			showError(services.ReloadService(conf.Path), cv.Form())
+		}
+		if i := slices.Index(cv.model.items, conf); i >= 0 {
+			cv.model.PublishRowChanged(i)
+			cv.model.PublishRowEdited(i)
+		}
+		if conf == getCurrentConf() {
+			setCurrentConf(conf)
+		}
+	}
+	return conf.saveBase(content)
+}
+
+// subscribedContent returns the config in the subscribed content, which is either a bundle
+// of one config, or a config file if unsigned content is accepted.
+func subscribedContent(data []byte) ([]byte, error) {
+	if !config.IsZip(data) {
+		return data, appConf.Bundles.CheckUnsigned()
+	}
+	bundle, err := appConf.Bundles.OpenBundle(data)
+	if err != nil {
+		return nil, err
+	}
+	files := lo.Filter(bundle.Files, func(file config.BundleFile, i int) bool {
+		return slices.Contains(res.SupportedConfigFormats, strings.ToLower(filepath.Ext(file.Name)))
+	})
+	if len(files) != 1 {
+		return nil, errors.New(i18n.Sprintf("The bundle holds %d configs, but a subscription takes exactly one.", len(files)))
+	}
+	return files[0].Data, nil
+}
@@ -67,24 +67,32 @@ func (fm *FRPManager) idealSize(empty bool) walk.Size {
 func RunUI() error {
 	var err error
 	// Make sure the profiles directory exists.
-	if err = os.MkdirAll("profiles", os.ModePerm); err != nil {
-		return err
-	}
-	cfgList, err := loadAllConfs()
-	if err != nil {
+	if err = os.MkdirAll(profilesDir, os.ModePerm); err != nil {
 		return err
 	}
+	loadAppConf()
 	if appConf.Password != "" {
-		if r, err := NewValidateDialog().Run(); err != nil || r != win.IDOK {
+		vd := NewValidateDialog()
+		if r, err := vd.Run(); err != nil || r != win.IDOK {
 			return err
 		}
+		masterPassword = vd.password
 	}
-	fm := new(FRPManager)
-	fm.confPage = NewConfPage(cfgList)
-	fm.logPage, err = NewLogPage()
+	if err = openVault(); err != nil {
+		showErrorMessage(nil, "", i18n.Sprintf("Failed to open the secret vault: %v", err))
+	}
+	cfgList, sealed, err := loadAllConfs()
 	if err != nil {
 		return err
 	}
+	if len(sealed) > 0 {
+		showWarningMessage(nil, AppLocalName(),
+			i18n.Sprintf("The secrets of the following configs can't be decrypted with the key of the secret vault:")+
+				"\n\n"+strings.Join(sealed, "\n"))
+	}
+	fm := new(FRPManager)
+	fm.confPage = NewConfPage(cfgList)
+	fm.logPage = NewLogPage()
 	fm.prefPage = NewPrefPage()
 	fm.aboutPage = NewAboutPage()
 	mw := MainWindow{
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"syscall"
+	"time"
 	"unsafe"
 
 	"github.com/lxn/walk"
@@ -20,6 +21,8 @@ const stmSetIcon = 0x0170
 // ValidateDialog validates the administration password.
 type ValidateDialog struct {
 	hIcon win.HICON
+	// password is the validated password.
+	password string
 }
 
 func NewValidateDialog() *ValidateDialog {
@@ -54,12 +57,32 @@ func (vd *ValidateDialog) proc(h win.HWND, msg uint32, wp, lp uintptr) uintptr {
 	case win.WM_COMMAND:
 		switch win.LOWORD(uint32(wp)) {
 		case win.IDOK:
+			// Failed attempts are persisted, so restarting the program doesn't skip the delay
+			if wait := appConf.Lockout.Remaining(time.Now()); wait > 0 {
+				win.MessageBox(h, windows.StringToUTF16Ptr(i18n.Sprintf("Too many failed attempts. Try again in %s.", wait.Round(time.Second))),
+					windows.StringToUTF16Ptr(AppLocalName()), windows.MB_ICONERROR)
+				break
+			}
 			passwd := GetWindowText(win.GetDlgItem(h, res.DialogEdit))
-			if sec.EncryptPassword(passwd) != appConf.Password {
+			ok, rehash := sec.VerifyPassword(passwd, appConf.Password)
+			if !ok {
+				appConf.Lockout.Fail(time.Now())
+				saveAppConfig()
 				win.MessageBox(h, windows.StringToUTF16Ptr(i18n.Sprintf("The password is incorrect. Re-enter password.")),
 					windows.StringToUTF16Ptr(AppLocalName()), windows.MB_ICONERROR)
 				win.SetFocus(win.GetDlgItem(h, res.DialogEdit))
 			} else {
+				// Legacy and outdated hashes are replaced once the password is known
+				if rehash {
+					if hashed, err := sec.HashPassword(passwd); err == nil {
+						appConf.Password = hashed
+					}
+				}
+				if rehash || appConf.Lockout.Failures > 0 {
+					appConf.Lockout.Reset()
+					saveAppConfig()
+				}
+				vd.password = passwd
 				win.EndDialog(h, win.IDOK)
 			}
 		case win.IDCANCEL:
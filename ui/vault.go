@@ -0,0 +1,149 @@
+package ui
+
+import (
+	"errors"
+	"os"
+
+	"github.com/hzcrv1911/frpcgui/i18n"
+	"github.com/hzcrv1911/frpcgui/pkg/config"
+	"github.com/hzcrv1911/frpcgui/pkg/sec"
+	"github.com/hzcrv1911/frpcgui/services"
+)
+
+var (
+	// vault seals the secrets of configs on disk. It's nil if secrets are stored in plain text.
+	vault    *sec.Vault
+	vaultKey []byte
+	// masterPassword is the password entered at startup, which protects the vault key.
+	masterPassword string
+)
+
+// setVault starts using the key to seal secrets. A nil key disables the vault.
+func setVault(key []byte) error {
+	var v *sec.Vault
+	if key != nil {
+		var err error
+		if v, err = sec.NewVault(key); err != nil {
+			return err
+		}
+	}
+	vault, vaultKey = v, key
+	services.Vault = v
+	confStore.Vault = v
+	return nil
+}
+
+// openVault unlocks the key file with the master password, if the vault is enabled.
+func openVault() error {
+	if !appConf.SecretVault {
+		return nil
+	}
+	kf, err := sec.LoadKeyFile(config.VaultKeyFile)
+	if err != nil {
+		return err
+	}
+	key, err := kf.Unlock(masterPassword)
+	if err != nil {
+		return err
+	}
+	return setVault(key)
+}
+
+// protectVault rewrites the key file to be unlocked by a new master password.
+func protectVault(password string) error {
+	if vaultKey != nil {
+		kf, err := sec.NewKeyFile(vaultKey, password)
+		if err != nil {
+			return err
+		}
+		if err = kf.Save(config.VaultKeyFile); err != nil {
+			return err
+		}
+	}
+	masterPassword = password
+	return nil
+}
+
+// setMasterPassword changes the master password, whose hash is empty if the password is
+// removed. The key file is rewritten before the hash is saved, and restored if saving
+// fails, so that the key file is always unlocked by the saved password.
+func setMasterPassword(password, hashed string) error {
+	oldPassword, oldHash := masterPassword, appConf.Password
+	if err := protectVault(password); err != nil {
+		return err
+	}
+	appConf.Password = hashed
+	if err := saveAppConfig(); err != nil {
+		appConf.Password = oldHash
+		protectVault(oldPassword)
+		return err
+	}
+	return nil
+}
+
+// enableVault creates a vault key and seals the secrets of all configs.
+func enableVault() error {
+	key, err := sec.GenerateKey()
+	if err != nil {
+		return err
+	}
+	kf, err := sec.NewKeyFile(key, masterPassword)
+	if err != nil {
+		return err
+	}
+	if err = kf.Save(config.VaultKeyFile); err != nil {
+		return err
+	}
+	if err = setVault(key); err != nil {
+		return err
+	}
+	appConf.SecretVault = true
+	if err = saveAppConfig(); err != nil {
+		appConf.SecretVault = false
+		setVault(nil)
+		os.Remove(config.VaultKeyFile)
+		return err
+	}
+	return saveAllConfs()
+}
+
+// disableVault writes the secrets of all configs in plain text and removes the vault key.
+func disableVault() error {
+	for _, conf := range getConfList() {
+		if err := openSecrets(conf.Name(), conf.Data); err != nil {
+			return err
+		}
+	}
+	appConf.SecretVault = false
+	if err := saveAppConfig(); err != nil {
+		appConf.SecretVault = true
+		return err
+	}
+	setVault(nil)
+	if err := saveAllConfs(); err != nil {
+		return err
+	}
+	return os.Remove(config.VaultKeyFile)
+}
+
+// saveAllConfs writes all configs to disk, which applies a change of the vault.
+func saveAllConfs() error {
+	var errs []error
+	for _, conf := range getConfList() {
+		errs = append(errs, conf.Save())
+	}
+	return errors.Join(errs...)
+}
+
+// openSecrets decrypts the sealed secrets of a loaded or imported config.
+func openSecrets(name string, data *config.ClientConfig) error {
+	if err := data.OpenSecrets(vault); err != nil {
+		return errors.New(i18n.Sprintf("The secrets of config \"%s\" can't be decrypted.", name))
+	}
+	return nil
+}
+
+// sealContent returns the config content with its secrets sealed, if the vault is enabled.
+func sealContent(src []byte) []byte {
+	return config.SealContent(src, vault)
+}